@@ -0,0 +1,70 @@
+// Idrc compiles an IDR schema.
+//
+// Usage:
+//
+//	idrc [-package name] [-go file] [-idr file] schema
+//
+// Idrc parses and validates the schema. With the -go flag, it writes a Go
+// file defining a struct type for each record, with the idr struct tags
+// encoding them as described by the schema. With the -idr flag, it writes
+// the IDR encoding of the schema that may be stored in a DIS node.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/chmike/ditp/idr/schema"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: idrc [-package name] [-go file] [-idr file] schema\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	pkg := flag.String("package", "main", "package name of the generated Go file")
+	goFile := flag.String("go", "", "generated Go file name")
+	idrFile := flag.String("idr", "", "IDR encoded schema file name")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *pkg, *goFile, *idrFile); err != nil {
+		fmt.Fprintf(os.Stderr, "idrc: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(name, pkg, goFile, idrFile string) error {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	s, err := schema.Parse(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if goFile != "" {
+		out, err := schema.GenerateGo(s, pkg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := os.WriteFile(goFile, out, 0o644); err != nil {
+			return err
+		}
+	}
+	if idrFile != "" {
+		out, err := schema.Marshal(s)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(idrFile, out, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+	"github.com/chmike/ditp/idr/low"
+)
+
+// entry is a decoded value.
+type entry struct {
+	Offset   int      `json:"offset"`
+	Size     int      `json:"size"`
+	Field    *uint64  `json:"field,omitempty"` // record field number
+	Role     string   `json:"role,omitempty"`  // key or value of a map entry
+	Tag      string   `json:"tag"`
+	Value    any      `json:"value,omitempty"`
+	URI      string   `json:"uri,omitempty"` // dis: form of a DIR
+	Count    *uint64  `json:"count,omitempty"`
+	ElemTag  string   `json:"elemTag,omitempty"`
+	KeyTag   string   `json:"keyTag,omitempty"`
+	ValueTag string   `json:"valueTag,omitempty"`
+	Children []*entry `json:"children,omitempty"`
+}
+
+// dumpError is the first malformed data met.
+type dumpError struct {
+	Offset int    `json:"offset"` // offset of the first malformed byte
+	Msg    string `json:"message"`
+}
+
+func (e *dumpError) Error() string {
+	return fmt.Sprintf("malformed data at offset %d: %s", e.Offset, e.Msg)
+}
+
+// decoder decodes the entries of the data b.
+type decoder struct {
+	b []byte
+}
+
+// entries returns the entries of the sequence of tagged values in d.b, and
+// the first error met.
+func (d *decoder) entries() ([]*entry, error) {
+	var entries []*entry
+	r := low.NewReader(d.b)
+	for r.Len() > 0 {
+		e, err := d.tagged(r)
+		if e != nil {
+			entries = append(entries, e)
+		}
+		if err != nil {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
+
+// tagged returns the entry of the next tagged value.
+func (d *decoder) tagged(r *low.Reader) (*entry, error) {
+	start := r.Offset()
+	tag := r.Tag()
+	if err := d.check(r, tag, start); err != nil {
+		return nil, err
+	}
+	e, err := d.value(r, tag)
+	if e != nil {
+		e.Size += e.Offset - start
+		e.Offset = start
+	}
+	return e, err
+}
+
+// check returns the error of r or an error if tag is not a value tag.
+func (d *decoder) check(r *low.Reader, tag low.TagT, off int) error {
+	if err := d.err(r); err != nil {
+		return err
+	}
+	if tag >= low.MaxTag || tag == low.BytesTag {
+		return &dumpError{Offset: off, Msg: fmt.Sprintf("invalid tag %d", uint64(tag))}
+	}
+	return nil
+}
+
+// err converts the first error of the readers to a dumpError. The first
+// malformed byte of truncated data is the end of the data decoded by the
+// reader, which may be the end of a composite value.
+func (d *decoder) err(rs ...*low.Reader) error {
+	var r *low.Reader
+	for _, r = range rs {
+		if r.Err() != nil {
+			break
+		}
+	}
+	var de *low.DecodeError
+	if !errors.As(r.Err(), &de) {
+		return r.Err()
+	}
+	off := de.Offset
+	if errors.Is(de, io.ErrUnexpectedEOF) {
+		off = r.Offset() + r.Len()
+	}
+	return &dumpError{Offset: off, Msg: de.Error()}
+}
+
+// value returns the entry of the next value encoded as specified by tag.
+func (d *decoder) value(r *low.Reader, tag low.TagT) (*entry, error) {
+	e := &entry{Offset: r.Offset(), Tag: tag.String()}
+	switch tag {
+	case low.ArrayTag:
+		c, et, n := r.Array()
+		if err := d.err(r, &c); err != nil {
+			return nil, err
+		}
+		e.Count, e.ElemTag = &n, et.String()
+		e.Size = r.Offset() - e.Offset
+		if et == low.NoneTag {
+			// the elements have no encoding
+			break
+		}
+		for i := uint64(0); i < n; i++ {
+			if err := d.check(&c, et, c.Offset()); err != nil {
+				return e, err
+			}
+			x, err := d.value(&c, et)
+			if x != nil {
+				e.Children = append(e.Children, x)
+			}
+			if err != nil {
+				return e, err
+			}
+		}
+	case low.ListTag:
+		c, n := r.List()
+		if err := d.err(r, &c); err != nil {
+			return nil, err
+		}
+		e.Count = &n
+		e.Size = r.Offset() - e.Offset
+		for i := uint64(0); i < n; i++ {
+			x, err := d.tagged(&c)
+			if x != nil {
+				e.Children = append(e.Children, x)
+			}
+			if err != nil {
+				return e, err
+			}
+		}
+	case low.MapTag:
+		c, kt, vt, n := r.Map()
+		if err := d.err(r, &c); err != nil {
+			return nil, err
+		}
+		e.Count, e.KeyTag, e.ValueTag = &n, kt.String(), vt.String()
+		e.Size = r.Offset() - e.Offset
+		if kt == low.NoneTag && vt == low.NoneTag {
+			// the entries have no encoding
+			break
+		}
+		for i := uint64(0); i < 2*n; i++ {
+			t, role := kt, "key"
+			if i%2 == 1 {
+				t, role = vt, "value"
+			}
+			if err := d.check(&c, t, c.Offset()); err != nil {
+				return e, err
+			}
+			x, err := d.value(&c, t)
+			if x != nil {
+				x.Role = role
+				e.Children = append(e.Children, x)
+			}
+			if err != nil {
+				return e, err
+			}
+		}
+	case low.RecordTag:
+		c := r.Record()
+		if err := d.err(r, &c); err != nil {
+			return nil, err
+		}
+		e.Size = r.Offset() - e.Offset
+		for c.Len() > 0 {
+			x, err := d.field(&c)
+			if x != nil {
+				e.Children = append(e.Children, x)
+			}
+			if err != nil {
+				return e, err
+			}
+		}
+	default:
+		v := r.Value(tag)
+		if err := d.err(r); err != nil {
+			return nil, err
+		}
+		e.Size = r.Offset() - e.Offset
+		e.Value = v
+		if v, ok := v.(dir.DIR); ok {
+			e.URI = v.URI()
+		}
+	}
+	return e, nil
+}
+
+// field returns the entry of the next record field.
+func (d *decoder) field(r *low.Reader) (*entry, error) {
+	start := r.Offset()
+	num, tag := r.Field()
+	if err := d.check(r, tag, start); err != nil {
+		return nil, err
+	}
+	e, err := d.value(r, tag)
+	if e != nil {
+		e.Size += e.Offset - start
+		e.Offset = start
+		e.Field = &num
+	}
+	return e, err
+}
+
+// format returns the text representation of a scalar value.
+func format(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("0x%x", v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case dir.DIR:
+		return fmt.Sprintf("%v %s", v, v)
+	}
+	return fmt.Sprint(v)
+}
+
+// writeText writes the entries as text, one per line, with the nested
+// values indented.
+func writeText(w io.Writer, entries []*entry, depth int) {
+	for _, e := range entries {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%8d %6d %s", e.Offset, e.Size, strings.Repeat("  ", depth))
+		if e.Field != nil {
+			fmt.Fprintf(&b, "%d: ", *e.Field)
+		}
+		if e.Role != "" {
+			fmt.Fprintf(&b, "%s: ", e.Role)
+		}
+		b.WriteString(e.Tag)
+		switch {
+		case e.ElemTag != "":
+			fmt.Fprintf(&b, " count=%d elem=%s", *e.Count, e.ElemTag)
+		case e.KeyTag != "":
+			fmt.Fprintf(&b, " count=%d key=%s value=%s", *e.Count, e.KeyTag, e.ValueTag)
+		case e.Count != nil:
+			fmt.Fprintf(&b, " count=%d", *e.Count)
+		case e.Value != nil:
+			b.WriteString(" " + format(e.Value))
+		}
+		fmt.Fprintln(w, b.String())
+		writeText(w, e.Children, depth+1)
+	}
+}
+
+// jsonValues converts the values of the entries to values supported by
+// encoding/json.
+func jsonValues(entries []*entry) {
+	for _, e := range entries {
+		switch v := e.Value.(type) {
+		case float32:
+			if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+				e.Value = fmt.Sprint(v)
+			}
+		case float64:
+			if math.IsInf(v, 0) || math.IsNaN(v) {
+				e.Value = fmt.Sprint(v)
+			}
+		case complex64, complex128:
+			e.Value = fmt.Sprint(v)
+		case []byte:
+			e.Value = fmt.Sprintf("0x%x", v)
+		case time.Time:
+			e.Value = v.Format(time.RFC3339Nano)
+		case dir.DIR:
+			e.Value = v.String()
+		}
+		jsonValues(e.Children)
+	}
+}
+
+// dump writes the values of the tagged IDR data b as text or as JSON. It
+// returns the first malformed data error.
+func dump(w io.Writer, b []byte, asJSON bool) error {
+	d := decoder{b: b}
+	entries, err := d.entries()
+	if !asJSON {
+		writeText(w, entries, 0)
+		return err
+	}
+	jsonValues(entries)
+	out := struct {
+		Values []*entry   `json:"values"`
+		Error  *dumpError `json:"error,omitempty"`
+	}{Values: entries}
+	if out.Values == nil {
+		out.Values = []*entry{}
+	}
+	errors.As(err, &out.Error)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chmike/ditp/dir"
+	"github.com/chmike/ditp/idr/low"
+)
+
+// sample returns tagged values of most kinds.
+func sample() low.Encoder {
+	var e low.Encoder
+	e = low.AppendTag(e, low.Uint16Tag)
+	e = low.AppendUint16(e, 300)
+	e = low.AppendTag(e, low.StringTag)
+	e = low.AppendString(e, "hello")
+	e = low.AppendTag(e, low.DIRTag)
+	e = low.AppendDIR(e, dir.MustParse("dir:1.2"))
+	e = low.AppendTag(e, low.RecordTag)
+	e, p := low.BeginRecord(e)
+	e = low.AppendField(e, 1, low.BoolTag)
+	e = low.AppendBool(e, true)
+	e = low.AppendField(e, 2, low.ArrayTag)
+	e, q := low.BeginArray(e, low.VarIntTag, 2)
+	e = low.AppendVarInt(e, -1)
+	e = low.AppendVarInt(e, 1)
+	e = low.EndComposite(e, q)
+	e = low.AppendField(e, 3, low.MapTag)
+	e, q = low.BeginMap(e, low.StringTag, low.BlobTag, 1)
+	e = low.AppendString(e, "k")
+	e = low.AppendBlob(e, []byte{0xCA, 0xFE})
+	e = low.EndComposite(e, q)
+	e = low.EndComposite(e, p)
+	e = low.AppendTag(e, low.ListTag)
+	e, p = low.BeginList(e, 1)
+	e = low.AppendTag(e, low.NoneTag)
+	e = low.EndComposite(e, p)
+	return e
+}
+
+func TestDumpText(t *testing.T) {
+	var out bytes.Buffer
+	if err := dump(&out, sample(), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := `       0      3 Uint16Tag 300
+       3      7 StringTag "hello"
+      10      4 DIRTag dir:1.2 dis:1.2/
+      14     23 RecordTag
+      16      3   1: BoolTag true
+      19      7   2: ArrayTag count=2 elem=VarIntTag
+      24      1     VarIntTag -1
+      25      1     VarIntTag 1
+      26     11   3: MapTag count=1 key=StringTag value=BlobTag
+      32      2     key: StringTag "k"
+      34      3     value: BlobTag 0xcafe
+      37      4 ListTag count=1
+      40      1   NoneTag
+`
+	if out.String() != exp {
+		t.Errorf("expect\n%s\ngot\n%s", exp, out.String())
+	}
+}
+
+func TestDumpMalformed(t *testing.T) {
+	valid := sample()
+	record := func(b []byte) []byte {
+		e := low.AppendTag(nil, low.RecordTag)
+		e = low.AppendSize(e, uint64(len(b)))
+		return append(e, b...)
+	}
+	tests := []struct {
+		data []byte
+		off  int
+	}{
+		{data: valid[:len(valid)-1], off: len(valid) - 1},                       // 0
+		{data: []byte{byte(low.MaxTag)}, off: 0},                                // 1
+		{data: []byte{byte(low.BoolTag), 1, byte(low.BytesTag)}, off: 2},        // 2
+		{data: []byte{byte(low.ListTag), 1, 5}, off: 2},                         // 3
+		{data: append(record([]byte{1, byte(low.Uint16Tag), 0}), 1, 1), off: 5}, // 4
+		{data: record([]byte{1, byte(low.MaxTag), 0}), off: 2},                  // 5
+		{data: []byte{byte(low.StringTag), 3, 'a'}, off: 1},                     // 6
+		{data: []byte{byte(low.ArrayTag), 2, byte(low.BoolTag), 5}, off: 3},     // 7
+		{data: []byte{byte(low.ArrayTag), 2, byte(low.BoolTag), 1}, off: 3},     // 8
+		{data: []byte{byte(low.ListTag), 2, 1, byte(low.MaxTag)}, off: 3},       // 9
+	}
+	for i, test := range tests {
+		var out bytes.Buffer
+		err := dump(&out, test.data, false)
+		var de *dumpError
+		if !errors.As(err, &de) {
+			t.Errorf("%d expect dumpError, got %v", i, err)
+			continue
+		}
+		if de.Offset != test.off {
+			t.Errorf("%d expect offset %d, got %d: %v", i, test.off, de.Offset, err)
+		}
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	data := append(sample(), byte(low.MaxTag))
+	var out bytes.Buffer
+	err := dump(&out, data, true)
+	if err == nil {
+		t.Fatal("expect error")
+	}
+	var res struct {
+		Values []*entry
+		Error  *dumpError
+	}
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Values) != 5 {
+		t.Fatalf("expect 5 values, got %d", len(res.Values))
+	}
+	if res.Error == nil || res.Error.Offset != len(data)-1 {
+		t.Errorf("expect error at offset %d, got %v", len(data)-1, res.Error)
+	}
+	if d := res.Values[2]; d.Value != "dir:1.2" || d.URI != "dis:1.2/" {
+		t.Errorf("expect dir:1.2 and dis:1.2/, got %v and %v", d.Value, d.URI)
+	}
+	m := res.Values[3].Children[2]
+	if *m.Field != 3 || len(m.Children) != 2 || m.Children[1].Value != "0xcafe" {
+		t.Errorf("unexpected map field %+v", m)
+	}
+	if !strings.Contains(out.String(), `"role": "key"`) {
+		t.Errorf("expect map key role in\n%s", out.String())
+	}
+}
@@ -0,0 +1,56 @@
+// Idrdump prints the tagged IDR values of a file or of the standard input.
+//
+// Usage:
+//
+//	idrdump [-json] [file]
+//
+// Each value is printed on a line with its byte offset, its byte size
+// including its tag, its tag name and its decoded value. The elements of
+// composite values are printed on the following lines with an indentation.
+// DIRs are printed in their dir: and dis: forms. With the -json flag, the
+// values are printed as a JSON document.
+//
+// When the data is malformed, the values decoded so far are printed and
+// idrdump reports the byte offset of the first malformed byte and exits
+// with status 1. The offset of truncated data is the data length.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: idrdump [-json] [file]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the values as JSON")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "idrdump: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(name string, asJSON bool) error {
+	var b []byte
+	var err error
+	if name == "" || name == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return err
+	}
+	return dump(os.Stdout, b, asJSON)
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/chmike/ditp/idr"
+)
+
+const (
+	lowPath = "github.com/chmike/ditp/idr/low"
+	dirPath = "github.com/chmike/ditp/dir"
+)
+
+// prim is a low level encoding. The encoding functions are named after it.
+type prim struct {
+	name  string // suffix of the low level function names
+	typ   string // Go type of the encoded values
+	max   string // maximum size argument of the decoding function
+	sized bool   // the size of the encoding depends on the value
+	to    string // format of the conversion to typ, if not a type conversion
+	from  string // format of the conversion from typ, if not a type conversion
+}
+
+// basicPrim returns the encoding of the basic type typ with the encoding
+// option opt.
+func basicPrim(typ, opt string) (prim, bool) {
+	varint := opt == "varint"
+	fixed := opt == "fixed"
+	switch typ {
+	case "bool":
+		return prim{name: "Bool", typ: "bool"}, true
+	case "int":
+		if fixed {
+			return prim{name: "Int64", typ: "int64"}, true
+		}
+		return prim{name: "VarInt", typ: "int", sized: true}, true
+	case "int8", "int16", "int32", "rune":
+		if varint {
+			return prim{name: "VarInt64", typ: "int64", sized: true}, true
+		}
+		typ = strings.Replace(typ, "rune", "int32", 1)
+		return prim{name: "I" + typ[1:], typ: typ}, true
+	case "int64":
+		if fixed {
+			return prim{name: "Int64", typ: "int64"}, true
+		}
+		return prim{name: "VarInt64", typ: "int64", sized: true}, true
+	case "uint":
+		if fixed {
+			return prim{name: "Uint64", typ: "uint64"}, true
+		}
+		return prim{name: "VarUint", typ: "uint", sized: true}, true
+	case "uint8", "uint16", "uint32", "byte":
+		if varint {
+			return prim{name: "VarUint64", typ: "uint64", sized: true}, true
+		}
+		typ = strings.Replace(typ, "byte", "uint8", 1)
+		return prim{name: "U" + typ[1:], typ: typ}, true
+	case "uint64":
+		if fixed {
+			return prim{name: "Uint64", typ: "uint64"}, true
+		}
+		return prim{name: "VarUint64", typ: "uint64", sized: true}, true
+	case "float32", "float64":
+		if varint {
+			return prim{name: "VarFloat", typ: "float64", sized: true}, true
+		}
+		return prim{name: "F" + typ[1:], typ: typ}, true
+	case "complex64", "complex128":
+		if varint {
+			return prim{name: "VarComplex", typ: "complex128", sized: true}, true
+		}
+		return prim{name: "C" + typ[1:], typ: typ}, true
+	case "string":
+		return prim{name: "String", typ: "string", max: "low.DefaultMaxStringSize", sized: true}, true
+	}
+	return prim{}, false
+}
+
+var (
+	blobPrim    = prim{name: "Blob", typ: "[]byte", max: "low.DefaultMaxBlobSize", sized: true}
+	dirPrim     = prim{name: "DIR", typ: "dir.DIR", sized: true}
+	timePrim    = prim{name: "Time", typ: "time.Time"}
+	varTimePrim = prim{name: "VarTime", typ: "time.Time", sized: true}
+	// microTimePrim encodes times in microseconds, as hand written code
+	microTimePrim = prim{name: "Int64", typ: "int64", to: "%s.UnixMicro()", from: "time.UnixMicro(%s)"}
+)
+
+// field is a struct field to encode.
+type field struct {
+	name   string // name of the field
+	typ    string // Go type of the field, or of the slice elements
+	slice  bool   // the field is a slice of typ
+	record bool   // typ is a struct type with generated methods
+	prim   prim   // encoding of typ when it is not a record
+}
+
+// conv returns the conversion of x to the type t when its type u differs.
+func conv(t, u, x string) string {
+	if t == u {
+		return x
+	}
+	return t + "(" + x + ")"
+}
+
+// toPrim returns the conversion of x of the type of f to the type of its
+// encoding.
+func (f field) toPrim(x string) string {
+	if f.prim.to != "" {
+		return fmt.Sprintf(f.prim.to, x)
+	}
+	return conv(f.prim.typ, f.typ, x)
+}
+
+// fromPrim returns the conversion of x of the type of the encoding of f
+// to the type of f.
+func (f field) fromPrim(x string) string {
+	if f.prim.from != "" {
+		return fmt.Sprintf(f.prim.from, x)
+	}
+	return conv(f.typ, f.prim.typ, x)
+}
+
+// typeDecl is a type declaration of the package.
+type typeDecl struct {
+	spec    *ast.TypeSpec
+	imports map[string]string // import paths by name in the file of spec
+}
+
+// pkg is a parsed package.
+type pkg struct {
+	name  string
+	types map[string]typeDecl
+}
+
+// parsePackage parses the non test Go files of the directory dir.
+func parsePackage(dir string) (*pkg, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	p := &pkg{types: make(map[string]typeDecl)}
+	fset := token.NewFileSet()
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		if p.name == "" {
+			p.name = f.Name.Name
+		} else if p.name != f.Name.Name {
+			return nil, fmt.Errorf("multiple packages in %s: %s and %s", dir, p.name, f.Name.Name)
+		}
+		imports := make(map[string]string)
+		for _, s := range f.Imports {
+			path, _ := strconv.Unquote(s.Path.Value)
+			name := path[strings.LastIndex(path, "/")+1:]
+			if s.Name != nil {
+				name = s.Name.Name
+			}
+			imports[name] = path
+		}
+		for _, d := range f.Decls {
+			if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.TYPE {
+				for _, s := range d.Specs {
+					s := s.(*ast.TypeSpec)
+					p.types[s.Name.Name] = typeDecl{spec: s, imports: imports}
+				}
+			}
+		}
+	}
+	if p.name == "" {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	return p, nil
+}
+
+// fields returns the fields to encode of the struct type name. The struct
+// types in records have generated methods.
+func (p *pkg) fields(name string, records []string) ([]field, error) {
+	decl, ok := p.types[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	st, ok := decl.spec.Type.(*ast.StructType)
+	if !ok || decl.spec.TypeParams != nil {
+		return nil, fmt.Errorf("type %s is not a struct", name)
+	}
+	var fields []field
+	nums := map[uint64]bool{}
+	for _, f := range st.Fields.List {
+		var opt, tag string
+		var tagged bool
+		if f.Tag != nil {
+			s, _ := strconv.Unquote(f.Tag.Value)
+			if tag, tagged = reflect.StructTag(s).Lookup("idr"); tag == "-" {
+				continue
+			}
+		}
+		names := f.Names
+		if len(names) == 0 {
+			id, ok := f.Type.(*ast.Ident)
+			if !ok {
+				return nil, fmt.Errorf("%s: unsupported embedded field", name)
+			}
+			names = []*ast.Ident{id}
+		}
+		for _, n := range names {
+			if tagged {
+				// same struct tags as the idr package
+				num, o, err := idr.ParseTag(tag)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", name, n.Name, err)
+				}
+				if !n.IsExported() {
+					return nil, fmt.Errorf("%s.%s: %w: field is not exported", name, n.Name, idr.ErrInvalidTag)
+				}
+				if nums[num] {
+					return nil, fmt.Errorf("%s.%s: %w: field number %d is not unique", name, n.Name, idr.ErrInvalidTag, num)
+				}
+				nums[num], opt = true, o
+			}
+			if !n.IsExported() {
+				continue
+			}
+			x, err := p.field(decl, f.Type, opt, records)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", name, n.Name, err)
+			}
+			x.name = n.Name
+			fields = append(fields, x)
+		}
+	}
+	return fields, nil
+}
+
+// field returns the encoding of the field type t declared in the file of
+// decl.
+func (p *pkg) field(decl typeDecl, t ast.Expr, opt string, records []string) (field, error) {
+	switch t := t.(type) {
+	case *ast.Ident:
+		if x, ok := basicPrim(t.Name, opt); ok {
+			typ := strings.NewReplacer("byte", "uint8", "rune", "int32").Replace(t.Name)
+			return field{typ: typ, prim: x}, nil
+		}
+		if slices.Contains(records, t.Name) {
+			return field{typ: t.Name, record: true}, nil
+		}
+		if d, ok := p.types[t.Name]; ok {
+			if u, ok := d.spec.Type.(*ast.Ident); ok {
+				if x, ok := basicPrim(u.Name, opt); ok {
+					return field{typ: t.Name, prim: x}, nil
+				}
+			}
+		}
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			switch decl.imports[x.Name] + "." + t.Sel.Name {
+			case "time.Time":
+				switch opt {
+				case "fixed":
+					return field{typ: timePrim.typ, prim: timePrim}, nil
+				case "micro":
+					return field{typ: "time.Time", prim: microTimePrim}, nil
+				}
+				return field{typ: varTimePrim.typ, prim: varTimePrim}, nil
+			case dirPath + ".DIR":
+				return field{typ: dirPrim.typ, prim: dirPrim}, nil
+			}
+		}
+	case *ast.ArrayType:
+		if t.Len != nil {
+			break
+		}
+		if id, ok := t.Elt.(*ast.Ident); ok && (id.Name == "byte" || id.Name == "uint8") {
+			return field{typ: blobPrim.typ, prim: blobPrim}, nil
+		}
+		x, err := p.field(decl, t.Elt, opt, records)
+		if err != nil || x.slice {
+			break
+		}
+		x.slice = true
+		return x, nil
+	}
+	var b bytes.Buffer
+	format.Node(&b, token.NewFileSet(), t)
+	return field{}, fmt.Errorf("unsupported type %s", b.String())
+}
+
+// generator writes the generated code.
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+	n       int // counter of the temporary variables
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// use records the import required by the Go type typ.
+func (g *generator) use(typ string) {
+	switch typ {
+	case "time.Time":
+		g.imports["time"] = true
+	case "dir.DIR":
+		g.imports[dirPath] = true
+	}
+}
+
+// generate returns the source of the IDR encoding methods of the struct
+// types named typeNames in the package in the directory dir.
+func generate(dir string, typeNames []string) ([]byte, error) {
+	p, err := parsePackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	g := generator{imports: map[string]bool{lowPath: true}}
+	for _, name := range typeNames {
+		fields, err := p.fields(name, typeNames)
+		if err != nil {
+			return nil, err
+		}
+		g.appendMethod(name, fields)
+		g.decodeMethod(name, fields)
+		g.sizeMethod(name, fields)
+	}
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by idrgen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", p.name)
+	// standard library imports first
+	var std, other []string
+	for path := range g.imports {
+		if strings.Contains(path, ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	slices.Sort(std)
+	slices.Sort(other)
+	for _, path := range std {
+		fmt.Fprintf(&src, "\t%q\n", path)
+	}
+	if len(std) > 0 {
+		src.WriteString("\n")
+	}
+	for _, path := range other {
+		fmt.Fprintf(&src, "\t%q\n", path)
+	}
+	src.WriteString(")\n")
+	src.Write(g.buf.Bytes())
+	return format.Source(src.Bytes())
+}
+
+func (g *generator) appendMethod(name string, fields []field) {
+	g.printf("\n// AppendIDR appends the IDR encoding of v.\n")
+	g.printf("func (v *%s) AppendIDR(e low.Encoder) low.Encoder {\n", name)
+	for _, f := range fields {
+		switch {
+		case f.record && f.slice:
+			g.printf("e = low.AppendSize(e, uint64(len(v.%s)))\n", f.name)
+			g.printf("for i := range v.%s {\ne = v.%[1]s[i].AppendIDR(e)\n}\n", f.name)
+		case f.record:
+			g.printf("e = v.%s.AppendIDR(e)\n", f.name)
+		case f.slice:
+			g.printf("e = low.AppendSize(e, uint64(len(v.%s)))\n", f.name)
+			g.printf("for _, x := range v.%s {\n", f.name)
+			g.printf("e = low.Append%s(e, %s)\n}\n", f.prim.name, f.toPrim("x"))
+		default:
+			g.printf("e = low.Append%s(e, %s)\n", f.prim.name, f.toPrim("v."+f.name))
+		}
+	}
+	g.printf("return e\n}\n")
+}
+
+func (g *generator) decodeMethod(name string, fields []field) {
+	g.printf("\n// DecodeIDR decodes v from the front of d and returns the remaining\n")
+	g.printf("// bytes. Panics if the data is invalid or truncated.\n")
+	g.printf("func (v *%s) DecodeIDR(d low.Decoder) low.Decoder {\n", name)
+	for _, f := range fields {
+		args := "d"
+		if f.prim.max != "" {
+			args += ", " + f.prim.max
+		}
+		switch {
+		case f.slice:
+			g.n++
+			g.printf("d, n%d := low.Size(d)\n", g.n)
+			g.printf("v.%s = v.%[1]s[:0]\n", f.name)
+			g.printf("for i := uint64(0); i < n%d; i++ {\n", g.n)
+			if f.record {
+				g.printf("v.%s = append(v.%[1]s, %s{})\n", f.name, f.typ)
+				g.printf("d = v.%s[len(v.%[1]s)-1].DecodeIDR(d)\n}\n", f.name)
+				break
+			}
+			g.use(f.prim.typ)
+			g.printf("var x %s\n", f.prim.typ)
+			g.printf("d, x = low.%s(%s)\n", f.prim.name, args)
+			g.use(f.typ)
+			g.printf("v.%s = append(v.%[1]s, %s)\n}\n", f.name, f.fromPrim("x"))
+		case f.record:
+			g.printf("d = v.%s.DecodeIDR(d)\n", f.name)
+		case f.typ == f.prim.typ:
+			g.printf("d, v.%s = low.%s(%s)\n", f.name, f.prim.name, args)
+		default:
+			g.n++
+			g.use(f.typ)
+			g.printf("d, x%d := low.%s(%s)\n", g.n, f.prim.name, args)
+			g.printf("v.%s = %s\n", f.name, f.fromPrim(fmt.Sprintf("x%d", g.n)))
+		}
+	}
+	g.printf("return d\n}\n")
+	g.n = 0
+}
+
+func (g *generator) sizeMethod(name string, fields []field) {
+	g.printf("\n// SizeIDR returns the byte size of the IDR encoding of v.\n")
+	g.printf("func (v *%s) SizeIDR() int {\n", name)
+	g.printf("n := 0\n")
+	for _, f := range fields {
+		switch {
+		case f.record && f.slice:
+			g.printf("n += low.SizeSize(len(v.%s))\n", f.name)
+			g.printf("for i := range v.%s {\nn += v.%[1]s[i].SizeIDR()\n}\n", f.name)
+		case f.record:
+			g.printf("n += v.%s.SizeIDR()\n", f.name)
+		case f.slice && f.prim.sized:
+			g.printf("n += low.SizeSize(len(v.%s))\n", f.name)
+			g.printf("for _, x := range v.%s {\n", f.name)
+			g.printf("n += low.Size%s(%s)\n}\n", f.prim.name, f.toPrim("x"))
+		case f.slice:
+			g.printf("n += low.SizeSize(len(v.%s)) + len(v.%[1]s)*low.Size%s()\n", f.name, f.prim.name)
+		case f.prim.sized:
+			g.printf("n += low.Size%s(%s)\n", f.prim.name, f.toPrim("v."+f.name))
+		default:
+			g.printf("n += low.Size%s()\n", f.prim.name)
+		}
+	}
+	g.printf("return n\n}\n")
+}
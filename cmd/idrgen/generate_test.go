@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestGolden(t *testing.T) {
+	golden := filepath.Join("internal", "example", "smallstruct_idr.go")
+	src, err := generate(filepath.Dir(golden), []string{"SmallStruct", "All", "Item"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *update {
+		if err := os.WriteFile(golden, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, exp) {
+		t.Errorf("generated code differs from %s, run go generate or go test -update", golden)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		src   string
+		types []string
+		err   string
+	}{
+		// 0
+		{src: "type A struct{}", types: []string{"B"}, err: "type B not found"},
+		{src: "type A int", types: []string{"A"}, err: "type A is not a struct"},
+		{src: "type A struct{ X map[int]int }", types: []string{"A"}, err: "A.X: unsupported type map[int]int"},
+		{src: "type A struct{ X *int }", types: []string{"A"}, err: "A.X: unsupported type *int"},
+		{src: "type A struct{ X B }\ntype B struct{}", types: []string{"A"}, err: "A.X: unsupported type B"},
+		// 5
+		{src: "type A struct{ X [][]int }", types: []string{"A"}, err: "A.X: unsupported type [][]int"},
+		{src: "type A struct{ X [2]int }", types: []string{"A"}, err: "A.X: unsupported type [2]int"},
+		{src: "type A struct{ X int `idr:\"1,zigzag\"` }", types: []string{"A"}, err: "A.X: invalid struct tag: option \"zigzag\""},
+		{src: "type A struct{ *B }\ntype B struct{}", types: []string{"A"}, err: "A: unsupported embedded field"},
+		{src: "type A struct{ X int", types: []string{"A"}, err: "expected"},
+		// 10
+		{src: "type A struct{ X int `idr:\",fixed\"` }", types: []string{"A"}, err: "A.X: invalid struct tag: field number \"\""},
+		{src: "type A struct{ X int `idr:\"0\"` }", types: []string{"A"}, err: "A.X: invalid struct tag: field number \"0\""},
+		{src: "type A struct{ X, Y int `idr:\"1\"` }", types: []string{"A"}, err: "A.Y: invalid struct tag: field number 1 is not unique"},
+		{src: "type A struct{ x int `idr:\"1\"` }", types: []string{"A"}, err: "A.x: invalid struct tag: field is not exported"},
+	}
+	for i, test := range tests {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\n"+test.src+"\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = generate(dir, test.types)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%d expect error %q, got %v", i, test.err, err)
+		}
+	}
+	if _, err := generate(t.TempDir(), []string{"A"}); err == nil {
+		t.Error("expect error with empty directory")
+	}
+}
@@ -0,0 +1,57 @@
+package example
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/chmike/ditp/idr/low"
+)
+
+func randString(l int) string {
+	buf := make([]byte, l)
+	for i := 0; i < (l+1)/2; i++ {
+		buf[i] = byte(rand.Intn(256))
+	}
+	return fmt.Sprintf("%x", buf)[:l]
+}
+
+func generateSmallStruct() []*SmallStruct {
+	a := make([]*SmallStruct, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		a = append(a, &SmallStruct{
+			Name:     randString(16),
+			BirthDay: time.Now(),
+			Phone:    randString(10),
+			Siblings: rand.Intn(5),
+			Spouse:   rand.Intn(2) == 1,
+			Money:    rand.Float64(),
+		})
+	}
+	return a
+}
+
+var e low.Encoder
+
+func BenchmarkAppendIDR(b *testing.B) {
+	data := generateSmallStruct()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		e = data[rand.Intn(len(data))].AppendIDR(make([]byte, 0, 64))
+	}
+}
+
+var a2 SmallStruct
+
+func BenchmarkDecodeIDR(b *testing.B) {
+	src := generateSmallStruct()
+	encoded := make([][]byte, len(src))
+	for i, v := range src {
+		encoded[i] = v.AppendIDR(nil)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		a2.DecodeIDR(low.Decoder(encoded[rand.Intn(len(encoded))]))
+	}
+}
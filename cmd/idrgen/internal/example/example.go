@@ -0,0 +1,74 @@
+// Package example holds struct types whose IDR encoding methods are
+// generated by idrgen. The generated file is also the golden file of the
+// idrgen tests.
+package example
+
+import (
+	"time"
+
+	"github.com/chmike/ditp/dir"
+)
+
+//go:generate go run ../.. -type SmallStruct,All,Item
+
+// SmallStruct is the struct of the serialization benchmarks. Its encoding
+// is the one of the hand written benchmark code of the idr/low package.
+type SmallStruct struct {
+	Name     string    `idr:"1"`
+	BirthDay time.Time `idr:"2,micro"`
+	Phone    string    `idr:"3"`
+	Siblings int       `idr:"4"`
+	Spouse   bool      `idr:"5"`
+	Money    float64   `idr:"6"`
+}
+
+// Level is a type defined with a basic underlying type.
+type Level uint8
+
+// Item is a struct used as a field of All.
+type Item struct {
+	ID    uint64 `idr:"1"`
+	Label string `idr:"2"`
+}
+
+// All has fields of all the supported types.
+type All struct {
+	Bool       bool
+	Int        int
+	Int8       int8
+	Int16      int16
+	Int32      int32
+	Int64      int64
+	Uint       uint
+	Uint8      uint8
+	Uint16     uint16
+	Uint32     uint32
+	Uint64     uint64
+	Float32    float32
+	Float64    float64
+	Complex64  complex64
+	Complex128 complex128
+	Byte       byte
+	Rune       rune
+	String     string
+	Blob       []byte
+	DIR        dir.DIR
+	Time       time.Time
+	FixedTime  time.Time `idr:"1,fixed"`
+	VarInt16   int16     `idr:"2,varint"`
+	FixedInt   int       `idr:"3,fixed"`
+	VarFloat   float32   `idr:"4,varint"`
+	Level      Level
+	VarLevel   Level `idr:"5,varint"`
+	Item       Item
+	Items      []Item
+	Strings    []string
+	Int32s     []int32
+	Levels     []Level `idr:"6,varint"`
+	Times      []time.Time
+	MicroTimes []time.Time `idr:"7,micro"`
+	DIRs       []dir.DIR
+	Blobs      [][]byte
+	Ignored    int `idr:"-"`
+	private    int
+}
@@ -0,0 +1,112 @@
+package example
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+	"github.com/chmike/ditp/idr"
+	"github.com/chmike/ditp/idr/low"
+)
+
+// encodeEx and decodeEx are the hand written code of the benchmarks of the
+// idr/low package.
+func encodeEx(a *SmallStruct) low.Encoder {
+	e := make([]byte, 0, 64)
+	e = low.AppendString(e, a.Name)
+	e = low.AppendInt64(e, a.BirthDay.UnixMicro())
+	e = low.AppendString(e, a.Phone)
+	e = low.AppendVarInt(e, a.Siblings)
+	e = low.AppendBool(e, a.Spouse)
+	e = low.AppendFloat64(e, a.Money)
+	return e
+}
+
+func decodeEx(d low.Decoder, a *SmallStruct) low.Decoder {
+	d, a.Name = low.String(d, 255)
+	d, tmp1 := low.Int64(d)
+	a.BirthDay = time.UnixMicro(tmp1)
+	d, a.Phone = low.String(d, 255)
+	d, a.Siblings = low.VarInt(d)
+	d, a.Spouse = low.Bool(d)
+	d, a.Money = low.Float64(d)
+	return d
+}
+
+func TestSmallStruct(t *testing.T) {
+	s := SmallStruct{
+		Name:     "benchmark",
+		BirthDay: time.UnixMicro(1700000000123456),
+		Phone:    "709-345678",
+		Siblings: 3,
+		Spouse:   true,
+		Money:    10000,
+	}
+	// same encoding as the hand written code
+	exp := encodeEx(&s)
+	e := s.AppendIDR(nil)
+	if !bytes.Equal(e, exp) {
+		t.Errorf("expect %#v, got %#v", exp, e)
+	}
+	if s.SizeIDR() != len(e) {
+		t.Errorf("expect size %d, got %d", len(e), s.SizeIDR())
+	}
+	var s2, s3 SmallStruct
+	if d := s2.DecodeIDR(low.Decoder(e)); len(d) != 0 || !reflect.DeepEqual(s, s2) {
+		t.Errorf("expect %+v, got %+v", s, s2)
+	}
+	if decodeEx(low.Decoder(e), &s3); !reflect.DeepEqual(s2, s3) {
+		t.Errorf("expect %+v, got %+v", s3, s2)
+	}
+}
+
+func TestAll(t *testing.T) {
+	now := time.Unix(1700000000, 123).In(time.FixedZone("", 3600))
+	a := All{
+		Bool: true, Int: -1, Int8: -2, Int16: -3, Int32: -4, Int64: -5,
+		Uint: 1, Uint8: 2, Uint16: 3, Uint32: 4, Uint64: 5,
+		Float32: 1.5, Float64: 2.5, Complex64: complex(1, 2), Complex128: complex(3, 4),
+		Byte: 'b', Rune: 'r', String: "string", Blob: []byte{1, 2},
+		DIR: dir.MustMake(1, 2, 0), Time: now, FixedTime: now.Truncate(time.Microsecond),
+		VarInt16: -300, FixedInt: 7, VarFloat: 0.5, Level: 8, VarLevel: 9,
+		Item:       Item{ID: 10, Label: "item"},
+		Items:      []Item{{ID: 11}, {Label: "twelve"}},
+		Strings:    []string{"a", "", "c"},
+		Int32s:     []int32{-1, 1},
+		Levels:     []Level{1, 2, 3},
+		Times:      []time.Time{now, now.Add(time.Hour)},
+		MicroTimes: []time.Time{time.UnixMicro(1), time.UnixMicro(-1)},
+		DIRs:       []dir.DIR{dir.MustMake(1, 0)},
+		Blobs:      [][]byte{{1}, {}},
+	}
+	e := a.AppendIDR(nil)
+	if a.SizeIDR() != len(e) {
+		t.Errorf("expect size %d, got %d", len(e), a.SizeIDR())
+	}
+	b := All{Ignored: 1, Levels: []Level{5, 5, 5, 5}}
+	if d := b.DecodeIDR(low.Decoder(e)); len(d) != 0 {
+		t.Errorf("expect no bytes left, got %d", len(d))
+	}
+	b.Ignored = 0
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("expect %+v, got %+v", a, b)
+	}
+}
+
+func TestAllMarshal(t *testing.T) {
+	// the idr package accepts the struct tags and encodes the tagged fields
+	a := All{FixedTime: time.Unix(1700000000, 0).UTC(), VarInt16: -300, FixedInt: 7, VarFloat: 0.5, VarLevel: 9, Levels: []Level{1, 2}, MicroTimes: []time.Time{time.UnixMicro(5)}}
+	e, err := idr.Marshal(&a)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var b All
+	if err := idr.Unmarshal(e, &b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("expect %+v, got %+v", a, b)
+	}
+}
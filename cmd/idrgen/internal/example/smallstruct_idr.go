@@ -0,0 +1,288 @@
+// Code generated by idrgen; DO NOT EDIT.
+
+package example
+
+import (
+	"time"
+
+	"github.com/chmike/ditp/dir"
+	"github.com/chmike/ditp/idr/low"
+)
+
+// AppendIDR appends the IDR encoding of v.
+func (v *SmallStruct) AppendIDR(e low.Encoder) low.Encoder {
+	e = low.AppendString(e, v.Name)
+	e = low.AppendInt64(e, v.BirthDay.UnixMicro())
+	e = low.AppendString(e, v.Phone)
+	e = low.AppendVarInt(e, v.Siblings)
+	e = low.AppendBool(e, v.Spouse)
+	e = low.AppendFloat64(e, v.Money)
+	return e
+}
+
+// DecodeIDR decodes v from the front of d and returns the remaining
+// bytes. Panics if the data is invalid or truncated.
+func (v *SmallStruct) DecodeIDR(d low.Decoder) low.Decoder {
+	d, v.Name = low.String(d, low.DefaultMaxStringSize)
+	d, x1 := low.Int64(d)
+	v.BirthDay = time.UnixMicro(x1)
+	d, v.Phone = low.String(d, low.DefaultMaxStringSize)
+	d, v.Siblings = low.VarInt(d)
+	d, v.Spouse = low.Bool(d)
+	d, v.Money = low.Float64(d)
+	return d
+}
+
+// SizeIDR returns the byte size of the IDR encoding of v.
+func (v *SmallStruct) SizeIDR() int {
+	n := 0
+	n += low.SizeString(v.Name)
+	n += low.SizeInt64()
+	n += low.SizeString(v.Phone)
+	n += low.SizeVarInt(v.Siblings)
+	n += low.SizeBool()
+	n += low.SizeFloat64()
+	return n
+}
+
+// AppendIDR appends the IDR encoding of v.
+func (v *All) AppendIDR(e low.Encoder) low.Encoder {
+	e = low.AppendBool(e, v.Bool)
+	e = low.AppendVarInt(e, v.Int)
+	e = low.AppendInt8(e, v.Int8)
+	e = low.AppendInt16(e, v.Int16)
+	e = low.AppendInt32(e, v.Int32)
+	e = low.AppendVarInt64(e, v.Int64)
+	e = low.AppendVarUint(e, v.Uint)
+	e = low.AppendUint8(e, v.Uint8)
+	e = low.AppendUint16(e, v.Uint16)
+	e = low.AppendUint32(e, v.Uint32)
+	e = low.AppendVarUint64(e, v.Uint64)
+	e = low.AppendFloat32(e, v.Float32)
+	e = low.AppendFloat64(e, v.Float64)
+	e = low.AppendComplex64(e, v.Complex64)
+	e = low.AppendComplex128(e, v.Complex128)
+	e = low.AppendUint8(e, v.Byte)
+	e = low.AppendInt32(e, v.Rune)
+	e = low.AppendString(e, v.String)
+	e = low.AppendBlob(e, v.Blob)
+	e = low.AppendDIR(e, v.DIR)
+	e = low.AppendVarTime(e, v.Time)
+	e = low.AppendTime(e, v.FixedTime)
+	e = low.AppendVarInt64(e, int64(v.VarInt16))
+	e = low.AppendInt64(e, int64(v.FixedInt))
+	e = low.AppendVarFloat(e, float64(v.VarFloat))
+	e = low.AppendUint8(e, uint8(v.Level))
+	e = low.AppendVarUint64(e, uint64(v.VarLevel))
+	e = v.Item.AppendIDR(e)
+	e = low.AppendSize(e, uint64(len(v.Items)))
+	for i := range v.Items {
+		e = v.Items[i].AppendIDR(e)
+	}
+	e = low.AppendSize(e, uint64(len(v.Strings)))
+	for _, x := range v.Strings {
+		e = low.AppendString(e, x)
+	}
+	e = low.AppendSize(e, uint64(len(v.Int32s)))
+	for _, x := range v.Int32s {
+		e = low.AppendInt32(e, x)
+	}
+	e = low.AppendSize(e, uint64(len(v.Levels)))
+	for _, x := range v.Levels {
+		e = low.AppendVarUint64(e, uint64(x))
+	}
+	e = low.AppendSize(e, uint64(len(v.Times)))
+	for _, x := range v.Times {
+		e = low.AppendVarTime(e, x)
+	}
+	e = low.AppendSize(e, uint64(len(v.MicroTimes)))
+	for _, x := range v.MicroTimes {
+		e = low.AppendInt64(e, x.UnixMicro())
+	}
+	e = low.AppendSize(e, uint64(len(v.DIRs)))
+	for _, x := range v.DIRs {
+		e = low.AppendDIR(e, x)
+	}
+	e = low.AppendSize(e, uint64(len(v.Blobs)))
+	for _, x := range v.Blobs {
+		e = low.AppendBlob(e, x)
+	}
+	return e
+}
+
+// DecodeIDR decodes v from the front of d and returns the remaining
+// bytes. Panics if the data is invalid or truncated.
+func (v *All) DecodeIDR(d low.Decoder) low.Decoder {
+	d, v.Bool = low.Bool(d)
+	d, v.Int = low.VarInt(d)
+	d, v.Int8 = low.Int8(d)
+	d, v.Int16 = low.Int16(d)
+	d, v.Int32 = low.Int32(d)
+	d, v.Int64 = low.VarInt64(d)
+	d, v.Uint = low.VarUint(d)
+	d, v.Uint8 = low.Uint8(d)
+	d, v.Uint16 = low.Uint16(d)
+	d, v.Uint32 = low.Uint32(d)
+	d, v.Uint64 = low.VarUint64(d)
+	d, v.Float32 = low.Float32(d)
+	d, v.Float64 = low.Float64(d)
+	d, v.Complex64 = low.Complex64(d)
+	d, v.Complex128 = low.Complex128(d)
+	d, v.Byte = low.Uint8(d)
+	d, v.Rune = low.Int32(d)
+	d, v.String = low.String(d, low.DefaultMaxStringSize)
+	d, v.Blob = low.Blob(d, low.DefaultMaxBlobSize)
+	d, v.DIR = low.DIR(d)
+	d, v.Time = low.VarTime(d)
+	d, v.FixedTime = low.Time(d)
+	d, x1 := low.VarInt64(d)
+	v.VarInt16 = int16(x1)
+	d, x2 := low.Int64(d)
+	v.FixedInt = int(x2)
+	d, x3 := low.VarFloat(d)
+	v.VarFloat = float32(x3)
+	d, x4 := low.Uint8(d)
+	v.Level = Level(x4)
+	d, x5 := low.VarUint64(d)
+	v.VarLevel = Level(x5)
+	d = v.Item.DecodeIDR(d)
+	d, n6 := low.Size(d)
+	v.Items = v.Items[:0]
+	for i := uint64(0); i < n6; i++ {
+		v.Items = append(v.Items, Item{})
+		d = v.Items[len(v.Items)-1].DecodeIDR(d)
+	}
+	d, n7 := low.Size(d)
+	v.Strings = v.Strings[:0]
+	for i := uint64(0); i < n7; i++ {
+		var x string
+		d, x = low.String(d, low.DefaultMaxStringSize)
+		v.Strings = append(v.Strings, x)
+	}
+	d, n8 := low.Size(d)
+	v.Int32s = v.Int32s[:0]
+	for i := uint64(0); i < n8; i++ {
+		var x int32
+		d, x = low.Int32(d)
+		v.Int32s = append(v.Int32s, x)
+	}
+	d, n9 := low.Size(d)
+	v.Levels = v.Levels[:0]
+	for i := uint64(0); i < n9; i++ {
+		var x uint64
+		d, x = low.VarUint64(d)
+		v.Levels = append(v.Levels, Level(x))
+	}
+	d, n10 := low.Size(d)
+	v.Times = v.Times[:0]
+	for i := uint64(0); i < n10; i++ {
+		var x time.Time
+		d, x = low.VarTime(d)
+		v.Times = append(v.Times, x)
+	}
+	d, n11 := low.Size(d)
+	v.MicroTimes = v.MicroTimes[:0]
+	for i := uint64(0); i < n11; i++ {
+		var x int64
+		d, x = low.Int64(d)
+		v.MicroTimes = append(v.MicroTimes, time.UnixMicro(x))
+	}
+	d, n12 := low.Size(d)
+	v.DIRs = v.DIRs[:0]
+	for i := uint64(0); i < n12; i++ {
+		var x dir.DIR
+		d, x = low.DIR(d)
+		v.DIRs = append(v.DIRs, x)
+	}
+	d, n13 := low.Size(d)
+	v.Blobs = v.Blobs[:0]
+	for i := uint64(0); i < n13; i++ {
+		var x []byte
+		d, x = low.Blob(d, low.DefaultMaxBlobSize)
+		v.Blobs = append(v.Blobs, x)
+	}
+	return d
+}
+
+// SizeIDR returns the byte size of the IDR encoding of v.
+func (v *All) SizeIDR() int {
+	n := 0
+	n += low.SizeBool()
+	n += low.SizeVarInt(v.Int)
+	n += low.SizeInt8()
+	n += low.SizeInt16()
+	n += low.SizeInt32()
+	n += low.SizeVarInt64(v.Int64)
+	n += low.SizeVarUint(v.Uint)
+	n += low.SizeUint8()
+	n += low.SizeUint16()
+	n += low.SizeUint32()
+	n += low.SizeVarUint64(v.Uint64)
+	n += low.SizeFloat32()
+	n += low.SizeFloat64()
+	n += low.SizeComplex64()
+	n += low.SizeComplex128()
+	n += low.SizeUint8()
+	n += low.SizeInt32()
+	n += low.SizeString(v.String)
+	n += low.SizeBlob(v.Blob)
+	n += low.SizeDIR(v.DIR)
+	n += low.SizeVarTime(v.Time)
+	n += low.SizeTime()
+	n += low.SizeVarInt64(int64(v.VarInt16))
+	n += low.SizeInt64()
+	n += low.SizeVarFloat(float64(v.VarFloat))
+	n += low.SizeUint8()
+	n += low.SizeVarUint64(uint64(v.VarLevel))
+	n += v.Item.SizeIDR()
+	n += low.SizeSize(len(v.Items))
+	for i := range v.Items {
+		n += v.Items[i].SizeIDR()
+	}
+	n += low.SizeSize(len(v.Strings))
+	for _, x := range v.Strings {
+		n += low.SizeString(x)
+	}
+	n += low.SizeSize(len(v.Int32s)) + len(v.Int32s)*low.SizeInt32()
+	n += low.SizeSize(len(v.Levels))
+	for _, x := range v.Levels {
+		n += low.SizeVarUint64(uint64(x))
+	}
+	n += low.SizeSize(len(v.Times))
+	for _, x := range v.Times {
+		n += low.SizeVarTime(x)
+	}
+	n += low.SizeSize(len(v.MicroTimes)) + len(v.MicroTimes)*low.SizeInt64()
+	n += low.SizeSize(len(v.DIRs))
+	for _, x := range v.DIRs {
+		n += low.SizeDIR(x)
+	}
+	n += low.SizeSize(len(v.Blobs))
+	for _, x := range v.Blobs {
+		n += low.SizeBlob(x)
+	}
+	return n
+}
+
+// AppendIDR appends the IDR encoding of v.
+func (v *Item) AppendIDR(e low.Encoder) low.Encoder {
+	e = low.AppendVarUint64(e, v.ID)
+	e = low.AppendString(e, v.Label)
+	return e
+}
+
+// DecodeIDR decodes v from the front of d and returns the remaining
+// bytes. Panics if the data is invalid or truncated.
+func (v *Item) DecodeIDR(d low.Decoder) low.Decoder {
+	d, v.ID = low.VarUint64(d)
+	d, v.Label = low.String(d, low.DefaultMaxStringSize)
+	return d
+}
+
+// SizeIDR returns the byte size of the IDR encoding of v.
+func (v *Item) SizeIDR() int {
+	n := 0
+	n += low.SizeVarUint64(v.ID)
+	n += low.SizeString(v.Label)
+	return n
+}
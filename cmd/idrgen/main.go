@@ -0,0 +1,80 @@
+// Idrgen generates the IDR encoding methods of Go struct types.
+//
+// Usage:
+//
+//	idrgen -type T1,T2 [-output file] [directory]
+//
+// For each struct type, idrgen generates the methods
+//
+//	func (v *T) AppendIDR(e low.Encoder) low.Encoder
+//	func (v *T) DecodeIDR(d low.Decoder) low.Decoder
+//	func (v *T) SizeIDR() int
+//
+// calling the idr/low encoding functions directly. The exported fields
+// are encoded in sequence in their declaration order, without tags, as
+// with hand written code. This is not the record encoding of idr.Marshal.
+// Fields with the struct tag `idr:"-"` are ignored. The idr struct tags
+// are those of the idr package, checked with idr.ParseTag, so that the
+// struct types may also be encoded with idr.Marshal. The field numbers
+// must be unique but are not encoded. The option varint, as in
+// `idr:"1,varint"`, selects the compact Var encoding of integers, floats
+// and complex numbers, the option fixed the fixed size encoding of
+// integers and times, and the option micro the encoding of times as the
+// Int64 number of microseconds since the Unix epoch. Strings and byte
+// slices are decoded with the maximum sizes low.DefaultMaxStringSize and
+// low.DefaultMaxBlobSize.
+//
+// The supported field types are booleans, integers, floats, complex
+// numbers, strings, []byte, dir.DIR, time.Time, the types of the package
+// defined with one of them as underlying type, the struct types for which
+// methods are generated, and slices of these types. Slices are encoded
+// as their length followed by their elements.
+//
+// The generated file is written in the package directory and named after
+// the first type, for instance t1_idr.go, unless the -output flag is
+// given. Idrgen is intended to be called by go generate:
+//
+//	//go:generate idrgen -type SmallStruct
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: idrgen -type T1,T2 [-output file] [directory]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	typeNames := flag.String("type", "", "comma separated list of struct type names; required")
+	output := flag.String("output", "", "output file name; default <directory>/<type>_idr.go")
+	flag.Usage = usage
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+	src, err := generate(dir, types)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "idrgen: %v\n", err)
+		os.Exit(1)
+	}
+	name := *output
+	if name == "" {
+		name = filepath.Join(dir, strings.ToLower(types[0])+"_idr.go")
+	}
+	if err := os.WriteFile(name, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "idrgen: %v\n", err)
+		os.Exit(1)
+	}
+}
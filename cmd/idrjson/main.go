@@ -0,0 +1,98 @@
+// Idrjson converts a tagged IDR value to its JSON representation and
+// back.
+//
+// Usage:
+//
+//	idrjson json [-indent] [file]
+//	idrjson idr [file]
+//
+// The json command reads the IDR value of the file or of the standard
+// input and writes its JSON representation to the standard output. The
+// idr command reads a JSON representation and writes the IDR value. The
+// JSON representation is described in the idr/idrjson package. Converting
+// back the JSON of an IDR value yields the same bytes.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chmike/ditp/idr/idrjson"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: idrjson json [-indent] [file]\n       idrjson idr [file]\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("idrjson "+os.Args[1], flag.ExitOnError)
+	fs.Usage = func() {
+		usage()
+		fs.PrintDefaults()
+	}
+	var indent *bool
+	switch os.Args[1] {
+	case "json":
+		indent = fs.Bool("indent", false, "indent the JSON output")
+	case "idr":
+	default:
+		usage()
+		os.Exit(2)
+	}
+	fs.Parse(os.Args[2:])
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := run(os.Args[1], fs.Arg(0), indent != nil && *indent); err != nil {
+		fmt.Fprintf(os.Stderr, "idrjson: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(cmd, name string, indent bool) error {
+	var in []byte
+	var err error
+	if name == "" || name == "-" {
+		in, err = io.ReadAll(os.Stdin)
+	} else {
+		in, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return err
+	}
+	out, err := convert(cmd, in, indent)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// convert converts the input in as specified by the command cmd.
+func convert(cmd string, in []byte, indent bool) ([]byte, error) {
+	if cmd == "idr" {
+		return idrjson.FromJSON(in)
+	}
+	out, err := idrjson.ToJSON(in)
+	if err != nil {
+		return nil, err
+	}
+	if !indent {
+		return append(out, '\n'), nil
+	}
+	var b bytes.Buffer
+	if err := json.Indent(&b, out, "", "  "); err != nil {
+		return nil, err
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
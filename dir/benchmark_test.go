@@ -0,0 +1,75 @@
+package dir
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func generateDIRs() []DIR {
+	a := make([]DIR, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		ids := make([]uint64, 1+rand.Intn(MaxIDs))
+		for j := range ids {
+			ids[j] = 1 + uint64(rand.Intn(8))
+		}
+		if rand.Intn(2) == 1 {
+			ids[len(ids)-1] = 0
+		}
+		a = append(a, MustMake(ids...))
+	}
+	return a
+}
+
+var mpInt Map[int]
+
+func BenchmarkMapSet(b *testing.B) {
+	data := generateDIRs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		mpInt.Set(data[n%len(data)], n)
+	}
+}
+
+var vInt int
+var okBool bool
+
+func BenchmarkMapGet(b *testing.B) {
+	data := generateDIRs()
+	var mp Map[int]
+	for i, d := range data {
+		mp.Set(d, i)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vInt, okBool = mp.Get(data[n%len(data)])
+	}
+}
+
+func BenchmarkMapLongestPrefix(b *testing.B) {
+	data := generateDIRs()
+	var mp Map[int]
+	for i, d := range data {
+		if d.Node() {
+			mp.Set(d, i)
+		}
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, vInt, okBool = mp.LongestPrefix(data[n%len(data)])
+	}
+}
+
+func BenchmarkMapWalk(b *testing.B) {
+	data := generateDIRs()
+	var mp Map[int]
+	for i, d := range data {
+		mp.Set(d, i)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		mp.Walk(func(d DIR, v int) bool {
+			vInt += v
+			return true
+		})
+	}
+}
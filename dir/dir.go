@@ -3,6 +3,7 @@ package dir
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/bits"
 	"strconv"
 	"strings"
@@ -22,12 +23,25 @@ const MaxBinaryLen = MaxIDs * maxBinaryIDLen // = 63
 // maxBinaryIDLen is the maximum byte length of a binary encoded identifier.
 const maxBinaryIDLen = 9
 
+// MaxKeyLen is the maximum byte length of a key encoded DIR.
+const MaxKeyLen = MaxIDs * maxKeyIDLen // = 63
+
+// maxKeyIDLen is the maximum byte length of a key encoded identifier.
+const maxKeyIDLen = 9
+
+// keyMinMulti is the smallest identifier value encoded with multiple bytes
+// in a key.
+const keyMinMulti = 0xF8
+
 // MaxURILen is the maximum byte length of an ASCII encoded DIR.
 const MaxURILen = (maxURIIDLen+1)*MaxIDs + 4 // = 88
 
 // maxURIIDLen is the maximum byte length of an ASCII encoded ID.
 const maxURIIDLen = 11 // = (64 + 2)/6
 
+// uriChars are the characters encoding the 6 bit groups of an ID in a URI.
+const uriChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
+
 // Make returns a DIR with the given identifiers or an error when invalid.
 // A DIR defines a path in a tree like graph from the root toward the leafs.
 // A DIR may have at most MaxDIRLen identifiers. Only the first and last
@@ -70,6 +84,64 @@ func (d DIR) String() string {
 	return b.String()
 }
 
+// Parse parses the human readable representation of a DIR as returned by
+// String (e.g. "dir:1.2345.0"). The DIR must satisfy the same constraints
+// as with Make. The returned error reports the offset of the invalid
+// character in s.
+func Parse[T string | []byte](s T) (d DIR, err error) {
+	if len(s) < 4 || s[0] != 'd' || s[1] != 'i' || s[2] != 'r' || s[3] != ':' {
+		return d, fmt.Errorf("%w: string doesn't start with \"dir:\"", ErrInvalid)
+	}
+	i := 4
+	if i == len(s) {
+		return
+	}
+	prevStart := -1
+	for {
+		if d.d[0] == MaxIDs {
+			return DIR{}, fmt.Errorf("%w: too many identifiers at offset %d", ErrInvalid, i)
+		}
+		start := i
+		var v uint64
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			c := uint64(s[i] - '0')
+			if v > (math.MaxUint64-c)/10 {
+				return DIR{}, fmt.Errorf("%w: identifier %d overflows at offset %d", ErrInvalid, d.d[0], start)
+			}
+			v = v*10 + c
+			i++
+		}
+		if i == start {
+			return DIR{}, fmt.Errorf("%w: invalid character at offset %d", ErrInvalid, i)
+		}
+		if i-start > 1 && s[start] == '0' {
+			return DIR{}, fmt.Errorf("%w: identifier %d is not minimal length at offset %d", ErrInvalid, d.d[0], start)
+		}
+		if d.d[0] > 1 && d.d[d.d[0]] == 0 {
+			return DIR{}, fmt.Errorf("%w: identifier %d is 0 at offset %d", ErrInvalid, d.d[0]-1, prevStart)
+		}
+		d.d[0]++
+		d.d[d.d[0]] = v
+		if i == len(s) {
+			return
+		}
+		if s[i] != '.' {
+			return DIR{}, fmt.Errorf("%w: invalid character at offset %d", ErrInvalid, i)
+		}
+		prevStart = start
+		i++
+	}
+}
+
+// MustParse calls Parse and returns the DIR or panics in case of error.
+func MustParse[T string | []byte](s T) DIR {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
 // Len returns the number of identifiers in d.
 func (d DIR) Len() int {
 	return int(d.d[0])
@@ -108,6 +180,90 @@ func (d DIR) NodeDIR() DIR {
 	return d
 }
 
+// Depth returns the number of node identifiers in the path of d. The root
+// node DIR{0} and the information it contains have depth 0. Returns 0 when
+// d is nil.
+func (d DIR) Depth() int {
+	if d.d[0] == 0 {
+		return 0
+	}
+	return d.Len() - 1
+}
+
+// Parent returns the DIR of the node containing d. When d is an information
+// DIR, it is the same as NodeDIR. An error is returned when d is nil, the
+// root node DIR{0}, or the relative node DIR{0,0} as their parent is unknown.
+func (d DIR) Parent() (DIR, error) {
+	if d.Info() {
+		if d.Nil() {
+			return DIR{}, fmt.Errorf("%w: nil DIR has no parent", ErrInvalid)
+		}
+		return d.NodeDIR(), nil
+	}
+	n := d.Len()
+	if n == 1 || (n == 2 && d.d[1] == 0) {
+		return DIR{}, fmt.Errorf("%w: %v has no parent", ErrInvalid, d)
+	}
+	d.d[n] = 0
+	d.d[n-1] = 0
+	d.d[0]--
+	return d, nil
+}
+
+// Child returns the node DIR of the sub-node nodeID of the node containing d.
+// An error is returned when nodeID is 0, d is nil, or the resulting DIR would
+// have more than MaxIDs identifiers.
+func (d DIR) Child(nodeID uint64) (DIR, error) {
+	if d.Nil() {
+		return DIR{}, fmt.Errorf("%w: nil DIR has no child", ErrInvalid)
+	}
+	if nodeID == 0 {
+		return DIR{}, fmt.Errorf("%w: node identifier is 0", ErrInvalid)
+	}
+	n := d.Len()
+	if n == MaxIDs {
+		return DIR{}, fmt.Errorf("%w: too many identifiers", ErrInvalid)
+	}
+	d.d[n] = nodeID
+	d.d[n+1] = 0
+	d.d[0]++
+	return d, nil
+}
+
+// WithInfo returns the DIR of the information infoID in the node containing
+// d. When infoID is 0, the node DIR is returned. An error is returned when d
+// is nil.
+func (d DIR) WithInfo(infoID uint64) (DIR, error) {
+	if d.Nil() {
+		return DIR{}, fmt.Errorf("%w: nil DIR has no node", ErrInvalid)
+	}
+	d.d[d.d[0]] = infoID
+	return d, nil
+}
+
+// CommonPrefix returns the DIR of the deepest node containing or equal to
+// the nodes containing d and d2. Returns a nil DIR when d or d2 is nil, or
+// when one is relative and the other is absolute.
+func (d DIR) CommonPrefix(d2 DIR) DIR {
+	if d.Nil() || d2.Nil() || d.relative() != d2.relative() {
+		return DIR{}
+	}
+	n := min(d.Len(), d2.Len()) - 1
+	var r DIR
+	for i := 1; i <= n && d.d[i] == d2.d[i]; i++ {
+		r.d[i] = d.d[i]
+		r.d[0]++
+	}
+	r.d[0]++
+	return r
+}
+
+// relative returns true if the path of d starts with 0 and d is not the
+// root node DIR{0} or an information in it.
+func (d DIR) relative() bool {
+	return d.Len() > 1 && d.d[1] == 0
+}
+
 // Prefixes returns true if d is a node DIR and d2 is prefixed with d.
 func (d DIR) Prefixes(d2 DIR) bool {
 	if d.Info() || d.Len()-1 >= d2.Len() {
@@ -121,6 +277,91 @@ func (d DIR) Prefixes(d2 DIR) bool {
 	return d.Len() != d2.Len() || d2.InfoID() != 0
 }
 
+// Resolve returns the DIR rel resolved relative to the node containing d.
+// When rel is absolute or nil, it is returned unchanged. Otherwise, the
+// identifiers following the leading 0 of rel are appended to the node path
+// of d. The relative DIR DIR{0} and DIR{0,0} both resolve to the node
+// containing d. An error is returned when d is nil or the resulting DIR
+// would have more than MaxIDs identifiers.
+func (d DIR) Resolve(rel DIR) (DIR, error) {
+	if rel.Absolute() {
+		return rel, nil
+	}
+	if d.Nil() {
+		return DIR{}, fmt.Errorf("%w: nil base DIR", ErrInvalid)
+	}
+	p := d.Len() - 1
+	n := rel.Len() - 1
+	if n == 0 {
+		return d.NodeDIR(), nil
+	}
+	if p+n > MaxIDs {
+		return DIR{}, fmt.Errorf("%w: too many identifiers", ErrInvalid)
+	}
+	var r DIR
+	copy(r.d[1:], d.d[1:p+1])
+	copy(r.d[p+1:], rel.d[2:n+2])
+	r.d[0] = uint64(p + n)
+	return r, nil
+}
+
+// Rel returns the shortest relative DIR r such that d.Resolve(r) returns
+// target. target must be an absolute DIR located below the node containing
+// d. When target is the node containing d, DIR{0,0} is returned.
+func (d DIR) Rel(target DIR) (DIR, error) {
+	if d.Nil() {
+		return DIR{}, fmt.Errorf("%w: nil base DIR", ErrInvalid)
+	}
+	if target.Nil() || !target.Absolute() {
+		return DIR{}, fmt.Errorf("%w: target is not an absolute DIR", ErrInvalid)
+	}
+	p := d.Len() - 1
+	n := target.Len()
+	if n <= p {
+		return DIR{}, fmt.Errorf("%w: target is not below base", ErrInvalid)
+	}
+	for i := 1; i <= p; i++ {
+		if d.d[i] != target.d[i] {
+			return DIR{}, fmt.Errorf("%w: target is not below base", ErrInvalid)
+		}
+	}
+	if n-p+1 > MaxIDs {
+		return DIR{}, fmt.Errorf("%w: too many identifiers", ErrInvalid)
+	}
+	var r DIR
+	copy(r.d[2:], target.d[p+1:n+1])
+	r.d[0] = uint64(n - p + 1)
+	return r, nil
+}
+
+// Compare returns -1 if a sorts before b, 0 if a equals b, and +1 if a
+// sorts after b. It can be used with slices.SortFunc.
+//
+// DIRs are sorted in depth first tree order. The identifiers are compared
+// in sequence and when one DIR is a prefix of the other, the shortest
+// sorts first. As a consequence, a node DIR sorts before its content, and
+// the content of a node is a contiguous range of DIRs. The nil DIR sorts
+// first, and relative DIRs, starting with 0, sort between the root node
+// DIR{0} and the other absolute DIRs.
+func Compare(a, b DIR) int {
+	n := min(a.Len(), b.Len())
+	for i := 1; i <= n; i++ {
+		if a.d[i] != b.d[i] {
+			if a.d[i] < b.d[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case a.d[0] < b.d[0]:
+		return -1
+	case a.d[0] > b.d[0]:
+		return 1
+	}
+	return 0
+}
+
 // Nil returns true if d is a nil DIR. A nil DIR has no identifiers.
 func (d DIR) Nil() bool {
 	return d.d[0] == 0
@@ -249,6 +490,85 @@ func DecodeBinary(b []byte) (d DIR, err error) {
 	return d, nil
 }
 
+// Key returns d encoded as an order preserving key.
+func (d DIR) Key() []byte {
+	return d.AppendKey(make([]byte, 0, d.KeySize()))
+}
+
+// AppendKey appends d encoded as an order preserving key to b. The
+// bytewise order of keys is the same as the order defined by Compare, and
+// the key of a node DIR without its last byte prefixes the keys of all
+// the DIRs it contains. Each identifier v is encoded as a single byte when
+// v < 0xF8. Otherwise it is encoded as the byte 0xF7+n followed by the n
+// bytes of v in big endian order, where n is the minimal number of bytes.
+func (d DIR) AppendKey(b []byte) []byte {
+	n := d.Len()
+	if n == 0 {
+		return b
+	}
+	if b == nil {
+		b = make([]byte, 0, MaxKeyLen)
+	}
+	for i := 1; i <= n; i++ {
+		v := d.d[i]
+		if v < keyMinMulti {
+			b = append(b, byte(v))
+			continue
+		}
+		l := (bits.Len64(v) + 7) / 8
+		b = append(b, byte(keyMinMulti-1+l))
+		for s := (l - 1) * 8; s >= 0; s -= 8 {
+			b = append(b, byte(v>>s))
+		}
+	}
+	return b
+}
+
+// KeySize returns the byte size of the key encoded DIR.
+func (d DIR) KeySize() int {
+	var l int
+	for i := uint64(1); i <= d.d[0]; i++ {
+		l++
+		if v := d.d[i]; v >= keyMinMulti {
+			l += (bits.Len64(v) + 7) / 8
+		}
+	}
+	return l
+}
+
+// DecodeKey decodes the key encoded DIR in b.
+func DecodeKey(b []byte) (d DIR, err error) {
+	for i := 0; i < len(b); {
+		if d.d[0] == MaxIDs {
+			return DIR{}, fmt.Errorf("%w: too many identifiers", ErrInvalid)
+		}
+		v := uint64(b[i])
+		i++
+		if v >= keyMinMulti {
+			l := int(v - keyMinMulti + 1)
+			if i+l > len(b) {
+				return DIR{}, fmt.Errorf("%w: identifier %d is truncated", ErrInvalid, d.d[0])
+			}
+			if (l == 1 && b[i] < keyMinMulti) || (l > 1 && b[i] == 0) {
+				return DIR{}, fmt.Errorf("%w: identifier %d is not minimal length", ErrInvalid, d.d[0])
+			}
+			v = 0
+			for _, c := range b[i : i+l] {
+				v = v<<8 | uint64(c)
+			}
+			i += l
+		}
+		d.d[0]++
+		d.d[d.d[0]] = v
+	}
+	for i := 2; i < int(d.d[0]); i++ {
+		if d.d[i] == 0 {
+			return DIR{}, fmt.Errorf("%w: identifier %d is zero", ErrInvalid, i-1)
+		}
+	}
+	return d, nil
+}
+
 // URI returns d encoded as a URI string.
 func (d DIR) URI() string {
 	return string(d.AppendURI(make([]byte, 0, (maxURIIDLen+1)*d.Len()+4)))
@@ -258,14 +578,13 @@ func (d DIR) URI() string {
 // node DIR ends with '.'. DIR{0} (root/node) the URI "dis:./" and DIR{0,0} (relative
 // node) is the URI "dis:../".
 func (d DIR) AppendURI(b []byte) []byte {
-	const txtChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
 	b = append(b, "dis:"...)
 	n := d.Len()
 	if n > 0 {
 		for i := 1; i <= n; i++ {
 			id := d.d[i]
 			for id != 0 {
-				b = append(b, txtChars[id&0x3F])
+				b = append(b, uriChars[id&0x3F])
 				id >>= 6
 			}
 			b = append(b, '.')
@@ -2,6 +2,8 @@ package dir
 
 import (
 	"bytes"
+	"math/rand"
+	"slices"
 	"testing"
 )
 
@@ -392,3 +394,400 @@ func TestDecodeURI(t *testing.T) {
 
 	DecodeURI("dis:1.2/")
 }
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		o DIR
+		i string
+		e string
+	}{
+		// 0
+		{o: DIR{}, i: "dir:"},
+		{o: m(1), i: "dir:1"},
+		{o: m(0), i: "dir:0"},
+		{o: m(0, 0), i: "dir:0.0"},
+		{o: m(0, 1, 0), i: "dir:0.1.0"},
+		// 5
+		{o: m(1, 2345, 0), i: "dir:1.2345.0"},
+		{o: m(1, 2, 3, 4, 5, 6, 7), i: "dir:1.2.3.4.5.6.7"},
+		{o: m(0xFFFFFFFFFFFFFFFF), i: "dir:18446744073709551615"},
+		{i: "", e: "invalid dir: string doesn't start with \"dir:\""},
+		{i: "dis:1", e: "invalid dir: string doesn't start with \"dir:\""},
+		// 10
+		{i: "dir:18446744073709551616", e: "invalid dir: identifier 0 overflows at offset 4"},
+		{i: "dir:1.2.3.4.5.6.7.8", e: "invalid dir: too many identifiers at offset 18"},
+		{i: "dir:1.0.3", e: "invalid dir: identifier 1 is 0 at offset 6"},
+		{i: "dir:1.02", e: "invalid dir: identifier 1 is not minimal length at offset 6"},
+		{i: "dir:1..2", e: "invalid dir: invalid character at offset 6"},
+		// 15
+		{i: "dir:1.", e: "invalid dir: invalid character at offset 6"},
+		{i: "dir:.1", e: "invalid dir: invalid character at offset 4"},
+		{i: "dir:1,2", e: "invalid dir: invalid character at offset 5"},
+		{i: "dir:1.2 ", e: "invalid dir: invalid character at offset 7"},
+		{i: "dir:-1", e: "invalid dir: invalid character at offset 4"},
+	}
+	for i, test := range tests {
+		d, err := Parse(test.i)
+		var errStr string
+		if err != nil {
+			errStr = err.Error()
+		}
+		if test.e != errStr {
+			t.Errorf("%d expect error %q, got %q", i, test.e, errStr)
+			continue
+		}
+		if test.e != "" {
+			continue
+		}
+		if d != test.o {
+			t.Errorf("%d expect %v, got %v", i, test.o, d)
+		}
+		if s := d.String(); s != test.i {
+			t.Errorf("%d expect string %q, got %q", i, test.i, s)
+		}
+		if d2 := MustParse([]byte(test.i)); d2 != d {
+			t.Errorf("%d expect %v, got %v", i, d, d2)
+		}
+	}
+
+	if !doesPanic(func() {
+		MustParse("dir:1.0.1")
+	}) {
+		t.Error("expect panics")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		b, r, o DIR
+		e       string
+	}{
+		// 0
+		{b: m(1, 2, 0), r: m(0, 3), o: m(1, 2, 3)},
+		{b: m(1, 2, 0), r: m(0, 3, 0), o: m(1, 2, 3, 0)},
+		{b: m(1, 2, 0), r: m(0, 0), o: m(1, 2, 0)},
+		{b: m(1, 2, 0), r: m(0), o: m(1, 2, 0)},
+		{b: m(1, 2, 5), r: m(0, 3, 4), o: m(1, 2, 3, 4)},
+		// 5
+		{b: m(0), r: m(0, 3), o: m(3)},
+		{b: m(0), r: m(0, 0), o: m(0)},
+		{b: m(1, 2, 0), r: m(4, 5), o: m(4, 5)},
+		{b: m(1, 2, 0), r: m(), o: m()},
+		{b: m(), r: m(0, 1), e: "invalid dir: nil base DIR"},
+		// 10
+		{b: m(1, 2, 3, 4, 5, 0), r: m(0, 6, 7), o: m(1, 2, 3, 4, 5, 6, 7)},
+		{b: m(1, 2, 3, 4, 5, 0), r: m(0, 6, 7, 8), e: "invalid dir: too many identifiers"},
+		{b: m(0, 1, 0), r: m(0, 2), o: m(0, 1, 2)},
+	}
+	for i, test := range tests {
+		o, err := test.b.Resolve(test.r)
+		var errStr string
+		if err != nil {
+			errStr = err.Error()
+		}
+		if test.e != errStr {
+			t.Errorf("%d expect error %q, got %q", i, test.e, errStr)
+			continue
+		}
+		if test.e != "" {
+			continue
+		}
+		if o != test.o {
+			t.Errorf("%d expect %v, got %v", i, test.o, o)
+		}
+	}
+}
+
+func TestRel(t *testing.T) {
+	tests := []struct {
+		b, t, o DIR
+		e       string
+	}{
+		// 0
+		{b: m(1, 2, 0), t: m(1, 2, 3), o: m(0, 3)},
+		{b: m(1, 2, 0), t: m(1, 2, 3, 0), o: m(0, 3, 0)},
+		{b: m(1, 2, 0), t: m(1, 2, 0), o: m(0, 0)},
+		{b: m(1, 2, 5), t: m(1, 2, 3, 4), o: m(0, 3, 4)},
+		{b: m(0), t: m(3), o: m(0, 3)},
+		// 5
+		{b: m(0), t: m(0), e: "invalid dir: target is not an absolute DIR"},
+		{b: m(1, 2, 0), t: m(1, 3), e: "invalid dir: target is not below base"},
+		{b: m(1, 2, 0), t: m(1, 0), e: "invalid dir: target is not below base"},
+		{b: m(1, 2, 0), t: m(0, 1), e: "invalid dir: target is not an absolute DIR"},
+		{b: m(1, 2, 0), t: m(), e: "invalid dir: target is not an absolute DIR"},
+		// 10
+		{b: m(), t: m(1), e: "invalid dir: nil base DIR"},
+		{b: m(0), t: m(1, 2, 3, 4, 5, 6, 7), e: "invalid dir: too many identifiers"},
+		{b: m(1, 0), t: m(1, 2, 3, 4, 5, 6, 7), o: m(0, 2, 3, 4, 5, 6, 7)},
+	}
+	for i, test := range tests {
+		o, err := test.b.Rel(test.t)
+		var errStr string
+		if err != nil {
+			errStr = err.Error()
+		}
+		if test.e != errStr {
+			t.Errorf("%d expect error %q, got %q", i, test.e, errStr)
+			continue
+		}
+		if test.e != "" {
+			continue
+		}
+		if o != test.o {
+			t.Errorf("%d expect %v, got %v", i, test.o, o)
+		}
+		r, err := test.b.Resolve(o)
+		if err != nil || r != test.t {
+			t.Errorf("%d expect resolve to %v, got %v (%v)", i, test.t, r, err)
+		}
+	}
+}
+
+func TestNavigation(t *testing.T) {
+	tests := []struct {
+		i        DIR
+		depth    int
+		parent   DIR
+		parentE  string
+		child    DIR
+		childE   string
+		withInfo DIR
+		infoE    string
+	}{
+		// 0
+		{i: m(), parentE: "invalid dir: nil DIR has no parent", childE: "invalid dir: nil DIR has no child", infoE: "invalid dir: nil DIR has no node"},
+		{i: m(0), parentE: "invalid dir: dir:0 has no parent", child: m(9, 0), withInfo: m(8)},
+		{i: m(0, 0), depth: 1, parentE: "invalid dir: dir:0.0 has no parent", child: m(0, 9, 0), withInfo: m(0, 8)},
+		{i: m(5), parent: m(0), child: m(9, 0), withInfo: m(8)},
+		{i: m(1, 0), depth: 1, parent: m(0), child: m(1, 9, 0), withInfo: m(1, 8)},
+		// 5
+		{i: m(1, 2, 0), depth: 2, parent: m(1, 0), child: m(1, 2, 9, 0), withInfo: m(1, 2, 8)},
+		{i: m(1, 2, 3), depth: 2, parent: m(1, 2, 0), child: m(1, 2, 9, 0), withInfo: m(1, 2, 8)},
+		{i: m(0, 1, 0), depth: 2, parent: m(0, 0), child: m(0, 1, 9, 0), withInfo: m(0, 1, 8)},
+		{i: m(1, 2, 3, 4, 5, 6, 0), depth: 6, parent: m(1, 2, 3, 4, 5, 0), childE: "invalid dir: too many identifiers", withInfo: m(1, 2, 3, 4, 5, 6, 8)},
+		{i: m(1, 2, 3, 4, 5, 6), depth: 5, parent: m(1, 2, 3, 4, 5, 0), child: m(1, 2, 3, 4, 5, 9, 0), withInfo: m(1, 2, 3, 4, 5, 8)},
+	}
+	errStr := func(err error) string {
+		if err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	for i, test := range tests {
+		if d := test.i.Depth(); d != test.depth {
+			t.Errorf("%d expect depth %d, got %d", i, test.depth, d)
+		}
+		p, err := test.i.Parent()
+		if e := errStr(err); e != test.parentE {
+			t.Errorf("%d expect parent error %q, got %q", i, test.parentE, e)
+		} else if p != test.parent {
+			t.Errorf("%d expect parent %v, got %v", i, test.parent, p)
+		}
+		c, err := test.i.Child(9)
+		if e := errStr(err); e != test.childE {
+			t.Errorf("%d expect child error %q, got %q", i, test.childE, e)
+		} else if c != test.child {
+			t.Errorf("%d expect child %v, got %v", i, test.child, c)
+		}
+		w, err := test.i.WithInfo(8)
+		if e := errStr(err); e != test.infoE {
+			t.Errorf("%d expect info error %q, got %q", i, test.infoE, e)
+		} else if w != test.withInfo {
+			t.Errorf("%d expect info %v, got %v", i, test.withInfo, w)
+		}
+	}
+
+	if _, err := m(1, 0).Child(0); err == nil {
+		t.Error("expect error for child 0")
+	}
+	if d, _ := m(1, 2).WithInfo(0); d != m(1, 0) {
+		t.Errorf("expect %v, got %v", m(1, 0), d)
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		a, b, r DIR
+	}{
+		// 0
+		{a: m(), b: m(1), r: m()},
+		{a: m(1, 2, 0), b: m(1, 2, 0), r: m(1, 2, 0)},
+		{a: m(1, 2, 0), b: m(1, 2, 3), r: m(1, 2, 0)},
+		{a: m(1, 2, 0), b: m(1, 2), r: m(1, 0)},
+		{a: m(1, 2, 0), b: m(1, 3, 0), r: m(1, 0)},
+		// 5
+		{a: m(1, 0), b: m(2, 0), r: m(0)},
+		{a: m(0), b: m(1, 2, 3), r: m(0)},
+		{a: m(0, 1, 2), b: m(0, 1, 3), r: m(0, 1, 0)},
+		{a: m(0, 1, 0), b: m(0, 2, 0), r: m(0, 0)},
+		{a: m(0, 1, 0), b: m(1, 0), r: m()},
+	}
+	for i, test := range tests {
+		if r := test.a.CommonPrefix(test.b); r != test.r {
+			t.Errorf("%d expect %v, got %v", i, test.r, r)
+		}
+		if r := test.b.CommonPrefix(test.a); r != test.r {
+			t.Errorf("%d expect %v, got %v", i, test.r, r)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	sorted := []DIR{
+		m(),
+		m(0),
+		m(0, 0),
+		m(0, 1),
+		m(0, 1, 0),
+		m(0, 1, 1),
+		m(1),
+		m(1, 0),
+		m(1, 1),
+		m(1, 2, 0),
+		m(1, 2, 1),
+		m(1, 2, 3),
+		m(1, 2, 3, 0),
+		m(1, 2, 3, 4, 0),
+		m(1, 2, 3, 5),
+		m(1, 2, 4),
+		m(2),
+		m(0xFFFFFFFFFFFFFFFF),
+	}
+	for i := range sorted {
+		for j := range sorted {
+			exp := 0
+			if i < j {
+				exp = -1
+			} else if i > j {
+				exp = 1
+			}
+			if r := Compare(sorted[i], sorted[j]); r != exp {
+				t.Errorf("expect %d, got %d for %v compared with %v", exp, r, sorted[i], sorted[j])
+			}
+		}
+	}
+
+	shuffled := slices.Clone(sorted)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	slices.SortFunc(shuffled, Compare)
+	if !slices.Equal(shuffled, sorted) {
+		t.Errorf("expect %v, got %v", sorted, shuffled)
+	}
+
+	// the content of node 1.2 is a contiguous range
+	node := m(1, 2, 0)
+	for i, d := range sorted {
+		in := node.Prefixes(d)
+		if exp := i >= 10 && i <= 15; in != exp {
+			t.Errorf("%d expect %v is in %v to be %v", i, d, node, exp)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	var tests = []struct {
+		i DIR
+		o []byte
+	}{
+		// 0
+		{i: DIR{}, o: nil},
+		{i: m(0), o: []byte{0}},
+		{i: m(0, 0), o: []byte{0, 0}},
+		{i: m(0, 1, 0), o: []byte{0, 1, 0}},
+		{i: m(0xF7), o: []byte{0xF7}},
+		// 5
+		{i: m(0xF8), o: []byte{0xF8, 0xF8}},
+		{i: m(0xFF), o: []byte{0xF8, 0xFF}},
+		{i: m(0x100), o: []byte{0xF9, 0x01, 0x00}},
+		{i: m(0xFFFF), o: []byte{0xF9, 0xFF, 0xFF}},
+		{i: m(0x10000), o: []byte{0xFA, 0x01, 0x00, 0x00}},
+		// 10
+		{i: m(0x0100000000000000), o: []byte{0xFF, 0x01, 0, 0, 0, 0, 0, 0, 0}},
+		{i: m(0xFFFFFFFFFFFFFFFF), o: []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{i: m(1, 2345, 0), o: []byte{1, 0xF9, 0x09, 0x29, 0}},
+	}
+	for i, test := range tests {
+		b := test.i.AppendKey(nil)
+		if !bytes.Equal(test.o, b) {
+			t.Errorf("%d expect %#v, got %#v", i, test.o, b)
+		}
+		if sz := test.i.KeySize(); sz != len(test.o) {
+			t.Errorf("%d expect size %d, got %d", i, len(test.o), sz)
+		}
+		if k := test.i.Key(); !bytes.Equal(k, b) {
+			t.Errorf("%d expect %#v, got %#v", i, b, k)
+		}
+		d, err := DecodeKey(b)
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+		} else if d != test.i {
+			t.Errorf("%d expect %v, got %v", i, test.i, d)
+		}
+	}
+
+	if sz := m(1<<63, 1<<63, 1<<63, 1<<63, 1<<63, 1<<63, 1<<63).KeySize(); sz != MaxKeyLen {
+		t.Errorf("expect size %d, got %d", MaxKeyLen, sz)
+	}
+}
+
+func TestKeyOrder(t *testing.T) {
+	sorted := []DIR{
+		m(),
+		m(0),
+		m(0, 0),
+		m(0, 1, 0),
+		m(1),
+		m(1, 0),
+		m(1, 0xF7),
+		m(1, 0xF8, 0),
+		m(1, 0xF8, 1),
+		m(1, 0xFF),
+		m(1, 0x100, 0),
+		m(1, 0x100, 0xFFFF),
+		m(1, 0x101),
+		m(1, 0xFFFFFFFFFFFFFFFF),
+		m(2),
+		m(0x10000),
+	}
+	for i := range sorted {
+		for j := range sorted {
+			exp := Compare(sorted[i], sorted[j])
+			if r := bytes.Compare(sorted[i].Key(), sorted[j].Key()); r != exp {
+				t.Errorf("expect %d, got %d for keys of %v and %v", exp, r, sorted[i], sorted[j])
+			}
+		}
+	}
+	node := m(1, 0x100, 0)
+	prefix := node.Key()
+	prefix = prefix[:len(prefix)-1]
+	for _, d := range sorted {
+		in := bytes.HasPrefix(d.Key(), prefix)
+		if exp := d == node || node.Prefixes(d); in != exp {
+			t.Errorf("expect %v has key prefix of %v to be %v", d, node, exp)
+		}
+	}
+}
+
+func TestDecodeKey(t *testing.T) {
+	var tests = []struct {
+		i []byte
+		e string
+	}{
+		{i: []byte{0xF9, 0x01}, e: "invalid dir: identifier 0 is truncated"},
+		{i: []byte{1, 0xF8}, e: "invalid dir: identifier 1 is truncated"},
+		{i: []byte{0xF8, 0xF7}, e: "invalid dir: identifier 0 is not minimal length"},
+		{i: []byte{0xF9, 0x00, 0xFF}, e: "invalid dir: identifier 0 is not minimal length"},
+		{i: []byte{1, 2, 3, 4, 5, 6, 7, 8}, e: "invalid dir: too many identifiers"},
+		{i: []byte{1, 0, 3}, e: "invalid dir: identifier 1 is zero"},
+	}
+	for i, test := range tests {
+		_, err := DecodeKey(test.i)
+		var errStr string
+		if err != nil {
+			errStr = err.Error()
+		}
+		if test.e != errStr {
+			t.Errorf("%d expect error %q, got %q", i, test.e, errStr)
+		}
+	}
+}
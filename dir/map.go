@@ -0,0 +1,186 @@
+package dir
+
+import "slices"
+
+// Map is a map with DIR keys organized as a tree of identifiers. In
+// addition to the usual map operations, it supports the longest prefix
+// match and the iteration over the content of a node in the order defined
+// by Compare. Since a DIR has at most MaxIDs identifiers, the depth of the
+// tree is bounded and the operations don't allocate a path.
+//
+// The zero value is an empty map ready to use. A Map is not safe for
+// concurrent use.
+type Map[V any] struct {
+	root mapNode[V]
+	len  int
+}
+
+// mapNode is a node of the Map identifier tree. The children are sorted
+// by increasing identifier.
+type mapNode[V any] struct {
+	ids      []uint64
+	children []*mapNode[V]
+	value    V
+	set      bool
+}
+
+// child returns the child with identifier id or nil if not found.
+func (n *mapNode[V]) child(id uint64) *mapNode[V] {
+	if i, ok := slices.BinarySearch(n.ids, id); ok {
+		return n.children[i]
+	}
+	return nil
+}
+
+// Len returns the number of entries in m.
+func (m *Map[V]) Len() int {
+	return m.len
+}
+
+// find returns the tree node of d or nil if not found.
+func (m *Map[V]) find(d DIR) *mapNode[V] {
+	n := &m.root
+	for i := 1; i <= d.Len() && n != nil; i++ {
+		n = n.child(d.d[i])
+	}
+	return n
+}
+
+// Get returns the value associated to d and true, or the zero value and
+// false if d is not in m.
+func (m *Map[V]) Get(d DIR) (V, bool) {
+	if n := m.find(d); n != nil && n.set {
+		return n.value, true
+	}
+	var v V
+	return v, false
+}
+
+// Set associates the value v to d.
+func (m *Map[V]) Set(d DIR, v V) {
+	n := &m.root
+	for i := 1; i <= d.Len(); i++ {
+		id := d.d[i]
+		j, ok := slices.BinarySearch(n.ids, id)
+		if !ok {
+			n.ids = slices.Insert(n.ids, j, id)
+			n.children = slices.Insert(n.children, j, &mapNode[V]{})
+		}
+		n = n.children[j]
+	}
+	if !n.set {
+		n.set = true
+		m.len++
+	}
+	n.value = v
+}
+
+// Delete removes d from m and returns true if it was in m.
+func (m *Map[V]) Delete(d DIR) bool {
+	var path [MaxIDs + 1]*mapNode[V]
+	path[0] = &m.root
+	l := d.Len()
+	for i := 1; i <= l; i++ {
+		if path[i] = path[i-1].child(d.d[i]); path[i] == nil {
+			return false
+		}
+	}
+	n := path[l]
+	if !n.set {
+		return false
+	}
+	var zero V
+	n.value, n.set = zero, false
+	m.len--
+	for i := l; i > 0 && !path[i].set && len(path[i].ids) == 0; i-- {
+		p := path[i-1]
+		j, _ := slices.BinarySearch(p.ids, d.d[i])
+		p.ids = slices.Delete(p.ids, j, j+1)
+		p.children = slices.Delete(p.children, j, j+1)
+	}
+	return true
+}
+
+// LongestPrefix returns the entry of m with the longest key k such that k
+// equals d or k.Prefixes(d) is true. Returns false if there is no such
+// entry.
+func (m *Map[V]) LongestPrefix(d DIR) (DIR, V, bool) {
+	var k DIR
+	var v V
+	var found bool
+	n := &m.root
+	l := d.Len()
+	for i := 0; i < l; i++ {
+		if c := n.child(0); c != nil && c.set {
+			k = d
+			k.d[0] = uint64(i + 1)
+			k.d[i+1] = 0
+			clear(k.d[i+2:])
+			v, found = c.value, true
+		}
+		if n = n.child(d.d[i+1]); n == nil {
+			return k, v, found
+		}
+	}
+	if n.set {
+		return d, n.value, true
+	}
+	return k, v, found
+}
+
+// Walk calls fn for each entry of m in the order defined by Compare. The
+// iteration stops when fn returns false. Walk returns false if the
+// iteration was stopped. The map must not be modified by fn.
+func (m *Map[V]) Walk(fn func(DIR, V) bool) bool {
+	var d DIR
+	return m.root.walk(&d, fn)
+}
+
+// WalkPrefix calls fn for each entry of m whose key k equals d or
+// d.Prefixes(k) is true, in the order defined by Compare. The iteration
+// stops when fn returns false. WalkPrefix returns false if the iteration
+// was stopped. The map must not be modified by fn.
+func (m *Map[V]) WalkPrefix(d DIR, fn func(DIR, V) bool) bool {
+	if d.Info() {
+		if n := m.find(d); n != nil && n.set {
+			return fn(d, n.value)
+		}
+		return true
+	}
+	d.d[0]--
+	n := m.find(d)
+	if n == nil {
+		return true
+	}
+	for i, c := range n.children {
+		d.d[d.d[0]+1] = n.ids[i]
+		d.d[0]++
+		if !c.walk(&d, fn) {
+			return false
+		}
+		d.d[0]--
+	}
+	return true
+}
+
+// walk calls fn for n and its descendants in depth first order. d is the
+// key of n and is restored on return.
+func (n *mapNode[V]) walk(d *DIR, fn func(DIR, V) bool) bool {
+	if n.set && !fn(*d, n.value) {
+		return false
+	}
+	if len(n.children) == 0 {
+		return true
+	}
+	l := d.d[0] + 1
+	d.d[0] = l
+	for i, c := range n.children {
+		d.d[l] = n.ids[i]
+		if !c.walk(d, fn) {
+			return false
+		}
+	}
+	d.d[l] = 0
+	d.d[0] = l - 1
+	return true
+}
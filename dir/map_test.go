@@ -0,0 +1,137 @@
+package dir
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	var mp Map[int]
+	keys := []DIR{
+		m(1, 2, 3, 4, 5, 6, 7),
+		m(1, 2, 0),
+		m(),
+		m(0),
+		m(1, 2, 3),
+		m(0, 1),
+		m(1, 2, 3, 0),
+		m(2),
+		m(1, 0),
+	}
+	for i, k := range keys {
+		mp.Set(k, i)
+	}
+	if mp.Len() != len(keys) {
+		t.Errorf("expect len %d, got %d", len(keys), mp.Len())
+	}
+	for i, k := range keys {
+		if v, ok := mp.Get(k); !ok || v != i {
+			t.Errorf("%d expect %d for %v, got %d %v", i, i, k, v, ok)
+		}
+	}
+	for _, k := range []DIR{m(1), m(1, 2), m(1, 2, 3, 4, 0), m(3, 0)} {
+		if _, ok := mp.Get(k); ok {
+			t.Errorf("expect %v not found", k)
+		}
+	}
+	mp.Set(m(2), 100)
+	if v, _ := mp.Get(m(2)); v != 100 || mp.Len() != len(keys) {
+		t.Errorf("expect 100 and len %d, got %d and %d", len(keys), v, mp.Len())
+	}
+	mp.Set(m(2), 7)
+
+	sorted := slices.Clone(keys)
+	slices.SortFunc(sorted, Compare)
+	var got []DIR
+	mp.Walk(func(d DIR, v int) bool {
+		if keys[v] != d {
+			t.Errorf("expect value %d for %v, got %d", slices.Index(keys, d), d, v)
+		}
+		got = append(got, d)
+		return true
+	})
+	if !slices.Equal(got, sorted) {
+		t.Errorf("expect %v, got %v", sorted, got)
+	}
+	got = got[:0]
+	if mp.Walk(func(d DIR, v int) bool { got = append(got, d); return len(got) < 3 }) || len(got) != 3 {
+		t.Errorf("expect walk stopped after 3 entries, got %v", got)
+	}
+
+	prefixTests := []struct {
+		d DIR
+		o []DIR
+	}{
+		{d: m(1, 2, 0), o: []DIR{m(1, 2, 0), m(1, 2, 3), m(1, 2, 3, 0), m(1, 2, 3, 4, 5, 6, 7)}},
+		{d: m(1, 2, 3, 0), o: []DIR{m(1, 2, 3, 0), m(1, 2, 3, 4, 5, 6, 7)}},
+		{d: m(1, 2, 3), o: []DIR{m(1, 2, 3)}},
+		{d: m(1, 2, 4), o: nil},
+		{d: m(5, 0), o: nil},
+		{d: m(0), o: []DIR{m(0), m(0, 1), m(1, 0), m(1, 2, 0), m(1, 2, 3), m(1, 2, 3, 0), m(1, 2, 3, 4, 5, 6, 7), m(2)}},
+	}
+	for i, test := range prefixTests {
+		got = got[:0]
+		mp.WalkPrefix(test.d, func(d DIR, v int) bool {
+			got = append(got, d)
+			return true
+		})
+		if !slices.Equal(got, test.o) {
+			t.Errorf("%d expect %v, got %v", i, test.o, got)
+		}
+		for _, k := range test.o {
+			if k != test.d && !test.d.Prefixes(k) {
+				t.Errorf("%d expect %v prefixes %v", i, test.d, k)
+			}
+		}
+	}
+	got = got[:0]
+	if mp.WalkPrefix(m(1, 2, 0), func(d DIR, v int) bool { got = append(got, d); return false }) || len(got) != 1 {
+		t.Errorf("expect walk stopped after 1 entry, got %v", got)
+	}
+
+	lpTests := []struct {
+		d  DIR
+		k  DIR
+		ok bool
+	}{
+		{d: m(1, 2, 3, 4, 5, 6, 7), k: m(1, 2, 3, 4, 5, 6, 7), ok: true},
+		{d: m(1, 2, 3, 4, 5, 6, 8), k: m(1, 2, 3, 0), ok: true},
+		{d: m(1, 2, 3), k: m(1, 2, 3), ok: true},
+		{d: m(1, 2, 4, 0), k: m(1, 2, 0), ok: true},
+		{d: m(1, 2, 0), k: m(1, 2, 0), ok: true},
+		{d: m(1, 5), k: m(1, 0), ok: true},
+		{d: m(3), k: m(0), ok: true},
+		{d: m(0, 2), k: m(0), ok: true},
+		{d: m(), k: m(), ok: true},
+	}
+	for i, test := range lpTests {
+		k, v, ok := mp.LongestPrefix(test.d)
+		if ok != test.ok || k != test.k {
+			t.Errorf("%d expect %v %v, got %v %v", i, test.k, test.ok, k, ok)
+		}
+		if ok && keys[v] != k {
+			t.Errorf("%d expect value of %v, got %d", i, k, v)
+		}
+	}
+
+	if mp.Delete(m(1, 2, 4)) || mp.Delete(m(1, 2)) {
+		t.Error("expect delete of missing key returns false")
+	}
+	for i, k := range keys {
+		if !mp.Delete(k) {
+			t.Errorf("%d expect delete %v returns true", i, k)
+		}
+		if _, ok := mp.Get(k); ok {
+			t.Errorf("%d expect %v deleted", i, k)
+		}
+		if mp.Len() != len(keys)-i-1 {
+			t.Errorf("%d expect len %d, got %d", i, len(keys)-i-1, mp.Len())
+		}
+	}
+	if len(mp.root.ids) != 0 {
+		t.Errorf("expect empty tree, got %d children", len(mp.root.ids))
+	}
+	if _, _, ok := mp.LongestPrefix(m(1, 2, 3)); ok {
+		t.Error("expect no prefix found")
+	}
+}
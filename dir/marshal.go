@@ -0,0 +1,83 @@
+package dir
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalText implements encoding.TextMarshaler. The DIR is encoded as
+// a URI.
+func (d DIR) MarshalText() ([]byte, error) {
+	return d.AppendURI(nil), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts the URI
+// encoding of a DIR, and the human readable representation returned by
+// String.
+func (d *DIR) UnmarshalText(b []byte) (err error) {
+	var v DIR
+	if bytes.HasPrefix(b, []byte("dir:")) {
+		v, err = Parse(b)
+	} else {
+		v, err = DecodeURI(b)
+	}
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The DIR is binary
+// encoded.
+func (d DIR) MarshalBinary() ([]byte, error) {
+	return d.Binary(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (d *DIR) UnmarshalBinary(b []byte) error {
+	v, err := DecodeBinary(b)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The DIR is encoded as a JSON
+// string containing its URI encoding.
+func (d DIR) MarshalJSON() ([]byte, error) {
+	b := make([]byte, 0, MaxURILen+2)
+	b = append(b, '"')
+	b = d.AppendURI(b)
+	return append(b, '"'), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a JSON string
+// with the same content as UnmarshalText. The JSON value null leaves d
+// unchanged.
+func (d *DIR) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(b, &s) != nil {
+		return fmt.Errorf("%w: JSON value is not a string", ErrInvalid)
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// Format implements fmt.Formatter. The verb %v prints the human readable
+// representation returned by String, %s the URI encoding and %q the double
+// quoted URI encoding. Width and flags are applied as for strings.
+func (d DIR) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		fmt.Fprintf(f, fmt.FormatString(f, 's'), d.String())
+	case 's', 'q':
+		fmt.Fprintf(f, fmt.FormatString(f, verb), d.URI())
+	default:
+		fmt.Fprintf(f, "%%!%c(dir.DIR=%s)", verb, d.String())
+	}
+}
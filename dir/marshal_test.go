@@ -0,0 +1,142 @@
+package dir
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestMarshalText(t *testing.T) {
+	tests := []struct {
+		i DIR
+		o string
+	}{
+		{i: DIR{}, o: "dis:/"},
+		{i: m(0), o: "dis:./"},
+		{i: m(0, 1, 0), o: "dis:.1./"},
+		{i: m(1, 2345, 0), o: "dis:1.fa./"},
+	}
+	for i, test := range tests {
+		b, err := test.i.MarshalText()
+		if err != nil || string(b) != test.o {
+			t.Errorf("%d expect %q, got %q (%v)", i, test.o, b, err)
+		}
+		var d DIR
+		if err := d.UnmarshalText(b); err != nil || d != test.i {
+			t.Errorf("%d expect %v, got %v (%v)", i, test.i, d, err)
+		}
+		d = DIR{}
+		if err := d.UnmarshalText([]byte(test.i.String())); err != nil || d != test.i {
+			t.Errorf("%d expect %v, got %v (%v)", i, test.i, d, err)
+		}
+	}
+
+	d := m(1)
+	if err := d.UnmarshalText([]byte("dis:1.0.1/")); err == nil {
+		t.Error("expect error")
+	}
+	if err := d.UnmarshalText([]byte("dir:1.0.1")); err == nil {
+		t.Error("expect error")
+	}
+	if d != m(1) {
+		t.Errorf("expect %v unchanged, got %v", m(1), d)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for i, in := range []DIR{{}, m(0), m(0, 1, 0), m(1, 2345, 0), m(1, 2, 3, 4, 5, 6, 7)} {
+		b, err := in.MarshalBinary()
+		if err != nil || !bytes.Equal(b, in.Binary()) {
+			t.Errorf("%d expect %#v, got %#v (%v)", i, in.Binary(), b, err)
+		}
+		var d DIR
+		if err := d.UnmarshalBinary(b); err != nil || d != in {
+			t.Errorf("%d expect %v, got %v (%v)", i, in, d, err)
+		}
+	}
+	var d DIR
+	if err := d.UnmarshalBinary([]byte{0x80}); err == nil {
+		t.Error("expect error")
+	}
+
+	var buf bytes.Buffer
+	in := []DIR{m(1, 2, 0), m(0, 3)}
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out []DIR
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out) != len(in) || out[0] != in[0] || out[1] != in[1] {
+		t.Errorf("expect %v, got %v", in, out)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	type S struct {
+		D DIR
+		P *DIR
+		M map[string]DIR
+	}
+	p := m(0, 1)
+	in := S{D: m(1, 2345, 0), P: &p, M: map[string]DIR{"a": m(7)}}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exp := `{"D":"dis:1.fa./","P":"dis:.1/","M":{"a":"dis:7/"}}`; string(b) != exp {
+		t.Errorf("expect %s, got %s", exp, b)
+	}
+	var out S
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.D != in.D || out.P == nil || *out.P != *in.P || out.M["a"] != in.M["a"] {
+		t.Errorf("expect %v, got %v", in, out)
+	}
+
+	if err := json.Unmarshal([]byte(`{"D":"dir:1.2","P":null}`), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.D != m(1, 2) || out.P != nil {
+		t.Errorf("expect %v and nil, got %v and %v", m(1, 2), out.D, out.P)
+	}
+	if err := json.Unmarshal([]byte(`{"D":"dis:\u0031.fa./","P":"dir:\u0031"}`), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.D != m(1, 2345, 0) || out.P == nil || *out.P != m(1) {
+		t.Errorf("expect %v and %v, got %v and %v", m(1, 2345, 0), m(1), out.D, out.P)
+	}
+	if err := json.Unmarshal([]byte(`{"D":12}`), &out); err == nil {
+		t.Error("expect error")
+	}
+	if err := json.Unmarshal([]byte(`{"D":"dis:1.0.2/"}`), &out); err == nil {
+		t.Error("expect error")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	d := m(1, 2345, 0)
+	tests := []struct {
+		f string
+		o string
+	}{
+		{f: "%v", o: "dir:1.2345.0"},
+		{f: "%s", o: "dis:1.fa./"},
+		{f: "%q", o: `"dis:1.fa./"`},
+		{f: "%14v", o: "  dir:1.2345.0"},
+		{f: "%-12s|", o: "dis:1.fa./  |"},
+		{f: "%d", o: "%!d(dir.DIR=dir:1.2345.0)"},
+	}
+	for i, test := range tests {
+		if s := fmt.Sprintf(test.f, d); s != test.o {
+			t.Errorf("%d expect %q, got %q", i, test.o, s)
+		}
+	}
+	if s := fmt.Sprint(d); s != "dir:1.2345.0" {
+		t.Errorf("expect %q, got %q", "dir:1.2345.0", s)
+	}
+}
@@ -0,0 +1,182 @@
+package dir
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pattern is a DIR pattern that may contain wildcards. Its textual
+// representation extends the URI encoding of DIRs with two wildcards.
+// The wildcard "*" matches any identifier except 0, and the wildcard "**",
+// only allowed as last element, matches the content of the node at any
+// depth. For instance, "dis:1.5.*/" matches all information of the node
+// 1.5, and "dis:1.5.**/" matches all DIRs d such that the node DIR 1.5.0
+// prefixes d.
+type Pattern struct {
+	ids  [MaxIDs]uint64
+	kind [MaxIDs]patternKind
+	n    int
+}
+
+// patternKind is the kind of an element of a Pattern.
+type patternKind byte
+
+const (
+	patternID patternKind = iota
+	patternOne
+	patternAny
+)
+
+// ParsePattern parses the textual representation of a Pattern. The returned
+// error reports the offset of the invalid character in s.
+func ParsePattern[T string | []byte](s T) (p Pattern, err error) {
+	if len(s) < 5 || s[0] != 'd' || s[1] != 'i' || s[2] != 's' || s[3] != ':' || s[len(s)-1] != '/' {
+		return p, fmt.Errorf("%w: pattern doesn't start with \"dis:\" and end with \"/\"", ErrInvalid)
+	}
+	body := string(s[4 : len(s)-1])
+	switch body {
+	case "":
+		return p, nil
+	case ".":
+		p.n = 1
+		return p, nil
+	case "..":
+		p.n = 2
+		return p, nil
+	}
+	elems := strings.Split(body, ".")
+	if len(elems) > MaxIDs {
+		return Pattern{}, fmt.Errorf("%w: too many identifiers in pattern", ErrInvalid)
+	}
+	off := 4
+	for i, e := range elems {
+		switch e {
+		case "":
+			if i != 0 && i != len(elems)-1 {
+				return Pattern{}, fmt.Errorf("%w: identifier %d in pattern is 0 at offset %d", ErrInvalid, i, off)
+			}
+		case "*":
+			p.kind[i] = patternOne
+		case "**":
+			if i != len(elems)-1 {
+				return Pattern{}, fmt.Errorf("%w: wildcard \"**\" is not last at offset %d", ErrInvalid, off)
+			}
+			p.kind[i] = patternAny
+		default:
+			if len(e) > maxURIIDLen {
+				return Pattern{}, fmt.Errorf("%w: identifier %d in pattern overflows at offset %d", ErrInvalid, i, off)
+			}
+			var v uint64
+			for j := 0; j < len(e); j++ {
+				c := strings.IndexByte(uriChars, e[j])
+				if c < 0 {
+					return Pattern{}, fmt.Errorf("%w: invalid character in pattern at offset %d", ErrInvalid, off+j)
+				}
+				if j == maxURIIDLen-1 && c > 0xF {
+					return Pattern{}, fmt.Errorf("%w: identifier %d in pattern overflows at offset %d", ErrInvalid, i, off)
+				}
+				v |= uint64(c) << (6 * j)
+			}
+			if v == 0 && i != 0 && i != len(elems)-1 {
+				return Pattern{}, fmt.Errorf("%w: identifier %d in pattern is 0 at offset %d", ErrInvalid, i, off)
+			}
+			if e[len(e)-1] == '0' {
+				return Pattern{}, fmt.Errorf("%w: identifier %d in pattern is not minimal length at offset %d", ErrInvalid, i, off)
+			}
+			p.ids[i] = v
+		}
+		off += len(e) + 1
+	}
+	p.n = len(elems)
+	return p, nil
+}
+
+// MustParsePattern calls ParsePattern and returns the Pattern or panics in
+// case of error.
+func MustParsePattern[T string | []byte](s T) Pattern {
+	p, err := ParsePattern(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the textual representation of p.
+func (p Pattern) String() string {
+	var b strings.Builder
+	b.WriteString("dis:")
+	for i := 0; i < p.n; i++ {
+		if i != 0 {
+			b.WriteByte('.')
+		}
+		switch p.kind[i] {
+		case patternOne:
+			b.WriteByte('*')
+		case patternAny:
+			b.WriteString("**")
+		default:
+			for id := p.ids[i]; id != 0; id >>= 6 {
+				b.WriteByte(uriChars[id&0x3F])
+			}
+		}
+	}
+	if p.n > 0 && p.kind[p.n-1] == patternID && p.ids[p.n-1] == 0 &&
+		(p.n == 1 || (p.kind[p.n-2] == patternID && p.ids[p.n-2] == 0)) {
+		b.WriteByte('.')
+	}
+	b.WriteByte('/')
+	return b.String()
+}
+
+// Match returns true if d matches the pattern p.
+func (p Pattern) Match(d DIR) bool {
+	n := d.Len()
+	if p.n == 0 || p.kind[p.n-1] != patternAny {
+		if n != p.n {
+			return false
+		}
+	} else if n < p.n || (n == p.n && d.d[n] == 0) {
+		return false
+	}
+	for i := 0; i < p.n; i++ {
+		switch p.kind[i] {
+		case patternID:
+			if d.d[i+1] != p.ids[i] {
+				return false
+			}
+		case patternOne:
+			if d.d[i+1] == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Prefix returns the deepest node DIR n such that all DIRs d matching p
+// are equal to n or n.Prefixes(d) is true. The node DIR is made of the
+// identifiers preceding the first wildcard of p. It can be used to find
+// the candidate DIRs in a prefix index like Map. Returns a nil DIR when
+// p only matches the nil DIR.
+func (p Pattern) Prefix() DIR {
+	var d DIR
+	for i := 0; i < p.n; i++ {
+		if p.kind[i] != patternID {
+			d.d[0] = uint64(i + 1)
+			return d
+		}
+		d.d[i+1] = p.ids[i]
+	}
+	d.d[0] = uint64(p.n)
+	return d.NodeDIR()
+}
+
+// Wildcard returns true if p contains a wildcard.
+func (p Pattern) Wildcard() bool {
+	for i := 0; i < p.n; i++ {
+		if p.kind[i] != patternID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package dir
+
+import "testing"
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		i string
+		e string
+	}{
+		// 0
+		{i: "dis:/"},
+		{i: "dis:./"},
+		{i: "dis:../"},
+		{i: "dis:.1./"},
+		{i: "dis:1.fa./"},
+		// 5
+		{i: "dis:1.5.*/"},
+		{i: "dis:1.5.**/"},
+		{i: "dis:*.*./"},
+		{i: "dis:.*/"},
+		{i: "dis:1.*.3.4.5.6.**/"},
+		// 10
+		{i: "dis:__________F.*/"},
+		{i: "dis:1.5.*", e: "invalid dir: pattern doesn't start with \"dis:\" and end with \"/\""},
+		{i: "dis:**.1/", e: "invalid dir: wildcard \"**\" is not last at offset 4"},
+		{i: "dis:1..2/", e: "invalid dir: identifier 1 in pattern is 0 at offset 6"},
+		{i: "dis:1.***/", e: "invalid dir: invalid character in pattern at offset 6"},
+		// 15
+		{i: "dis:1.2.3.4.5.6.7.*/", e: "invalid dir: too many identifiers in pattern"},
+		{i: "dis:1.a0/", e: "invalid dir: identifier 1 in pattern is not minimal length at offset 6"},
+		{i: "dis:__________G/", e: "invalid dir: identifier 0 in pattern overflows at offset 4"},
+		{i: "dis:___________1/", e: "invalid dir: identifier 0 in pattern overflows at offset 4"},
+		{i: "dis:1.a/b/", e: "invalid dir: invalid character in pattern at offset 7"},
+		// 20
+		{i: "dis:1.0.2/", e: "invalid dir: identifier 1 in pattern is 0 at offset 6"},
+		{i: "dis:1.00.2/", e: "invalid dir: identifier 1 in pattern is 0 at offset 6"},
+		{i: "dis:1.0/", e: "invalid dir: identifier 1 in pattern is not minimal length at offset 6"},
+	}
+	for i, test := range tests {
+		p, err := ParsePattern(test.i)
+		var errStr string
+		if err != nil {
+			errStr = err.Error()
+		}
+		if test.e != errStr {
+			t.Errorf("%d expect error %q, got %q", i, test.e, errStr)
+			continue
+		}
+		if test.e != "" {
+			continue
+		}
+		if s := p.String(); s != test.i {
+			t.Errorf("%d expect %q, got %q", i, test.i, s)
+		}
+	}
+
+	// patterns without wildcards have the same encoding as DIRs
+	for i, d := range []DIR{{}, m(0), m(0, 0), m(0, 1), m(1, 0), m(0, 1, 0), m(1, 2345, 0), m(1, 2, 3, 4, 5, 6, 7)} {
+		p, err := ParsePattern(d.URI())
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		if p.Wildcard() || !p.Match(d) || p.String() != d.URI() {
+			t.Errorf("%d expect %q to match only %v", i, p, d)
+		}
+	}
+
+	if !doesPanic(func() {
+		MustParsePattern("dis:1..2/")
+	}) {
+		t.Error("expect panics")
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		p      string
+		prefix DIR
+		yes    []DIR
+		no     []DIR
+	}{
+		// 0
+		{
+			p:      "dis:1.5.*/",
+			prefix: m(1, 5, 0),
+			yes:    []DIR{m(1, 5, 1), m(1, 5, 9)},
+			no:     []DIR{m(1, 5, 0), m(1, 5, 1, 0), m(1, 5), m(1, 6, 1), m(0, 5, 1), m()},
+		},
+		{
+			p:      "dis:1.5.**/",
+			prefix: m(1, 5, 0),
+			yes:    []DIR{m(1, 5, 1), m(1, 5, 1, 0), m(1, 5, 1, 2, 3)},
+			no:     []DIR{m(1, 5, 0), m(1, 5), m(1, 6, 1), m(1, 0)},
+		},
+		{
+			p:      "dis:1.*./",
+			prefix: m(1, 0),
+			yes:    []DIR{m(1, 2, 0), m(1, 7, 0)},
+			no:     []DIR{m(1, 0), m(1, 2), m(1, 2, 3, 0)},
+		},
+		{
+			p:      "dis:*.2/",
+			prefix: m(0),
+			yes:    []DIR{m(1, 2), m(7, 2)},
+			no:     []DIR{m(0, 2), m(1, 3), m(1, 2, 0)},
+		},
+		{
+			p:      "dis:.**/",
+			prefix: m(0, 0),
+			yes:    []DIR{m(0, 1), m(0, 1, 0), m(0, 1, 2)},
+			no:     []DIR{m(0, 0), m(0), m(1, 1)},
+		},
+		// 5
+		{
+			p:      "dis:1.2/",
+			prefix: m(1, 0),
+			yes:    []DIR{m(1, 2)},
+			no:     []DIR{m(1, 2, 0), m(1, 3)},
+		},
+		{
+			p:      "dis:1.2./",
+			prefix: m(1, 2, 0),
+			yes:    []DIR{m(1, 2, 0)},
+			no:     []DIR{m(1, 2), m(1, 2, 3)},
+		},
+		{
+			p:      "dis:/",
+			prefix: m(),
+			yes:    []DIR{m()},
+			no:     []DIR{m(0), m(1)},
+		},
+	}
+	for i, test := range tests {
+		p := MustParsePattern(test.p)
+		if pr := p.Prefix(); pr != test.prefix {
+			t.Errorf("%d expect prefix %v, got %v", i, test.prefix, pr)
+		}
+		for _, d := range test.yes {
+			if !p.Match(d) {
+				t.Errorf("%d expect %q matches %v", i, test.p, d)
+			}
+			if pr := p.Prefix(); pr != d && !pr.Prefixes(d) {
+				t.Errorf("%d expect prefix %v prefixes %v", i, pr, d)
+			}
+		}
+		for _, d := range test.no {
+			if p.Match(d) {
+				t.Errorf("%d expect %q doesn't match %v", i, test.p, d)
+			}
+		}
+	}
+}
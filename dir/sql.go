@@ -0,0 +1,44 @@
+package dir
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+)
+
+// Value implements driver.Valuer. The DIR is stored as its binary encoding.
+func (d DIR) Value() (driver.Value, error) {
+	return d.Binary(), nil
+}
+
+// Scan implements sql.Scanner. It accepts the binary encoding of a DIR as
+// a []byte, or its URI encoding as a string or a []byte, since text
+// columns are often scanned as []byte. A []byte of the form "dis:.../"
+// that is a valid URI is decoded as a URI, and otherwise as a binary
+// encoding. The few binary encoded DIRs that are also a valid URI, like
+// the DIR with the identifiers 100, 105, 115, 58, 47 whose encoding is
+// "dis:/", are thus scanned as the URI. A NULL value is scanned as a nil
+// DIR.
+func (d *DIR) Scan(src any) (err error) {
+	var v DIR
+	switch s := src.(type) {
+	case nil:
+	case string:
+		v, err = DecodeURI(s)
+	case []byte:
+		err = ErrInvalid
+		if bytes.HasPrefix(s, []byte("dis:")) && bytes.HasSuffix(s, []byte("/")) {
+			v, err = DecodeURI(s)
+		}
+		if err != nil {
+			v, err = DecodeBinary(s)
+		}
+	default:
+		return fmt.Errorf("%w: can't scan %T into a DIR", ErrInvalid, src)
+	}
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
@@ -0,0 +1,128 @@
+package dir
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+// fakeDriver is a database driver storing a single column of values.
+// The query "INSERT" appends its argument to the column, and any other
+// query returns all the stored values.
+type fakeDriver struct{ rows []driver.Value }
+
+type fakeConn struct{ drv *fakeDriver }
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+type fakeRows struct {
+	rows []driver.Value
+	pos  int
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{drv: d}, nil }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.drv.rows = append(s.conn.drv.rows, args...)
+	return driver.RowsAffected(len(args)), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{rows: s.conn.drv.rows}, nil
+}
+
+func (r *fakeRows) Columns() []string { return []string{"dir"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos == len(r.rows) {
+		return io.EOF
+	}
+	dest[0] = r.rows[r.pos]
+	r.pos++
+	return nil
+}
+
+var fakeDrv = &fakeDriver{}
+
+func init() {
+	sql.Register("fakedir", fakeDrv)
+}
+
+func TestSQL(t *testing.T) {
+	db, err := sql.Open("fakedir", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	in := []DIR{m(1, 2345, 0), {}, m(0, 1), m(100, 105, 115, 58)}
+	for _, d := range in {
+		if _, err := db.Exec("INSERT", d); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	fakeDrv.rows = append(fakeDrv.rows, "dis:1.fa./", []byte("dis:.1./"), nil,
+		[]byte("dis:./"), []byte("dis:/"), []byte("dis:1/"))
+	exp := append(in, m(1, 2345, 0), m(0, 1, 0), DIR{}, m(0), DIR{}, m(1))
+	if !bytes.Equal(fakeDrv.rows[0].([]byte), m(1, 2345, 0).Binary()) {
+		t.Errorf("expect %#v, got %#v", m(1, 2345, 0).Binary(), fakeDrv.rows[0])
+	}
+
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rows.Close()
+	var out []DIR
+	for rows.Next() {
+		d := m(9)
+		if err := rows.Scan(&d); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out) != len(exp) {
+		t.Fatalf("expect %v, got %v", exp, out)
+	}
+	for i := range exp {
+		if out[i] != exp[i] {
+			t.Errorf("%d expect %v, got %v", i, exp[i], out[i])
+		}
+	}
+
+	for i, d := range in {
+		v, err := d.Value()
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		var out DIR
+		if err := out.Scan(v); err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+		} else if out != d {
+			t.Errorf("%d expect %v, got %v", i, d, out)
+		}
+	}
+
+	var d DIR
+	for i, src := range []any{int64(1), "dis:1.0.1/", []byte{0x80}, []byte("dis:1.0.1.2.3.4/")} {
+		if err := d.Scan(src); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%d expect ErrInvalid, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package idr
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+type SmallStruct struct {
+	Name     string    `idr:"1"`
+	BirthDay time.Time `idr:"2"`
+	Phone    string    `idr:"3"`
+	Siblings int       `idr:"4"`
+	Spouse   bool      `idr:"5"`
+	Money    float64   `idr:"6"`
+}
+
+func randString(l int) string {
+	buf := make([]byte, l)
+	for i := 0; i < (l+1)/2; i++ {
+		buf[i] = byte(rand.Intn(256))
+	}
+	return fmt.Sprintf("%x", buf)[:l]
+}
+
+func generateSmallStruct() []*SmallStruct {
+	a := make([]*SmallStruct, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		a = append(a, &SmallStruct{
+			Name:     randString(16),
+			BirthDay: time.Now(),
+			Phone:    randString(10),
+			Siblings: rand.Intn(5),
+			Spouse:   rand.Intn(2) == 1,
+			Money:    rand.Float64(),
+		})
+	}
+	return a
+}
+
+var e []byte
+
+func BenchmarkMarshal(b *testing.B) {
+	data := generateSmallStruct()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		e, _ = Marshal(data[rand.Intn(len(data))])
+	}
+}
+
+var a2 SmallStruct
+
+func BenchmarkUnmarshal(b *testing.B) {
+	src := generateSmallStruct()
+	encoded := make([][]byte, len(src))
+	for i, v := range src {
+		encoded[i], _ = Marshal(v)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_ = Unmarshal(encoded[rand.Intn(len(encoded))], &a2)
+	}
+}
@@ -0,0 +1,748 @@
+package idr
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+	"github.com/chmike/ditp/idr/low"
+)
+
+// maxPrealloc is the maximum number of slice elements allocated before
+// decoding them so that a hostile count can't trigger a huge allocation.
+const maxPrealloc = 1024
+
+// option is an encoding option of a struct field.
+type option uint8
+
+const (
+	defaultOpt option = iota
+	varintOpt
+	fixedOpt
+	microOpt
+)
+
+type encFunc func(e low.Encoder, v reflect.Value) (low.Encoder, error)
+type decFunc func(r *low.Reader, v reflect.Value) error
+
+// codec encodes and decodes the values of a type with the encoding
+// identified by tag.
+type codec struct {
+	tag low.TagT
+	enc encFunc
+	dec decFunc
+}
+
+type codecKey struct {
+	t   reflect.Type
+	opt option
+}
+
+var (
+	codecs   sync.Map // codecKey -> *codec
+	codecsMu sync.Mutex
+)
+
+var (
+	dirType  = reflect.TypeFor[dir.DIR]()
+	timeType = reflect.TypeFor[time.Time]()
+)
+
+// codecOf returns the codec of the type t with the encoding option opt.
+// The codecs are built once and cached.
+func codecOf(t reflect.Type, opt option) (*codec, error) {
+	k := codecKey{t: t, opt: opt}
+	if c, ok := codecs.Load(k); ok {
+		return c.(*codec), nil
+	}
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	b := builder{codecs: make(map[codecKey]*codec)}
+	c, err := b.codec(t, opt)
+	if err != nil {
+		return nil, err
+	}
+	for k, c := range b.codecs {
+		codecs.Store(k, c)
+	}
+	return c, nil
+}
+
+// builder builds the codecs of a type and of the types it refers to. The
+// codecs being built are registered before their element codecs so that
+// recursive types refer to them.
+type builder struct {
+	codecs map[codecKey]*codec
+}
+
+func (b *builder) codec(t reflect.Type, opt option) (*codec, error) {
+	k := codecKey{t: t, opt: opt}
+	if c, ok := codecs.Load(k); ok {
+		return c.(*codec), nil
+	}
+	if c, ok := b.codecs[k]; ok {
+		return c, nil
+	}
+	c := &codec{}
+	b.codecs[k] = c
+	if err := b.build(c, t, opt); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (b *builder) build(c *codec, t reflect.Type, opt option) error {
+	switch t {
+	case dirType:
+		c.tag, c.enc, c.dec = low.DIRTag, encDIR, decDIR
+		return nil
+	case timeType:
+		switch opt {
+		case fixedOpt:
+			c.tag, c.enc, c.dec = low.TimeTag, encTime, decTime
+		case microOpt:
+			c.tag, c.enc, c.dec = low.Int64Tag, encMicroTime, decMicroTime
+		default:
+			c.tag, c.enc, c.dec = low.VarTimeTag, encVarTime, decVarTime
+		}
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		c.tag, c.enc, c.dec = low.BoolTag, encBool, decBool
+	case reflect.Int:
+		intCodec(c, pick(opt, low.VarIntTag, low.VarIntTag, low.Int64Tag))
+	case reflect.Int8:
+		intCodec(c, pick(opt, low.Int8Tag, low.VarInt64Tag, low.Int8Tag))
+	case reflect.Int16:
+		intCodec(c, pick(opt, low.Int16Tag, low.VarInt64Tag, low.Int16Tag))
+	case reflect.Int32:
+		intCodec(c, pick(opt, low.Int32Tag, low.VarInt64Tag, low.Int32Tag))
+	case reflect.Int64:
+		intCodec(c, pick(opt, low.VarInt64Tag, low.VarInt64Tag, low.Int64Tag))
+	case reflect.Uint:
+		uintCodec(c, pick(opt, low.VarUintTag, low.VarUintTag, low.Uint64Tag))
+	case reflect.Uint8:
+		uintCodec(c, pick(opt, low.Uint8Tag, low.VarUint64Tag, low.Uint8Tag))
+	case reflect.Uint16:
+		uintCodec(c, pick(opt, low.Uint16Tag, low.VarUint64Tag, low.Uint16Tag))
+	case reflect.Uint32:
+		uintCodec(c, pick(opt, low.Uint32Tag, low.VarUint64Tag, low.Uint32Tag))
+	case reflect.Uint64:
+		uintCodec(c, pick(opt, low.VarUint64Tag, low.VarUint64Tag, low.Uint64Tag))
+	case reflect.Float32:
+		floatCodec(c, pick(opt, low.Float32Tag, low.VarFloatTag, low.Float32Tag))
+	case reflect.Float64:
+		floatCodec(c, pick(opt, low.Float64Tag, low.VarFloatTag, low.Float64Tag))
+	case reflect.Complex64:
+		complexCodec(c, pick(opt, low.Complex64Tag, low.VarComplexTag, low.Complex64Tag))
+	case reflect.Complex128:
+		complexCodec(c, pick(opt, low.Complex128Tag, low.VarComplexTag, low.Complex128Tag))
+	case reflect.String:
+		c.tag, c.enc, c.dec = low.StringTag, encString, decString
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			c.tag, c.enc, c.dec = low.BlobTag, encBlob, decBlob
+			return nil
+		}
+		c.tag = low.ArrayTag
+		elem, err := b.codec(t.Elem(), opt)
+		if err != nil {
+			return err
+		}
+		c.enc, c.dec = arrayEnc(elem), sliceDec(t, elem)
+	case reflect.Array:
+		c.tag = low.ArrayTag
+		elem, err := b.codec(t.Elem(), opt)
+		if err != nil {
+			return err
+		}
+		c.enc, c.dec = arrayEnc(elem), arrayDec(t, elem)
+	case reflect.Map:
+		c.tag = low.MapTag
+		key, err := b.codec(t.Key(), opt)
+		if err != nil {
+			return err
+		}
+		val, err := b.codec(t.Elem(), opt)
+		if err != nil {
+			return err
+		}
+		c.enc, c.dec = mapEnc(key, val), mapDec(t, key, val)
+	case reflect.Pointer:
+		if t.Elem().Kind() == reflect.Pointer {
+			return fmt.Errorf("%w: %v", ErrUnsupported, t)
+		}
+		elem, err := b.codec(t.Elem(), opt)
+		if err != nil {
+			return err
+		}
+		c.tag, c.enc, c.dec = elem.tag, pointerEnc(t, elem), pointerDec(t, elem)
+	case reflect.Struct:
+		c.tag = low.RecordTag
+		fields, err := b.fields(t)
+		if err != nil {
+			return err
+		}
+		c.enc, c.dec = recordEnc(fields), recordDec(fields)
+	default:
+		return fmt.Errorf("%w: %v", ErrUnsupported, t)
+	}
+	return nil
+}
+
+// pick returns the tag selected by the option opt.
+func pick(opt option, def, varint, fixed low.TagT) low.TagT {
+	switch opt {
+	case varintOpt:
+		return varint
+	case fixedOpt:
+		return fixed
+	}
+	return def
+}
+
+// checkTag decodes the next tag and checks that it is the tag of c.
+func checkTag(r *low.Reader, c *codec, t reflect.Type) error {
+	off := r.Offset()
+	tag := r.Tag()
+	if err := r.Err(); err != nil {
+		return err
+	}
+	return matchTag(off, tag, c.tag, t)
+}
+
+// matchTag returns an error if the tag of the value at offset off is not
+// the tag expected to decode a value of type t.
+func matchTag(off int, tag, expect low.TagT, t reflect.Type) error {
+	if tag != expect {
+		return fmt.Errorf("%w: %v at offset %d can't be decoded into %v", ErrMismatch, tag, off, t)
+	}
+	return nil
+}
+
+// decodeErr returns the error of r if any, or err otherwise. The error of
+// the Reader is the cause of a subsequent decoding error.
+func decodeErr(r *low.Reader, err error) error {
+	if r.Err() != nil {
+		return r.Err()
+	}
+	return err
+}
+
+func encBool(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+	return low.AppendBool(e, v.Bool()), nil
+}
+
+func decBool(r *low.Reader, v reflect.Value) error {
+	v.SetBool(r.Bool())
+	return nil
+}
+
+func encString(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+	return low.AppendString(e, v.String()), nil
+}
+
+func decString(r *low.Reader, v reflect.Value) error {
+	v.SetString(r.String(r.Limits().MaxStringSize))
+	return nil
+}
+
+func encBlob(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+	return low.AppendBlob(e, v.Bytes()), nil
+}
+
+func decBlob(r *low.Reader, v reflect.Value) error {
+	b := r.Blob(r.Limits().MaxBlobSize)
+	if r.Alloc(uint64(len(b)), 1) {
+		v.SetBytes(bytes.Clone(b))
+	}
+	return nil
+}
+
+func encDIR(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+	return low.AppendDIR(e, v.Interface().(dir.DIR)), nil
+}
+
+func decDIR(r *low.Reader, v reflect.Value) error {
+	v.Set(reflect.ValueOf(r.DIR()))
+	return nil
+}
+
+func encTime(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+	return low.AppendTime(e, v.Interface().(time.Time)), nil
+}
+
+func decTime(r *low.Reader, v reflect.Value) error {
+	v.Set(reflect.ValueOf(r.Time()))
+	return nil
+}
+
+func encVarTime(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+	return low.AppendVarTime(e, v.Interface().(time.Time)), nil
+}
+
+func decVarTime(r *low.Reader, v reflect.Value) error {
+	v.Set(reflect.ValueOf(r.VarTime()))
+	return nil
+}
+
+func encMicroTime(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+	return low.AppendInt64(e, v.Interface().(time.Time).UnixMicro()), nil
+}
+
+func decMicroTime(r *low.Reader, v reflect.Value) error {
+	v.Set(reflect.ValueOf(time.UnixMicro(r.Int64())))
+	return nil
+}
+
+// intCodec sets c to encode signed integers with the encoding tag.
+func intCodec(c *codec, tag low.TagT) {
+	c.tag = tag
+	switch tag {
+	case low.Int8Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendInt8(e, int8(v.Int())), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetInt(int64(r.Int8()))
+			return nil
+		}
+	case low.Int16Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendInt16(e, int16(v.Int())), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetInt(int64(r.Int16()))
+			return nil
+		}
+	case low.Int32Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendInt32(e, int32(v.Int())), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetInt(int64(r.Int32()))
+			return nil
+		}
+	case low.Int64Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendInt64(e, v.Int()), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetInt(r.Int64())
+			return nil
+		}
+	default:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendVarInt64(e, v.Int()), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			off := r.Offset()
+			x := r.VarInt64()
+			if v.OverflowInt(x) {
+				return fmt.Errorf("%w: %d at offset %d overflows %v", ErrRange, x, off, v.Type())
+			}
+			v.SetInt(x)
+			return nil
+		}
+	}
+}
+
+// uintCodec sets c to encode unsigned integers with the encoding tag.
+func uintCodec(c *codec, tag low.TagT) {
+	c.tag = tag
+	switch tag {
+	case low.Uint8Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendUint8(e, uint8(v.Uint())), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetUint(uint64(r.Uint8()))
+			return nil
+		}
+	case low.Uint16Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendUint16(e, uint16(v.Uint())), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetUint(uint64(r.Uint16()))
+			return nil
+		}
+	case low.Uint32Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendUint32(e, uint32(v.Uint())), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetUint(uint64(r.Uint32()))
+			return nil
+		}
+	case low.Uint64Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendUint64(e, v.Uint()), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetUint(r.Uint64())
+			return nil
+		}
+	default:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendVarUint64(e, v.Uint()), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			off := r.Offset()
+			x := r.VarUint64()
+			if v.OverflowUint(x) {
+				return fmt.Errorf("%w: %d at offset %d overflows %v", ErrRange, x, off, v.Type())
+			}
+			v.SetUint(x)
+			return nil
+		}
+	}
+}
+
+// floatCodec sets c to encode floats with the encoding tag.
+func floatCodec(c *codec, tag low.TagT) {
+	c.tag = tag
+	switch tag {
+	case low.Float32Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendFloat32(e, float32(v.Float())), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetFloat(float64(r.Float32()))
+			return nil
+		}
+	case low.Float64Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendFloat64(e, v.Float()), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetFloat(r.Float64())
+			return nil
+		}
+	default:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendVarFloat(e, v.Float()), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			off := r.Offset()
+			x := r.VarFloat()
+			if v.OverflowFloat(x) {
+				return fmt.Errorf("%w: %g at offset %d overflows %v", ErrRange, x, off, v.Type())
+			}
+			v.SetFloat(x)
+			return nil
+		}
+	}
+}
+
+// complexCodec sets c to encode complex numbers with the encoding tag.
+func complexCodec(c *codec, tag low.TagT) {
+	c.tag = tag
+	switch tag {
+	case low.Complex64Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendComplex64(e, complex64(v.Complex())), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetComplex(complex128(r.Complex64()))
+			return nil
+		}
+	case low.Complex128Tag:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendComplex128(e, v.Complex()), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			v.SetComplex(r.Complex128())
+			return nil
+		}
+	default:
+		c.enc = func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+			return low.AppendVarComplex(e, v.Complex()), nil
+		}
+		c.dec = func(r *low.Reader, v reflect.Value) error {
+			off := r.Offset()
+			x := r.VarComplex()
+			if v.OverflowComplex(x) {
+				return fmt.Errorf("%w: %g at offset %d overflows %v", ErrRange, x, off, v.Type())
+			}
+			v.SetComplex(x)
+			return nil
+		}
+	}
+}
+
+// arrayEnc returns the encoder of slices and arrays as IDR arrays.
+func arrayEnc(elem *codec) encFunc {
+	return func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+		e, p := low.BeginArray(e, elem.tag, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			if e, err = elem.enc(e, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return low.EndComposite(e, p), nil
+	}
+}
+
+// sliceDec returns the decoder of IDR arrays into slices of type t.
+func sliceDec(t reflect.Type, elem *codec) decFunc {
+	return func(r *low.Reader, v reflect.Value) error {
+		off := r.Offset()
+		c, tag, n := r.Array()
+		if err := c.Err(); err != nil {
+			return err
+		}
+		if n > 0 {
+			if err := matchTag(off, tag, elem.tag, t.Elem()); err != nil {
+				return err
+			}
+		}
+		if !c.Alloc(n, uint64(t.Elem().Size())) {
+			return c.Err()
+		}
+		s := reflect.MakeSlice(t, 0, int(min(n, maxPrealloc)))
+		z := reflect.Zero(t.Elem())
+		for i := 0; i < int(n); i++ {
+			s = reflect.Append(s, z)
+			if err := elem.dec(&c, s.Index(i)); err != nil {
+				return decodeErr(&c, err)
+			}
+		}
+		v.Set(s)
+		return c.Err()
+	}
+}
+
+// arrayDec returns the decoder of IDR arrays into arrays of type t. The
+// number of elements must match the array length.
+func arrayDec(t reflect.Type, elem *codec) decFunc {
+	return func(r *low.Reader, v reflect.Value) error {
+		off := r.Offset()
+		c, tag, n := r.Array()
+		if err := c.Err(); err != nil {
+			return err
+		}
+		if n != uint64(t.Len()) {
+			return fmt.Errorf("%w: array of %d elements at offset %d can't be decoded into %v", ErrMismatch, n, off, t)
+		}
+		if n > 0 {
+			if err := matchTag(off, tag, elem.tag, t.Elem()); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < int(n); i++ {
+			if err := elem.dec(&c, v.Index(i)); err != nil {
+				return decodeErr(&c, err)
+			}
+		}
+		return c.Err()
+	}
+}
+
+// mapEnc returns the encoder of maps as IDR maps.
+func mapEnc(key, val *codec) encFunc {
+	return func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+		e, p := low.BeginMap(e, key.tag, val.tag, v.Len())
+		it := v.MapRange()
+		for it.Next() {
+			var err error
+			if e, err = key.enc(e, it.Key()); err != nil {
+				return nil, err
+			}
+			if e, err = val.enc(e, it.Value()); err != nil {
+				return nil, err
+			}
+		}
+		return low.EndComposite(e, p), nil
+	}
+}
+
+// mapDec returns the decoder of IDR maps into maps of type t. The entries
+// are added to the map.
+func mapDec(t reflect.Type, key, val *codec) decFunc {
+	return func(r *low.Reader, v reflect.Value) error {
+		off := r.Offset()
+		c, kt, vt, n := r.Map()
+		if err := c.Err(); err != nil {
+			return err
+		}
+		if n > 0 {
+			if err := matchTag(off, kt, key.tag, t.Key()); err != nil {
+				return err
+			}
+			if err := matchTag(off, vt, val.tag, t.Elem()); err != nil {
+				return err
+			}
+		}
+		if !c.Alloc(n, uint64(t.Key().Size()+t.Elem().Size())) {
+			return c.Err()
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, int(min(n, maxPrealloc))))
+		}
+		for i := uint64(0); i < n; i++ {
+			k := reflect.New(t.Key()).Elem()
+			if err := key.dec(&c, k); err != nil {
+				return decodeErr(&c, err)
+			}
+			x := reflect.New(t.Elem()).Elem()
+			if err := val.dec(&c, x); err != nil {
+				return decodeErr(&c, err)
+			}
+			v.SetMapIndex(k, x)
+		}
+		return c.Err()
+	}
+}
+
+// pointerEnc returns the encoder of the values pointed to by pointers of
+// type t.
+func pointerEnc(t reflect.Type, elem *codec) encFunc {
+	return func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+		if v.IsNil() {
+			return nil, fmt.Errorf("%w: nil %v", ErrUnsupported, t)
+		}
+		return elem.enc(e, v.Elem())
+	}
+}
+
+// pointerDec returns the decoder into the values pointed to by pointers of
+// type t. A value is allocated when the pointer is nil.
+func pointerDec(t reflect.Type, elem *codec) decFunc {
+	return func(r *low.Reader, v reflect.Value) error {
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return elem.dec(r, v.Elem())
+	}
+}
+
+// field is a struct field encoded as a record field.
+type field struct {
+	num   uint64
+	index int
+	codec *codec
+}
+
+// fields returns the fields of the struct type t sorted by field number.
+func (b *builder) fields(t reflect.Type) ([]field, error) {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		s, ok := f.Tag.Lookup("idr")
+		if !ok || s == "-" {
+			continue
+		}
+		num, opt, err := parseTag(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %s of %v", err, f.Name, t)
+		}
+		if !f.IsExported() {
+			return nil, fmt.Errorf("%w: field %s of %v is not exported", ErrInvalidTag, f.Name, t)
+		}
+		c, err := b.codec(f.Type, opt)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field{num: num, index: i, codec: c})
+	}
+	slices.SortFunc(fields, func(a, b field) int { return cmp.Compare(a.num, b.num) })
+	for i := 1; i < len(fields); i++ {
+		if fields[i].num == fields[i-1].num {
+			return nil, fmt.Errorf("%w: field number %d of %v is not unique", ErrInvalidTag, fields[i].num, t)
+		}
+	}
+	return fields, nil
+}
+
+// ParseTag returns the field number and the encoding option of the idr
+// struct tag s, of the form "num" or "num,option". The field number must
+// be a positive integer and the option "varint", "fixed" or "micro". The
+// idrgen command accepts the same struct tags.
+func ParseTag(s string) (num uint64, opt string, err error) {
+	s, opt, _ = strings.Cut(s, ",")
+	num, err = strconv.ParseUint(s, 10, 64)
+	if err != nil || num == 0 {
+		return 0, "", fmt.Errorf("%w: field number %q", ErrInvalidTag, s)
+	}
+	if opt != "" && opt != "varint" && opt != "fixed" && opt != "micro" {
+		return 0, "", fmt.Errorf("%w: option %q", ErrInvalidTag, opt)
+	}
+	return num, opt, nil
+}
+
+// parseTag returns the field number and the encoding option of an idr
+// struct tag.
+func parseTag(s string) (uint64, option, error) {
+	num, o, err := ParseTag(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch o {
+	case "varint":
+		return num, varintOpt, nil
+	case "fixed":
+		return num, fixedOpt, nil
+	case "micro":
+		return num, microOpt, nil
+	}
+	return num, defaultOpt, nil
+}
+
+// recordEnc returns the encoder of structs as records.
+func recordEnc(fields []field) encFunc {
+	return func(e low.Encoder, v reflect.Value) (low.Encoder, error) {
+		e, p := low.BeginRecord(e)
+		for _, f := range fields {
+			fv := v.Field(f.index)
+			switch fv.Kind() {
+			case reflect.Pointer, reflect.Slice, reflect.Map:
+				if fv.IsNil() {
+					continue
+				}
+			}
+			var err error
+			e = low.AppendField(e, f.num, f.codec.tag)
+			if e, err = f.codec.enc(e, fv); err != nil {
+				return nil, err
+			}
+		}
+		return low.EndComposite(e, p), nil
+	}
+}
+
+// recordDec returns the decoder of records into structs. Unknown fields
+// are skipped.
+func recordDec(fields []field) decFunc {
+	return func(r *low.Reader, v reflect.Value) error {
+		c := r.Record()
+		for c.Len() > 0 && c.Err() == nil {
+			off := c.Offset()
+			num, tag := c.Field()
+			i, ok := slices.BinarySearchFunc(fields, num, func(f field, num uint64) int {
+				return cmp.Compare(f.num, num)
+			})
+			if !ok {
+				c.SkipValue(tag)
+				continue
+			}
+			f := fields[i]
+			if err := matchTag(off, tag, f.codec.tag, v.Type().Field(f.index).Type); err != nil {
+				return decodeErr(&c, err)
+			}
+			if err := f.codec.dec(&c, v.Field(f.index)); err != nil {
+				return decodeErr(&c, err)
+			}
+		}
+		return c.Err()
+	}
+}
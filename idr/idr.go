@@ -0,0 +1,114 @@
+// Package idr encodes and decodes Go values in the Information Data
+// Representation (IDR) using reflection. It is built on the low level
+// encoding package idr/low.
+//
+// A struct is encoded as a record whose fields are identified by the
+// number given in their idr struct tag. Fields without an idr tag, or
+// with the tag "-", are ignored. The field number may be followed by an
+// encoding option:
+//
+//	Count int       `idr:"1"`        // VarInt
+//	Port  uint16    `idr:"2,varint"` // VarUint64 instead of Uint16
+//	Stamp int64     `idr:"3,fixed"`  // Int64 instead of VarInt64
+//	When  time.Time `idr:"4,fixed"`  // Time instead of VarTime
+//	Born  time.Time `idr:"5,micro"`  // Int64 of the Unix time in µs
+//
+// The option "varint" selects the compact Var encoding of integers,
+// floats and complex numbers, and the option "fixed" selects the fixed
+// size encoding of integers and times. The option "micro" encodes times
+// as the Int64 number of microseconds since the Unix epoch, without the
+// time zone, and decodes them as local times. The option applies to the
+// elements of slices, arrays and maps.
+//
+// Booleans, integers, floats, complex numbers, strings, dir.DIR and
+// time.Time are encoded with the corresponding low level encoding.
+// Byte slices are encoded as blobs. Other slices and arrays are encoded
+// as IDR arrays, maps as IDR maps, and structs as records. Pointers are
+// encoded as the value they point to. Nil pointers, slices and maps of
+// struct fields are omitted. Interfaces, channels and functions are not
+// supported, nor are cyclic data structures.
+package idr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/chmike/ditp/idr/low"
+)
+
+// ErrUnsupported is the error returned when a type or value can't be
+// encoded.
+var ErrUnsupported = errors.New("unsupported type")
+
+// ErrInvalidTag is the error returned when an idr struct tag is invalid.
+var ErrInvalidTag = errors.New("invalid struct tag")
+
+// ErrMismatch is the error returned when the tag of an encoded value
+// doesn't match the type of the decoded value.
+var ErrMismatch = errors.New("type mismatch")
+
+// ErrRange is the error returned when a decoded value overflows the type
+// of the decoded value.
+var ErrRange = errors.New("value out of range")
+
+// Marshal returns the IDR encoding of v as a tagged value. The tag is
+// the one of the type of v, for instance RecordTag for a struct.
+func Marshal(v any) ([]byte, error) {
+	if v == nil {
+		return nil, fmt.Errorf("%w: nil", ErrUnsupported)
+	}
+	rv := reflect.ValueOf(v)
+	c, err := codecOf(rv.Type(), defaultOpt)
+	if err != nil {
+		return nil, err
+	}
+	e := low.AppendTag(nil, c.tag)
+	return c.enc(e, rv)
+}
+
+// MarshalCanonical returns the canonical IDR encoding of v, as defined by
+// low.CheckCanonical, so that the encoding of equal values is the same.
+// Map entries are sorted by key encoding, NaNs have the bits of math.NaN,
+// and times with a zero zone offset are encoded as UTC times.
+func MarshalCanonical(v any) ([]byte, error) {
+	b, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return low.Canonical(b)
+}
+
+// Unmarshal decodes the tagged value encoded in b and stores it in the
+// value pointed to by v. Struct fields not found in the encoded record
+// are left unchanged, and record fields unknown to the struct are
+// skipped. Decoding errors are returned as *low.DecodeError. The default
+// decoding limits are applied.
+func Unmarshal(b []byte, v any) error {
+	return UnmarshalLimits(b, v, low.Limits{})
+}
+
+// UnmarshalLimits is like Unmarshal with the given decoding limits. The
+// allocated bytes accounted are the ones of the decoded strings, byte
+// slices, slice elements and map entries.
+func UnmarshalLimits(b []byte, v any, limits low.Limits) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("%w: Unmarshal needs a non-nil pointer, got %T", ErrUnsupported, v)
+	}
+	c, err := codecOf(rv.Type().Elem(), defaultOpt)
+	if err != nil {
+		return err
+	}
+	r := low.NewLimitedReader(b, limits)
+	if err = checkTag(r, c, rv.Type().Elem()); err == nil {
+		err = c.dec(r, rv.Elem())
+	}
+	if err != nil || r.Err() != nil {
+		return decodeErr(r, err)
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: trailing data at offset %d", low.ErrInvalid, r.Offset())
+	}
+	return nil
+}
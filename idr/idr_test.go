@@ -0,0 +1,321 @@
+package idr
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+	"github.com/chmike/ditp/idr/low"
+)
+
+type inner struct {
+	Name string   `idr:"1"`
+	Tags []string `idr:"2"`
+}
+
+type all struct {
+	Bool       bool                `idr:"1"`
+	Int        int                 `idr:"2"`
+	Int8       int8                `idr:"3"`
+	Int16      int16               `idr:"4"`
+	Int32      int32               `idr:"5"`
+	Int64      int64               `idr:"6"`
+	Uint       uint                `idr:"7"`
+	Uint8      uint8               `idr:"8"`
+	Uint16     uint16              `idr:"9"`
+	Uint32     uint32              `idr:"10"`
+	Uint64     uint64              `idr:"11"`
+	Float32    float32             `idr:"12"`
+	Float64    float64             `idr:"13"`
+	Complex64  complex64           `idr:"14"`
+	Complex128 complex128          `idr:"15"`
+	String     string              `idr:"16"`
+	Blob       []byte              `idr:"17"`
+	DIR        dir.DIR             `idr:"18"`
+	Time       time.Time           `idr:"19"`
+	FixedTime  time.Time           `idr:"20,fixed"`
+	VarInt16   int16               `idr:"21,varint"`
+	FixedInt   int                 `idr:"22,fixed"`
+	VarUint32  uint32              `idr:"23,varint"`
+	VarFloat   float64             `idr:"24,varint"`
+	VarComplex complex64           `idr:"25,varint"`
+	Slice      []int32             `idr:"26"`
+	VarSlice   []int32             `idr:"27,varint"`
+	Array      [3]uint8            `idr:"28"`
+	Map        map[string]float32  `idr:"29"`
+	Inner      inner               `idr:"30"`
+	Ptr        *inner              `idr:"31"`
+	Ptrs       []*inner            `idr:"32"`
+	NilPtr     *inner              `idr:"33"`
+	Nested     map[dir.DIR][]inner `idr:"34"`
+	MicroTime  time.Time           `idr:"35,micro"`
+	Ignored    int
+	Skipped    int `idr:"-"`
+}
+
+type list struct {
+	Value int   `idr:"1"`
+	Next  *list `idr:"2"`
+}
+
+func TestMarshal(t *testing.T) {
+	now := time.Now()
+	in := all{
+		Bool:       true,
+		Int:        -1,
+		Int8:       math.MinInt8,
+		Int16:      math.MaxInt16,
+		Int32:      -3,
+		Int64:      math.MinInt64,
+		Uint:       300,
+		Uint8:      math.MaxUint8,
+		Uint16:     4,
+		Uint32:     5,
+		Uint64:     math.MaxUint64,
+		Float32:    1.5,
+		Float64:    -2.25,
+		Complex64:  complex(1, 2),
+		Complex128: complex(-3, 4),
+		String:     "string",
+		Blob:       []byte{1, 2, 3},
+		DIR:        dir.MustMake(1, 2, 0),
+		Time:       now,
+		FixedTime:  now.Truncate(time.Microsecond),
+		VarInt16:   -7,
+		FixedInt:   8,
+		VarUint32:  9,
+		VarFloat:   10.5,
+		VarComplex: complex(11, 12),
+		Slice:      []int32{1, -2, 3},
+		VarSlice:   []int32{},
+		Array:      [3]uint8{4, 5, 6},
+		Map:        map[string]float32{"a": 1, "b": 2},
+		Inner:      inner{Name: "inner", Tags: []string{"x", "y"}},
+		Ptr:        &inner{Name: "ptr"},
+		Ptrs:       []*inner{{Name: "a"}, {Name: "b"}},
+		Nested:     map[dir.DIR][]inner{dir.MustMake(1, 0): {{Name: "n"}}},
+		MicroTime:  now.Truncate(time.Microsecond),
+		Ignored:    1,
+		Skipped:    2,
+	}
+	b, err := Marshal(&in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out all
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !out.Time.Equal(in.Time) || !out.FixedTime.Equal(in.FixedTime) || !out.MicroTime.Equal(in.MicroTime) {
+		t.Errorf("expect times %v, %v and %v, got %v, %v and %v", in.Time, in.FixedTime, in.MicroTime, out.Time, out.FixedTime, out.MicroTime)
+	}
+	out.Time, out.FixedTime, out.MicroTime = in.Time, in.FixedTime, in.MicroTime
+	in.Ignored, in.Skipped = 0, 0
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("expect %+v, got %+v", in, out)
+	}
+
+	// the encoding is built with the low level encoding
+	e := low.AppendTag(nil, low.RecordTag)
+	e, p := low.BeginRecord(e)
+	e = low.AppendField(e, 1, low.StringTag)
+	e = low.AppendString(e, "inner")
+	e = low.AppendField(e, 2, low.ArrayTag)
+	e, q := low.BeginArray(e, low.StringTag, 1)
+	e = low.AppendString(e, "x")
+	e = low.EndComposite(e, q)
+	e = low.EndComposite(e, p)
+	b, err = Marshal(inner{Name: "inner", Tags: []string{"x"}})
+	if err != nil || !bytes.Equal(b, e) {
+		t.Errorf("expect %#v, got %#v (%v)", e, b, err)
+	}
+
+	// top level values that are not records
+	tests := []any{
+		// 0
+		true, -1, uint16(2), "s", []byte{1}, dir.MustMake(1, 0),
+		[]string{"a", "b"}, map[int]bool{1: true}, [2]int8{1, -1},
+	}
+	for i, test := range tests {
+		b, err := Marshal(test)
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		v := reflect.New(reflect.TypeOf(test))
+		if err := Unmarshal(b, v.Interface()); err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+		} else if !reflect.DeepEqual(v.Elem().Interface(), test) {
+			t.Errorf("%d expect %v, got %v", i, test, v.Elem())
+		}
+	}
+
+	// recursive type
+	l := &list{Value: 1, Next: &list{Value: 2, Next: &list{Value: 3}}}
+	b, err = Marshal(l)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var l2 *list
+	if err := Unmarshal(b, &l2); err != nil || !reflect.DeepEqual(l, l2) {
+		t.Errorf("expect %+v, got %+v (%v)", l, l2, err)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	type badNum struct {
+		A int `idr:"0"`
+	}
+	type badOpt struct {
+		A int `idr:"1,zigzag"`
+	}
+	type dupNum struct {
+		A int `idr:"1"`
+		B int `idr:"1"`
+	}
+	type unexported struct {
+		a int `idr:"1"`
+	}
+	type badType struct {
+		A any `idr:"1"`
+	}
+	tests := []struct {
+		v   any
+		err error
+	}{
+		// 0
+		{v: nil, err: ErrUnsupported},
+		{v: (*inner)(nil), err: ErrUnsupported},
+		{v: badNum{}, err: ErrInvalidTag},
+		{v: badOpt{}, err: ErrInvalidTag},
+		{v: dupNum{}, err: ErrInvalidTag},
+		// 5
+		{v: unexported{a: 1}, err: ErrInvalidTag},
+		{v: badType{}, err: ErrUnsupported},
+		{v: make(chan int), err: ErrUnsupported},
+		{v: []*inner{nil}, err: ErrUnsupported},
+		{v: new(*int), err: ErrUnsupported},
+	}
+	for i, test := range tests {
+		if _, err := Marshal(test.v); !errors.Is(err, test.err) {
+			t.Errorf("%d expect %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	type small struct {
+		A int8 `idr:"1,varint"`
+	}
+	type big struct {
+		A int64  `idr:"1"`
+		B string `idr:"2"`
+	}
+	type other struct {
+		B bool `idr:"2"`
+	}
+	b, _ := Marshal(big{A: 1000, B: "b"})
+
+	// unknown fields are skipped
+	var s small
+	if err := Unmarshal(b, &s); !errors.Is(err, ErrRange) {
+		t.Errorf("expect ErrRange, got %v", err)
+	}
+	b2, _ := Marshal(big{A: -100, B: "b"})
+	if err := Unmarshal(b2, &s); err != nil || s.A != -100 {
+		t.Errorf("expect -100, got %d (%v)", s.A, err)
+	}
+
+	var o other
+	if err := Unmarshal(b, &o); !errors.Is(err, ErrMismatch) {
+		t.Errorf("expect ErrMismatch, got %v", err)
+	}
+	var x []int
+	if err := Unmarshal(b, &x); !errors.Is(err, ErrMismatch) {
+		t.Errorf("expect ErrMismatch, got %v", err)
+	}
+	var a [2]int
+	b3, _ := Marshal([]int{1, 2, 3})
+	if err := Unmarshal(b3, &a); !errors.Is(err, ErrMismatch) {
+		t.Errorf("expect ErrMismatch, got %v", err)
+	}
+	var de *low.DecodeError
+	if err := Unmarshal(b[:len(b)-1], &big{}); !errors.As(err, &de) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expect DecodeError with ErrUnexpectedEOF, got %v", err)
+	}
+	if err := Unmarshal(b3[:len(b3)-1], &x); !errors.As(err, &de) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expect DecodeError with ErrUnexpectedEOF, got %v", err)
+	}
+	if err := Unmarshal(append(b, 0), &big{}); !errors.Is(err, low.ErrInvalid) {
+		t.Errorf("expect ErrInvalid, got %v", err)
+	}
+	if err := UnmarshalLimits(b, &big{}, low.Limits{MaxStringSize: 1}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := UnmarshalLimits(b, &big{}, low.Limits{MaxAlloc: 1, MaxStringSize: 1}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	b4, _ := Marshal("hello")
+	var str string
+	if err := UnmarshalLimits(b4, &str, low.Limits{MaxStringSize: 4}); !errors.As(err, &de) || !errors.Is(err, low.ErrTooBig) {
+		t.Errorf("expect DecodeError with ErrTooBig, got %v", err)
+	}
+	if err := UnmarshalLimits(b3, &x, low.Limits{MaxAlloc: 16}); !errors.As(err, &de) || !errors.Is(err, low.ErrAllocLimit) {
+		t.Errorf("expect DecodeError with ErrAllocLimit, got %v", err)
+	}
+	if err := UnmarshalLimits(b3, &x, low.Limits{MaxAlloc: 24}); err != nil || len(x) != 3 {
+		t.Errorf("expect 3 elements, got %v (%v)", x, err)
+	}
+	type node struct {
+		Next *node `idr:"1"`
+	}
+	b5, _ := Marshal(node{Next: &node{Next: &node{}}})
+	if err := UnmarshalLimits(b5, &node{}, low.Limits{MaxDepth: 2}); !errors.As(err, &de) || !errors.Is(err, low.ErrTooDeep) {
+		t.Errorf("expect DecodeError with ErrTooDeep, got %v", err)
+	}
+	if err := Unmarshal(b, big{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expect ErrUnsupported, got %v", err)
+	}
+	if err := Unmarshal(b, (*big)(nil)); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expect ErrUnsupported, got %v", err)
+	}
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	type rec struct {
+		Map  map[string]int `idr:"2"`
+		When time.Time      `idr:"1"`
+	}
+	in := rec{Map: make(map[string]int), When: time.Date(2024, 5, 1, 0, 0, 0, 0, time.FixedZone("", 0))}
+	for i := 0; i < 20; i++ {
+		in.Map[string(rune('a'+i))] = i
+	}
+	b, err := MarshalCanonical(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := low.CheckCanonical(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		b2, err := MarshalCanonical(in)
+		if err != nil || !bytes.Equal(b, b2) {
+			t.Fatalf("expect same encoding, got %x and %x, %v", b, b2, err)
+		}
+	}
+	var out rec
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(in.Map, out.Map) || !out.When.Equal(in.When) || out.When.Location() != time.UTC {
+		t.Errorf("expect %v, got %v", in, out)
+	}
+	if _, err := MarshalCanonical(make(chan int)); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expect ErrUnsupported, got %v", err)
+	}
+}
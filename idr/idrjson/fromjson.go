@@ -0,0 +1,460 @@
+package idrjson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+	"github.com/chmike/ditp/idr/low"
+)
+
+// FromJSON returns the tagged IDR value of its JSON representation j.
+func FromJSON(j []byte) ([]byte, error) {
+	var o object
+	if err := unmarshal(j, &o); err != nil {
+		return nil, err
+	}
+	if o.Num != nil {
+		return nil, fmt.Errorf("%w: tagged value with num member", ErrInvalid)
+	}
+	return appendTagged(nil, &o)
+}
+
+// unmarshal decodes the JSON object j into o. Unknown members are invalid.
+func unmarshal(j []byte, o *object) error {
+	d := json.NewDecoder(bytes.NewReader(j))
+	d.DisallowUnknownFields()
+	if err := d.Decode(o); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if d.More() {
+		return fmt.Errorf("%w: trailing data at offset %d", ErrInvalid, d.InputOffset())
+	}
+	return nil
+}
+
+// tagOf returns the tag of the type name.
+func tagOf(name string) (low.TagT, error) {
+	t, ok := tags[name]
+	if !ok {
+		return t, fmt.Errorf("%w: unknown type %q", ErrInvalid, name)
+	}
+	return t, nil
+}
+
+// appendTagged appends the tagged value of the object o.
+func appendTagged(e low.Encoder, o *object) (low.Encoder, error) {
+	t, err := tagOf(o.Type)
+	if err != nil {
+		return e, err
+	}
+	e = low.AppendTag(e, t)
+	if isComposite(t) {
+		return appendComposite(e, t, o)
+	}
+	if o.Elem != "" || o.Key != "" || o.Items != nil || o.Entries != nil || o.Fields != nil {
+		return e, fmt.Errorf("%w: %s value with composite members", ErrInvalid, o.Type)
+	}
+	return appendScalar(e, t, o.Value)
+}
+
+// appendValue appends the value of type t represented by j.
+func appendValue(e low.Encoder, t low.TagT, j json.RawMessage) (low.Encoder, error) {
+	if !isComposite(t) {
+		return appendScalar(e, t, j)
+	}
+	var o object
+	if err := unmarshal(j, &o); err != nil {
+		return e, err
+	}
+	if o.Type != "" || o.Num != nil || o.Value != nil {
+		return e, fmt.Errorf("%w: untagged %s value with type, num or value member", ErrInvalid, tagName(t))
+	}
+	return appendComposite(e, t, &o)
+}
+
+// appendComposite appends the composite value of type t of the object o.
+func appendComposite(e low.Encoder, t low.TagT, o *object) (low.Encoder, error) {
+	stray := o.Value != nil
+	switch t {
+	case low.ArrayTag:
+		stray = stray || o.Key != "" || o.Entries != nil || o.Fields != nil
+	case low.ListTag:
+		stray = stray || o.Elem != "" || o.Key != "" || o.Entries != nil || o.Fields != nil
+	case low.MapTag:
+		stray = stray || o.Items != nil || o.Fields != nil
+	case low.RecordTag:
+		stray = stray || o.Elem != "" || o.Key != "" || o.Items != nil || o.Entries != nil
+	}
+	if stray {
+		return e, fmt.Errorf("%w: %s value with members of other types", ErrInvalid, tagName(t))
+	}
+	var p int
+	var err error
+	switch t {
+	case low.ArrayTag:
+		et, err := tagOf(o.Elem)
+		if err != nil {
+			return e, err
+		}
+		e, p = low.BeginArray(e, et, len(o.Items))
+		for _, j := range o.Items {
+			if e, err = appendValue(e, et, j); err != nil {
+				return e, err
+			}
+		}
+	case low.ListTag:
+		e, p = low.BeginList(e, len(o.Items))
+		for _, j := range o.Items {
+			var item object
+			if err := unmarshal(j, &item); err != nil {
+				return e, err
+			}
+			if item.Num != nil {
+				return e, fmt.Errorf("%w: list item with num member", ErrInvalid)
+			}
+			if e, err = appendTagged(e, &item); err != nil {
+				return e, err
+			}
+		}
+	case low.MapTag:
+		kt, err := tagOf(o.Key)
+		if err != nil {
+			return e, err
+		}
+		vt, err := tagOf(o.Elem)
+		if err != nil {
+			return e, err
+		}
+		e, p = low.BeginMap(e, kt, vt, len(o.Entries))
+		for _, kv := range o.Entries {
+			if e, err = appendValue(e, kt, kv[0]); err != nil {
+				return e, err
+			}
+			if e, err = appendValue(e, vt, kv[1]); err != nil {
+				return e, err
+			}
+		}
+	case low.RecordTag:
+		e, p = low.BeginRecord(e)
+		for _, j := range o.Fields {
+			var f object
+			if err := unmarshal(j, &f); err != nil {
+				return e, err
+			}
+			if f.Num == nil {
+				return e, fmt.Errorf("%w: record field without num member", ErrInvalid)
+			}
+			e = low.AppendVarUint64(e, *f.Num)
+			if e, err = appendTagged(e, &f); err != nil {
+				return e, err
+			}
+		}
+	}
+	return low.EndComposite(e, p), nil
+}
+
+// appendScalar appends the scalar value of type t represented by j.
+func appendScalar(e low.Encoder, t low.TagT, j json.RawMessage) (low.Encoder, error) {
+	if t == low.NoneTag {
+		if j != nil && string(j) != "null" {
+			return e, fmt.Errorf("%w: none value %s", ErrInvalid, j)
+		}
+		return e, nil
+	}
+	invalid := fmt.Errorf("%w: invalid %s value %s", ErrInvalid, tagName(t), j)
+	if j == nil {
+		return e, fmt.Errorf("%w: missing %s value", ErrInvalid, tagName(t))
+	}
+	switch t {
+	case low.BoolTag:
+		switch string(j) {
+		case "true":
+			return low.AppendBool(e, true), nil
+		case "false":
+			return low.AppendBool(e, false), nil
+		}
+	case low.ByteTag, low.Uint8Tag, low.Uint16Tag, low.Uint32Tag, low.Uint64Tag,
+		low.SizeTag, low.VarUintTag, low.VarUint64Tag:
+		v, err := strconv.ParseUint(number(j), 10, uintSize(t))
+		if err != nil {
+			return e, invalid
+		}
+		switch t {
+		case low.ByteTag, low.Uint8Tag:
+			return low.AppendUint8(e, uint8(v)), nil
+		case low.Uint16Tag:
+			return low.AppendUint16(e, uint16(v)), nil
+		case low.Uint32Tag:
+			return low.AppendUint32(e, uint32(v)), nil
+		case low.Uint64Tag:
+			return low.AppendUint64(e, v), nil
+		}
+		return low.AppendVarUint64(e, v), nil
+	case low.Int8Tag, low.Int16Tag, low.Int32Tag, low.Int64Tag, low.VarIntTag, low.VarInt64Tag:
+		v, err := strconv.ParseInt(number(j), 10, intSize(t))
+		if err != nil {
+			return e, invalid
+		}
+		switch t {
+		case low.Int8Tag:
+			return low.AppendInt8(e, int8(v)), nil
+		case low.Int16Tag:
+			return low.AppendInt16(e, int16(v)), nil
+		case low.Int32Tag:
+			return low.AppendInt32(e, int32(v)), nil
+		case low.Int64Tag:
+			return low.AppendInt64(e, v), nil
+		}
+		return low.AppendVarInt64(e, v), nil
+	case low.Float32Tag:
+		if v, ok := parseFloat(j, 32); ok {
+			return low.AppendUint32(e, uint32(v)), nil
+		}
+	case low.Float64Tag:
+		if v, ok := parseFloat(j, 64); ok {
+			return low.AppendUint64(e, v), nil
+		}
+	case low.VarFloatTag:
+		if v, ok := parseFloat(j, 64); ok {
+			return low.AppendVarFloat(e, math.Float64frombits(v)), nil
+		}
+	case low.Complex64Tag, low.Complex128Tag, low.VarComplexTag:
+		var parts []json.RawMessage
+		if json.Unmarshal(j, &parts) != nil || len(parts) != 2 {
+			return e, invalid
+		}
+		size := 64
+		if t == low.Complex64Tag {
+			size = 32
+		}
+		re, ok1 := parseFloat(parts[0], size)
+		im, ok2 := parseFloat(parts[1], size)
+		if !ok1 || !ok2 {
+			return e, invalid
+		}
+		switch t {
+		case low.Complex64Tag:
+			return low.AppendUint32(low.AppendUint32(e, uint32(re)), uint32(im)), nil
+		case low.Complex128Tag:
+			return low.AppendUint64(low.AppendUint64(e, re), im), nil
+		}
+		c := complex(math.Float64frombits(re), math.Float64frombits(im))
+		return low.AppendVarComplex(e, c), nil
+	case low.TimeTag, low.VarTimeTag:
+		var s string
+		if json.Unmarshal(j, &s) != nil {
+			return e, invalid
+		}
+		v, err := parseTime(s)
+		if err != nil {
+			return e, invalid
+		}
+		if t == low.TimeTag {
+			return low.AppendTime(e, v), nil
+		}
+		return low.AppendVarTime(e, v), nil
+	case low.BlobTag:
+		var s string
+		if json.Unmarshal(j, &s) != nil {
+			return e, invalid
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return e, invalid
+		}
+		return low.AppendBlob(e, b), nil
+	case low.StringTag:
+		var s string
+		if json.Unmarshal(j, &s) == nil {
+			return low.AppendString(e, s), nil
+		}
+		var o struct {
+			Base64 *string `json:"base64"`
+		}
+		d := json.NewDecoder(bytes.NewReader(j))
+		d.DisallowUnknownFields()
+		if d.Decode(&o) != nil || o.Base64 == nil {
+			return e, invalid
+		}
+		b, err := base64.StdEncoding.DecodeString(*o.Base64)
+		if err != nil {
+			return e, invalid
+		}
+		return low.AppendBlob(e, b), nil
+	case low.DIRTag:
+		var s string
+		if json.Unmarshal(j, &s) != nil {
+			return e, invalid
+		}
+		d, err := dir.DecodeURI(s)
+		if err != nil {
+			return e, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		return low.AppendDIR(e, d), nil
+	case low.DeltaInt64Tag, low.MonoInt64Tag, low.DeltaDeltaInt64Tag:
+		var items []json.RawMessage
+		if json.Unmarshal(j, &items) != nil {
+			return e, invalid
+		}
+		v := make([]int64, len(items))
+		for i, item := range items {
+			x, err := strconv.ParseInt(number(item), 10, 64)
+			if err != nil {
+				return e, invalid
+			}
+			v[i] = x
+		}
+		switch t {
+		case low.DeltaInt64Tag:
+			return low.AppendDeltaSeq(e, v), nil
+		case low.MonoInt64Tag:
+			return low.AppendMonoSeq(e, v), nil
+		}
+		return low.AppendDeltaDeltaSeq(e, v), nil
+	case low.DeltaUint64Tag, low.MonoUint64Tag:
+		var items []json.RawMessage
+		if json.Unmarshal(j, &items) != nil {
+			return e, invalid
+		}
+		v := make([]uint64, len(items))
+		for i, item := range items {
+			x, err := strconv.ParseUint(number(item), 10, 64)
+			if err != nil {
+				return e, invalid
+			}
+			v[i] = x
+		}
+		if t == low.DeltaUint64Tag {
+			return low.AppendDeltaSeq(e, v), nil
+		}
+		return low.AppendMonoSeq(e, v), nil
+	}
+	return e, invalid
+}
+
+// number returns the text of the JSON number or string j.
+func number(j json.RawMessage) string {
+	var s string
+	if json.Unmarshal(j, &s) == nil {
+		return s
+	}
+	return string(j)
+}
+
+// uintSize returns the bit size of the unsigned integers of type t.
+func uintSize(t low.TagT) int {
+	switch t {
+	case low.ByteTag, low.Uint8Tag:
+		return 8
+	case low.Uint16Tag:
+		return 16
+	case low.Uint32Tag:
+		return 32
+	case low.VarUintTag:
+		return strconv.IntSize
+	}
+	return 64
+}
+
+// intSize returns the bit size of the signed integers of type t.
+func intSize(t low.TagT) int {
+	switch t {
+	case low.Int8Tag:
+		return 8
+	case low.Int16Tag:
+		return 16
+	case low.Int32Tag:
+		return 32
+	case low.VarIntTag:
+		return strconv.IntSize
+	}
+	return 64
+}
+
+// parseFloat returns the bits of the float of the given bit size
+// represented by j, and false if j is invalid.
+func parseFloat(j json.RawMessage, size int) (uint64, bool) {
+	var s string
+	if json.Unmarshal(j, &s) != nil {
+		f, err := strconv.ParseFloat(string(j), size)
+		if err != nil {
+			return 0, false
+		}
+		if size == 32 {
+			return uint64(math.Float32bits(float32(f))), true
+		}
+		return math.Float64bits(f), true
+	}
+	switch s {
+	case "NaN":
+		if size == 32 {
+			return nan32, true
+		}
+		return nan64, true
+	case "+Inf":
+		if size == 32 {
+			return uint64(math.Float32bits(float32(math.Inf(1)))), true
+		}
+		return math.Float64bits(math.Inf(1)), true
+	case "-Inf":
+		if size == 32 {
+			return uint64(math.Float32bits(float32(math.Inf(-1)))), true
+		}
+		return math.Float64bits(math.Inf(-1)), true
+	}
+	hex, ok := strings.CutPrefix(s, "NaN(0x")
+	if hex, ok = strings.CutSuffix(hex, ")"); !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(hex, 16, size)
+	if err != nil {
+		return 0, false
+	}
+	if size == 32 && !math.IsNaN(float64(math.Float32frombits(uint32(v)))) ||
+		size == 64 && !math.IsNaN(math.Float64frombits(v)) {
+		return 0, false
+	}
+	return v, true
+}
+
+// parseTime parses a time in the RFC 3339 format with an optional
+// seconds zone offset. The location is UTC for the zone offset Z.
+func parseTime(s string) (time.Time, error) {
+	if v, ok := strings.CutSuffix(s, "Z"); ok {
+		return time.Parse(timeLayout, v)
+	}
+	i := strings.LastIndexAny(s, "+-")
+	if i < 0 || i < strings.IndexByte(s, 'T') {
+		return time.Time{}, fmt.Errorf("missing zone offset")
+	}
+	parts := strings.Split(s[i+1:], ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return time.Time{}, fmt.Errorf("invalid zone offset")
+	}
+	var off int
+	for k, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || len(p) < 2 || k > 0 && v > 59 {
+			return time.Time{}, fmt.Errorf("invalid zone offset")
+		}
+		off = off*60 + int(v)
+	}
+	if len(parts) == 2 {
+		off *= 60
+	}
+	if s[i] == '-' {
+		off = -off
+	}
+	t, err := time.Parse(timeLayout, s[:i])
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(t.Unix()-int64(off), int64(t.Nanosecond())).In(time.FixedZone("", off)), nil
+}
@@ -0,0 +1,463 @@
+// Package idrjson converts tagged IDR values to JSON and back without
+// loss of type information.
+//
+// A tagged value is a JSON object whose member "type" is the name of the
+// tag, lower cased and without the Tag suffix, as in IDR schemas. The
+// member "value" holds the value of scalar types:
+//
+//	{"type":"uint16","value":300}
+//	{"type":"varuint64","value":"18446744073709551615"}
+//	{"type":"vartime","value":"2024-05-01T12:30:00.5+02:00"}
+//
+// The values are represented as follows:
+//
+//   - bool is a JSON boolean.
+//   - byte and the integers of at most 32 bits are JSON numbers.
+//   - The integers of 64 bits, uint64, int64, size, varuint, varint,
+//     varuint64 and varint64, are decimal JSON strings because JSON
+//     numbers are often decoded as float64.
+//   - Floats are JSON numbers, or the strings "NaN", "+Inf" and "-Inf".
+//     A NaN whose bits differ from math.NaN is the string "NaN(0x...)"
+//     with its bits in hexadecimal.
+//   - Complex numbers are an array of the real and imaginary part.
+//   - time and vartime are RFC 3339 strings with their zone offset. The
+//     offset is Z only for UTC times, and has seconds when needed.
+//   - blob is a base64 string.
+//   - string is a JSON string, or an object {"base64":"..."} when it is
+//     not valid UTF-8.
+//   - dir is the URI form of the DIR, as "dis:1.2/".
+//   - The integer sequences, as deltaint64 or monouint64, are arrays of
+//     decimal strings.
+//   - none has no value.
+//
+// The composite values are objects. An array has the member "elem" with
+// the tag name of the elements and the member "items" with their values.
+// A list has the member "items" with its tagged values. A map has the
+// members "key" and "elem" with the tag names of the keys and values,
+// and the member "entries" with an array of [key, value] pairs. A record
+// has the member "fields" with the tagged values of its fields, having
+// the additional member "num" with the field number.
+//
+//	{"type":"record","fields":[
+//		{"num":1,"type":"string","value":"Bob"},
+//		{"num":2,"type":"array","elem":"varint","items":[1,2]},
+//		{"num":3,"type":"map","key":"string","elem":"bool","entries":[["a",true]]}]}
+//
+// Converting to IDR the JSON of IDR data produced by the low level
+// encoders yields the same bytes.
+package idrjson
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/chmike/ditp/idr/low"
+)
+
+// ErrInvalid is the error returned when the JSON representation of a
+// value is invalid.
+var ErrInvalid = errors.New("invalid IDR JSON")
+
+// object is the JSON object of a tagged value or of a composite value.
+type object struct {
+	Num     *uint64              `json:"num,omitempty"`
+	Type    string               `json:"type,omitempty"`
+	Value   json.RawMessage      `json:"value,omitempty"`
+	Key     string               `json:"key,omitempty"`
+	Elem    string               `json:"elem,omitempty"`
+	Items   []json.RawMessage    `json:"items,omitempty"`
+	Entries [][2]json.RawMessage `json:"entries,omitempty"`
+	Fields  []json.RawMessage    `json:"fields,omitempty"`
+}
+
+// itemSize is the byte size of an array item or of a map key or value
+// accounted as allocated by the Reader, so that a hostile element count
+// of NoneTag values is bounded by the allocation limit.
+const itemSize = uint64(unsafe.Sizeof(json.RawMessage{}))
+
+// tags maps the tag names to the tags.
+var tags = func() map[string]low.TagT {
+	m := make(map[string]low.TagT)
+	for t := low.NoneTag; t < low.MaxTag; t++ {
+		if t != low.BytesTag {
+			m[tagName(t)] = t
+		}
+	}
+	return m
+}()
+
+// tagName returns the name of the tag t.
+func tagName(t low.TagT) string {
+	return strings.ToLower(strings.TrimSuffix(t.String(), "Tag"))
+}
+
+// isComposite returns true if t is the tag of a composite value.
+func isComposite(t low.TagT) bool {
+	return t == low.ArrayTag || t == low.ListTag || t == low.MapTag || t == low.RecordTag
+}
+
+// canonical NaN bits
+var (
+	nan32 = uint64(math.Float32bits(float32(math.NaN())))
+	nan64 = math.Float64bits(math.NaN())
+)
+
+// timeLayout is the layout of times without their zone offset.
+const timeLayout = "2006-01-02T15:04:05.999999999"
+
+// ToJSON returns the JSON representation of the tagged IDR value b.
+func ToJSON(b []byte) ([]byte, error) {
+	r := low.NewReader(b)
+	o, err := tagged(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%w: trailing data at offset %d", low.ErrInvalid, r.Offset())
+	}
+	return json.Marshal(o)
+}
+
+// tag returns the next tag and its name.
+func tag(r *low.Reader) (low.TagT, string, error) {
+	off := r.Offset()
+	t := r.Tag()
+	if r.Err() != nil {
+		return t, "", r.Err()
+	}
+	if t >= low.MaxTag || t == low.BytesTag {
+		return t, "", fmt.Errorf("%w: tag %d at offset %d", low.ErrInvalid, uint64(t), off)
+	}
+	return t, tagName(t), nil
+}
+
+// tagged returns the object of the next tagged value.
+func tagged(r *low.Reader) (*object, error) {
+	t, name, err := tag(r)
+	if err != nil {
+		return nil, err
+	}
+	return typed(r, t, name)
+}
+
+// typed returns the object of the next value of type t with the type name.
+func typed(r *low.Reader, t low.TagT, name string) (*object, error) {
+	if isComposite(t) {
+		o, err := composite(r, t)
+		if err != nil {
+			return nil, err
+		}
+		o.Type = name
+		return o, nil
+	}
+	if t == low.NoneTag {
+		return &object{Type: name}, nil
+	}
+	v, err := scalar(r, t)
+	if err != nil {
+		return nil, err
+	}
+	return &object{Type: name, Value: v}, nil
+}
+
+// value returns the JSON representation of the next value of type t.
+func value(r *low.Reader, t low.TagT) (json.RawMessage, error) {
+	if !isComposite(t) {
+		return scalar(r, t)
+	}
+	o, err := composite(r, t)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(o)
+}
+
+// composite returns the object of the next composite value of type t,
+// without its type.
+func composite(r *low.Reader, t low.TagT) (*object, error) {
+	var o object
+	off := r.Offset()
+	switch t {
+	case low.ArrayTag:
+		c, et, n := r.Array()
+		if err := errOf(r, &c); err != nil {
+			return nil, err
+		}
+		if et >= low.MaxTag || et == low.BytesTag {
+			return nil, fmt.Errorf("%w: array element tag %d at offset %d", low.ErrInvalid, uint64(et), off)
+		}
+		o.Elem = tagName(et)
+		if !c.Alloc(n, itemSize) {
+			return nil, c.Err()
+		}
+		for i := uint64(0); i < n; i++ {
+			v, err := value(&c, et)
+			if err != nil {
+				return nil, err
+			}
+			o.Items = append(o.Items, v)
+		}
+	case low.ListTag:
+		c, n := r.List()
+		if err := errOf(r, &c); err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < n; i++ {
+			e, err := tagged(&c)
+			if err != nil {
+				return nil, err
+			}
+			v, err := json.Marshal(e)
+			if err != nil {
+				return nil, err
+			}
+			o.Items = append(o.Items, v)
+		}
+	case low.MapTag:
+		c, kt, vt, n := r.Map()
+		if err := errOf(r, &c); err != nil {
+			return nil, err
+		}
+		for _, t := range []low.TagT{kt, vt} {
+			if t >= low.MaxTag || t == low.BytesTag {
+				return nil, fmt.Errorf("%w: map tag %d at offset %d", low.ErrInvalid, uint64(t), off)
+			}
+		}
+		o.Key, o.Elem = tagName(kt), tagName(vt)
+		if !c.Alloc(n, 2*itemSize) {
+			return nil, c.Err()
+		}
+		for i := uint64(0); i < n; i++ {
+			k, err := value(&c, kt)
+			if err != nil {
+				return nil, err
+			}
+			v, err := value(&c, vt)
+			if err != nil {
+				return nil, err
+			}
+			o.Entries = append(o.Entries, [2]json.RawMessage{k, v})
+		}
+	case low.RecordTag:
+		c := r.Record()
+		if err := errOf(r, &c); err != nil {
+			return nil, err
+		}
+		for c.Len() > 0 {
+			num := c.VarUint64()
+			t, name, err := tag(&c)
+			if err != nil {
+				return nil, err
+			}
+			f, err := typed(&c, t, name)
+			if err != nil {
+				return nil, err
+			}
+			f.Num = &num
+			v, err := json.Marshal(f)
+			if err != nil {
+				return nil, err
+			}
+			o.Fields = append(o.Fields, v)
+		}
+	}
+	return &o, nil
+}
+
+// errOf returns the error of the reader r or of the reader c of the
+// content of a composite value.
+func errOf(r, c *low.Reader) error {
+	if r.Err() != nil {
+		return r.Err()
+	}
+	return c.Err()
+}
+
+// scalar returns the JSON representation of the next scalar value of
+// type t.
+func scalar(r *low.Reader, t low.TagT) (json.RawMessage, error) {
+	var b []byte
+	switch t {
+	case low.NoneTag:
+		return json.RawMessage("null"), nil
+	case low.BoolTag:
+		b = strconv.AppendBool(b, r.Bool())
+	case low.ByteTag:
+		b = strconv.AppendUint(b, uint64(r.Byte()), 10)
+	case low.Uint8Tag:
+		b = strconv.AppendUint(b, uint64(r.Uint8()), 10)
+	case low.Uint16Tag:
+		b = strconv.AppendUint(b, uint64(r.Uint16()), 10)
+	case low.Uint32Tag:
+		b = strconv.AppendUint(b, uint64(r.Uint32()), 10)
+	case low.Uint64Tag:
+		b = quoteUint(b, r.Uint64())
+	case low.Int8Tag:
+		b = strconv.AppendInt(b, int64(r.Int8()), 10)
+	case low.Int16Tag:
+		b = strconv.AppendInt(b, int64(r.Int16()), 10)
+	case low.Int32Tag:
+		b = strconv.AppendInt(b, int64(r.Int32()), 10)
+	case low.Int64Tag:
+		b = quoteInt(b, r.Int64())
+	case low.Float32Tag:
+		b = appendFloat(b, uint64(math.Float32bits(r.Float32())), 32)
+	case low.Float64Tag:
+		b = appendFloat(b, math.Float64bits(r.Float64()), 64)
+	case low.Complex64Tag:
+		c := r.Complex64()
+		b = append(b, '[')
+		b = appendFloat(b, uint64(math.Float32bits(real(c))), 32)
+		b = append(b, ',')
+		b = appendFloat(b, uint64(math.Float32bits(imag(c))), 32)
+		b = append(b, ']')
+	case low.Complex128Tag, low.VarComplexTag:
+		var c complex128
+		if t == low.Complex128Tag {
+			c = r.Complex128()
+		} else {
+			c = r.VarComplex()
+		}
+		b = append(b, '[')
+		b = appendFloat(b, math.Float64bits(real(c)), 64)
+		b = append(b, ',')
+		b = appendFloat(b, math.Float64bits(imag(c)), 64)
+		b = append(b, ']')
+	case low.TimeTag, low.VarTimeTag:
+		off := r.Offset()
+		var v time.Time
+		if t == low.TimeTag {
+			v = r.Time()
+		} else {
+			v = r.VarTime()
+		}
+		if r.Err() != nil {
+			break
+		}
+		var err error
+		if b, err = appendTime(b, v); err != nil {
+			return nil, fmt.Errorf("%w: %v at offset %d", low.ErrInvalid, err, off)
+		}
+	case low.SizeTag:
+		b = quoteUint(b, r.Size())
+	case low.BlobTag:
+		b = append(b, '"')
+		b = base64.StdEncoding.AppendEncode(b, r.Blob(uint64(r.Len())))
+		b = append(b, '"')
+	case low.StringTag:
+		s := r.Blob(uint64(r.Len()))
+		if !utf8.Valid(s) {
+			b = append(b, `{"base64":"`...)
+			b = base64.StdEncoding.AppendEncode(b, s)
+			b = append(b, `"}`...)
+			break
+		}
+		var err error
+		if b, err = json.Marshal(string(s)); err != nil {
+			return nil, err
+		}
+	case low.DIRTag:
+		b = append(b, '"')
+		b = r.DIR().AppendURI(b)
+		b = append(b, '"')
+	case low.VarUintTag:
+		b = quoteUint(b, uint64(r.VarUint()))
+	case low.VarIntTag:
+		b = quoteInt(b, int64(r.VarInt()))
+	case low.VarUint64Tag:
+		b = quoteUint(b, r.VarUint64())
+	case low.VarInt64Tag:
+		b = quoteInt(b, r.VarInt64())
+	case low.VarFloatTag:
+		b = appendFloat(b, math.Float64bits(r.VarFloat()), 64)
+	case low.DeltaInt64Tag, low.MonoInt64Tag, low.DeltaDeltaInt64Tag:
+		v, _ := r.Value(t).([]int64)
+		b = append(b, '[')
+		for i, x := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = quoteInt(b, x)
+		}
+		b = append(b, ']')
+	case low.DeltaUint64Tag, low.MonoUint64Tag:
+		v, _ := r.Value(t).([]uint64)
+		b = append(b, '[')
+		for i, x := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = quoteUint(b, x)
+		}
+		b = append(b, ']')
+	default:
+		return nil, fmt.Errorf("%w: tag %d at offset %d", low.ErrInvalid, uint64(t), r.Offset())
+	}
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+	return b, nil
+}
+
+func quoteUint(b []byte, v uint64) []byte {
+	b = append(b, '"')
+	return append(strconv.AppendUint(b, v, 10), '"')
+}
+
+func quoteInt(b []byte, v int64) []byte {
+	b = append(b, '"')
+	return append(strconv.AppendInt(b, v, 10), '"')
+}
+
+// appendFloat appends the JSON representation of the float of the given
+// bit size whose bits are given.
+func appendFloat(b []byte, bits uint64, size int) []byte {
+	f := math.Float64frombits(bits)
+	nan := nan64
+	if size == 32 {
+		f = float64(math.Float32frombits(uint32(bits)))
+		nan = nan32
+	}
+	switch {
+	case math.IsNaN(f) && bits == nan:
+		return append(b, `"NaN"`...)
+	case math.IsNaN(f):
+		return fmt.Appendf(b, `"NaN(0x%x)"`, bits)
+	case math.IsInf(f, 1):
+		return append(b, `"+Inf"`...)
+	case math.IsInf(f, -1):
+		return append(b, `"-Inf"`...)
+	}
+	return strconv.AppendFloat(b, f, 'g', -1, size)
+}
+
+// appendTime appends the JSON representation of the time t. The years
+// of the representation are limited to 0 to 9999.
+func appendTime(b []byte, t time.Time) ([]byte, error) {
+	if t.Year() < 0 || t.Year() > 9999 {
+		return nil, fmt.Errorf("time year %d out of range", t.Year())
+	}
+	b = append(b, '"')
+	b = t.AppendFormat(b, timeLayout)
+	if t.Location() == time.UTC {
+		return append(b, `Z"`...), nil
+	}
+	_, off := t.Zone()
+	sign := byte('+')
+	if off < 0 {
+		sign, off = '-', -off
+	}
+	b = fmt.Appendf(b, "%c%02d:%02d", sign, off/3600, off/60%60)
+	if off%60 != 0 {
+		b = fmt.Appendf(b, ":%02d", off%60)
+	}
+	return append(b, '"'), nil
+}
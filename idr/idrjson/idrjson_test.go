@@ -0,0 +1,187 @@
+package idrjson
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+	"github.com/chmike/ditp/idr/low"
+)
+
+func TestToJSON(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*3600)
+	tests := []struct {
+		t   low.TagT
+		v   any
+		exp string
+	}{
+		// 0
+		{low.NoneTag, nil, `{"type":"none"}`},
+		{low.BoolTag, true, `{"type":"bool","value":true}`},
+		{low.Uint16Tag, uint16(300), `{"type":"uint16","value":300}`},
+		{low.Uint64Tag, uint64(math.MaxUint64), `{"type":"uint64","value":"18446744073709551615"}`},
+		{low.VarUintTag, uint(7), `{"type":"varuint","value":"7"}`},
+		// 5
+		{low.Int8Tag, int8(-3), `{"type":"int8","value":-3}`},
+		{low.VarInt64Tag, int64(math.MinInt64), `{"type":"varint64","value":"-9223372036854775808"}`},
+		{low.Float32Tag, float32(0.1), `{"type":"float32","value":0.1}`},
+		{low.Float64Tag, 0.1, `{"type":"float64","value":0.1}`},
+		{low.VarFloatTag, math.Inf(-1), `{"type":"varfloat","value":"-Inf"}`},
+		// 10
+		{low.Float64Tag, math.NaN(), `{"type":"float64","value":"NaN"}`},
+		{low.Float64Tag, math.Float64frombits(0x7ff8000000000002), `{"type":"float64","value":"NaN(0x7ff8000000000002)"}`},
+		{low.Complex64Tag, complex64(1 - 2i), `{"type":"complex64","value":[1,-2]}`},
+		{low.TimeTag, time.Date(2024, 5, 1, 12, 30, 0, 500, time.UTC), `{"type":"time","value":"2024-05-01T12:30:00.0000005Z"}`},
+		{low.VarTimeTag, time.Date(2024, 5, 1, 12, 30, 0, 0, paris), `{"type":"vartime","value":"2024-05-01T12:30:00+02:00"}`},
+		// 15
+		{low.VarTimeTag, time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("", 0)), `{"type":"vartime","value":"2024-05-01T12:30:00+00:00"}`},
+		{low.VarTimeTag, time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("", -3725)), `{"type":"vartime","value":"2024-05-01T12:30:00-01:02:05"}`},
+		{low.BlobTag, []byte{1, 2, 3}, `{"type":"blob","value":"AQID"}`},
+		{low.StringTag, "héllo", `{"type":"string","value":"héllo"}`},
+		{low.StringTag, "\xff", `{"type":"string","value":{"base64":"/w=="}}`},
+		// 20
+		{low.DIRTag, dir.MustParse("dir:1.2"), `{"type":"dir","value":"dis:1.2/"}`},
+		{low.SizeTag, uint64(5), `{"type":"size","value":"5"}`},
+		{low.ArrayTag, low.ArrayValue{Tag: low.VarIntTag, Elems: []any{1, -2}}, `{"type":"array","elem":"varint","items":["1","-2"]}`},
+		{low.ArrayTag, low.ArrayValue{Tag: low.StringTag}, `{"type":"array","elem":"string"}`},
+		{low.ListTag, low.ListValue{{Tag: low.BoolTag, Value: false}, {Tag: low.NoneTag}}, `{"type":"list","items":[{"type":"bool","value":false},{"type":"none"}]}`},
+		// 25
+		{low.MapTag, low.MapValue{KeyTag: low.StringTag, ValueTag: low.Uint8Tag, Keys: []any{"a"}, Values: []any{uint8(1)}}, `{"type":"map","key":"string","elem":"uint8","entries":[["a",1]]}`},
+		{low.RecordTag, low.RecordValue{{Num: 1, Tag: low.StringTag, Value: "Bob"}, {Num: 2, Tag: low.ArrayTag, Value: low.ArrayValue{Tag: low.ArrayTag, Elems: []any{low.ArrayValue{Tag: low.BoolTag, Elems: []any{true}}}}}}, `{"type":"record","fields":[{"num":1,"type":"string","value":"Bob"},{"num":2,"type":"array","elem":"array","items":[{"elem":"bool","items":[true]}]}]}`},
+		{low.DeltaInt64Tag, []int64{5, -3, math.MaxInt64}, `{"type":"deltaint64","value":["5","-3","9223372036854775807"]}`},
+		{low.MonoUint64Tag, []uint64{1, 2, math.MaxUint64}, `{"type":"monouint64","value":["1","2","18446744073709551615"]}`},
+		{low.DeltaDeltaInt64Tag, []int64{}, `{"type":"deltadeltaint64","value":[]}`},
+	}
+	for i, test := range tests {
+		b := low.AppendValue(nil, test.t, test.v)
+		j, err := ToJSON(b)
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		if string(j) != test.exp {
+			t.Errorf("%d expect %s, got %s", i, test.exp, j)
+		}
+		b2, err := FromJSON(j)
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(b, b2) {
+			t.Errorf("%d expect %x, got %x", i, b, b2)
+		}
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		j   string
+		exp []byte
+	}{
+		// 0
+		{`{"type":"uint64","value":5}`, low.AppendValue(nil, low.Uint64Tag, uint64(5))},
+		{`{"type":"int16","value":"-5"}`, low.AppendValue(nil, low.Int16Tag, int16(-5))},
+		{` { "value" : 1.5e3, "type" : "float32" } `, low.AppendValue(nil, low.Float32Tag, float32(1500))},
+		{`{"type":"none","value":null}`, low.AppendValue(nil, low.NoneTag, nil)},
+		{`{"type":"time","value":"2024-05-01T14:30:00+02:00"}`, low.AppendValue(nil, low.TimeTag, time.Date(2024, 5, 1, 14, 30, 0, 0, time.FixedZone("", 7200)))},
+		// 5
+		{`{"type":"list","items":[]}`, low.AppendValue(nil, low.ListTag, low.ListValue{})},
+		{`{"type":"record"}`, low.AppendValue(nil, low.RecordTag, low.RecordValue{})},
+	}
+	for i, test := range tests {
+		b, err := FromJSON([]byte(test.j))
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(b, test.exp) {
+			t.Errorf("%d expect %x, got %x", i, test.exp, b)
+		}
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []string{
+		// 0
+		`{"type":"bytes","value":1}`,
+		`{"type":"foo"}`,
+		`{"type":"uint8","value":256}`,
+		`{"type":"uint8"}`,
+		`{"type":"int8","value":1.5}`,
+		// 5
+		`{"type":"bool","value":1}`,
+		`{"type":"float32","value":"Inf"}`,
+		`{"type":"float64","value":"NaN(0x1)"}`,
+		`{"type":"complex128","value":[1]}`,
+		`{"type":"time","value":"2024-05-01T14:30:00"}`,
+		// 10
+		`{"type":"time","value":"2024-05-01T14:30:00+2:00"}`,
+		`{"type":"blob","value":"!"}`,
+		`{"type":"string","value":{"hex":"ff"}}`,
+		`{"type":"dir","value":"dir:1.2"}`,
+		`{"type":"none","value":1}`,
+		// 15
+		`{"type":"bool","value":true,"items":[]}`,
+		`{"type":"bool","value":true,"extra":1}`,
+		`{"type":"bool","value":true} {}`,
+		`{"num":1,"type":"bool","value":true}`,
+		`{"type":"array","elem":"bytes"}`,
+		// 20
+		`{"type":"array","elem":"array","items":[{"type":"array","elem":"bool"}]}`,
+		`{"type":"list","items":[{"num":1,"type":"none"}]}`,
+		`{"type":"map","key":"string","elem":"bool","entries":[["a"]]}`,
+		`{"type":"record","fields":[{"type":"none"}]}`,
+		`[]`,
+		// 25
+		`{"type":"array","elem":"bool","key":"x","value":1}`,
+		`{"type":"array","elem":"bool","value":[true]}`,
+		`{"type":"array","elem":"bool","entries":[]}`,
+		`{"type":"list","elem":"bool","items":[]}`,
+		`{"type":"list","key":"bool"}`,
+		// 30
+		`{"type":"map","key":"bool","elem":"bool","items":[]}`,
+		`{"type":"map","key":"bool","elem":"bool","fields":[]}`,
+		`{"type":"record","key":"string","fields":[]}`,
+		`{"type":"record","value":1}`,
+		`{"type":"list","items":[{"type":"record","items":[]}]}`,
+		// 35
+		`{"type":"map","key":"string","elem":"array","entries":[["a",{"elem":"bool","key":"x"}]]}`,
+	}
+	for i, test := range tests {
+		_, err := FromJSON([]byte(test))
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%d expect ErrInvalid, got %v", i, err)
+		}
+	}
+}
+
+func TestToJSONErrors(t *testing.T) {
+	tests := []struct {
+		b   []byte
+		err error
+	}{
+		// 0
+		{[]byte{byte(low.BytesTag)}, low.ErrInvalid},
+		{[]byte{byte(low.MaxTag)}, low.ErrInvalid},
+		{[]byte{byte(low.BoolTag), 1, 0}, low.ErrInvalid},
+		{[]byte{byte(low.Uint16Tag), 1}, nil},
+		{[]byte{byte(low.ArrayTag), 2, byte(low.BytesTag), 0}, low.ErrInvalid},
+		// 5
+		{[]byte{byte(low.RecordTag), 3, 1, byte(low.Uint16Tag), 0}, nil},
+		{low.AppendValue(nil, low.TimeTag, time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)), low.ErrInvalid},
+		{append([]byte{byte(low.ArrayTag), 10, byte(low.NoneTag)}, low.AppendVarUint64(nil, 1<<60)...), low.ErrAllocLimit},
+	}
+	for i, test := range tests {
+		_, err := ToJSON(test.b)
+		var de *low.DecodeError
+		switch {
+		case test.err == nil && !errors.As(err, &de):
+			t.Errorf("%d expect DecodeError, got %v", i, err)
+		case test.err != nil && !errors.Is(err, test.err):
+			t.Errorf("%d expect %v, got %v", i, test.err, err)
+		}
+	}
+}
@@ -113,3 +113,129 @@ func BenchmarkDecode(b *testing.B) {
 // 		d = decodeEx(d, &a2)
 // 	}
 // }
+
+var s string
+
+// encodeStrings returns the encoding of 1000 strings picked among n
+// distinct strings of the given byte size.
+func encodeStrings(n, size int) Encoder {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = randString(size)
+	}
+	var e Encoder
+	for i := 0; i < 1000; i++ {
+		e = AppendString(e, values[rand.Intn(n)])
+	}
+	return e
+}
+
+func BenchmarkString(b *testing.B) {
+	data := encodeStrings(50, 16)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for d = Decoder(data); len(d) > 0; {
+			d, s = String(d, 255)
+		}
+	}
+}
+
+func BenchmarkUnsafeString(b *testing.B) {
+	data := encodeStrings(50, 16)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for d = Decoder(data); len(d) > 0; {
+			d, s = UnsafeString(d, 255)
+		}
+	}
+}
+
+func BenchmarkInternString(b *testing.B) {
+	data := encodeStrings(50, 16)
+	in := NewInterner(0, 0)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for d = Decoder(data); len(d) > 0; {
+			d, s = InternString(d, 255, in)
+		}
+	}
+}
+
+var floats = func() []float32 {
+	v := make([]float32, 1024)
+	for i := range v {
+		v[i] = rand.Float32()
+	}
+	return v
+}()
+
+func BenchmarkAppendPackedArray(b *testing.B) {
+	e = make(Encoder, 0, SizePackedArray(floats))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		e = AppendPackedArray(e[:0], floats)
+	}
+}
+
+func BenchmarkAppendArrayLoop(b *testing.B) {
+	e = make(Encoder, 0, SizePackedArray(floats))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var p int
+		e, p = BeginArray(e[:0], Float32Tag, len(floats))
+		for _, v := range floats {
+			e = AppendFloat32(e, v)
+		}
+		e = EndComposite(e, p)
+	}
+}
+
+var x []float32
+
+func BenchmarkPackedArray(b *testing.B) {
+	data := AppendPackedArray(nil, floats)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		d, x = PackedArray[float32](Decoder(data))
+	}
+}
+
+func BenchmarkArrayLoop(b *testing.B) {
+	data := AppendPackedArray(nil, floats)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var c Decoder
+		var m uint64
+		d, c, _, m = Array(Decoder(data))
+		x = make([]float32, m)
+		for i := range x {
+			c, x[i] = Float32(c)
+		}
+	}
+}
+
+var stamps = func() []int64 {
+	v := make([]int64, 1000)
+	for i := range v {
+		v[i] = 1_700_000_000_000 + int64(i)*1000 + int64(i%3)
+	}
+	return v
+}()
+
+var y []int64
+
+func BenchmarkAppendDeltaDeltaSeq(b *testing.B) {
+	e = make(Encoder, 0, SizeDeltaDeltaSeq(stamps))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		e = AppendDeltaDeltaSeq(e[:0], stamps)
+	}
+}
+
+func BenchmarkDeltaDeltaSeq(b *testing.B) {
+	data := AppendDeltaDeltaSeq(nil, stamps)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		d, y = DeltaDeltaSeq(Decoder(data))
+	}
+}
@@ -0,0 +1,384 @@
+package low
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"slices"
+	"time"
+)
+
+// ErrNotCanonical is the error returned when an encoding is valid but not
+// canonical.
+var ErrNotCanonical = errors.New("non canonical encoding")
+
+// canonical NaN bits
+var (
+	canonicalNaN32 = math.Float32bits(float32(math.NaN()))
+	canonicalNaN64 = math.Float64bits(math.NaN())
+)
+
+// IsCanonical returns true if b is the canonical encoding of a tagged
+// value.
+func IsCanonical(b []byte) bool {
+	return CheckCanonical(b) == nil
+}
+
+// CheckCanonical returns nil if b is the canonical encoding of a tagged
+// value. Otherwise it returns a DecodeError with the offset of the first
+// non canonical or invalid value. The error is ErrNotCanonical when the
+// encoding is valid but not canonical.
+//
+// The canonical encoding of a tagged value is unique so that the hash or
+// signature of a value is reproducible. The tag of a value is its type:
+// a number encoded as Uint64 or as VarUint64 is two different values,
+// each with its canonical encoding. An encoding is canonical when
+//
+//   - the VarUint encodings, including tags, sizes, counts and field
+//     numbers, have the minimal length, as for DIRs,
+//   - bools are 0 or 1,
+//   - NaN floats have the bits of math.NaN,
+//   - the nanoseconds of times are less than 1e9,
+//   - the zone offset of VarTime is omitted when 0,
+//   - map entries are sorted by the bytewise order of the key encodings,
+//     without duplicate keys,
+//   - record fields are sorted by increasing field number, without
+//     duplicate numbers,
+//   - the content of composite values has no trailing bytes.
+//
+// The encoders produce minimal VarUint encodings. Map entries, record
+// fields, NaNs and VarTime zone offsets are as given by the caller.
+func CheckCanonical(b []byte) error {
+	r := NewReader(b)
+	r.canonicalValue(r.canonicalTag())
+	if r.err == nil && r.Len() != 0 {
+		r.fail("Value", r.off, ErrInvalid)
+	}
+	return r.err
+}
+
+// canonicalVarUint64 returns the next VarUint64 and checks that its
+// encoding has the minimal length.
+func (r *Reader) canonicalVarUint64(op string) uint64 {
+	start := r.off
+	v := r.varUint64(op)
+	if r.err == nil && r.off-start > 1 && r.b[r.off-1] == 0 {
+		r.fail(op, start, ErrNotCanonical)
+	}
+	return v
+}
+
+// canonicalTag returns the next tag and checks that its encoding is
+// canonical.
+func (r *Reader) canonicalTag() TagT {
+	return TagT(r.canonicalVarUint64("Tag"))
+}
+
+// canonicalFloat checks that the float of the given bits is not a non
+// canonical NaN.
+func (r *Reader) canonicalFloat(op string, off int, bits uint64, size int) {
+	nan := math.IsNaN(math.Float64frombits(bits)) && bits != canonicalNaN64
+	if size == 32 {
+		f := math.Float32frombits(uint32(bits))
+		nan = f != f && uint32(bits) != canonicalNaN32
+	}
+	if nan {
+		r.fail(op, off, ErrNotCanonical)
+	}
+}
+
+// canonicalContent returns a Reader of the content of the next composite
+// value and checks that its size encoding is canonical.
+func (r *Reader) canonicalContent(op string) Reader {
+	start := r.off
+	n := r.canonicalVarUint64(op)
+	if r.err != nil {
+		return Reader{b: r.b, off: r.off, err: r.err}
+	}
+	if n > uint64(r.Len()) {
+		r.fail(op, start, io.ErrUnexpectedEOF)
+		return Reader{b: r.b, off: r.off, err: r.err}
+	}
+	c := r.sub(op, start, int(n))
+	if c.err != nil {
+		c = Reader{b: r.b, off: r.off, err: r.err}
+	}
+	return c
+}
+
+// end checks that the content of the composite value decoded by c has
+// been consumed and reports the error of c to r.
+func (r *Reader) end(op string, c *Reader) {
+	if c.err == nil && c.Len() != 0 {
+		c.fail(op, c.off, ErrNotCanonical)
+	}
+	r.adopt(c)
+}
+
+// canonicalValue checks that the value encoded as specified by the tag t
+// is canonical.
+func (r *Reader) canonicalValue(t TagT) {
+	if r.err != nil {
+		return
+	}
+	start := r.off
+	switch t {
+	case NoneTag:
+	case BoolTag:
+		if b := r.next("Bool", 1); b != nil && b[0] > 1 {
+			r.fail("Bool", start, ErrNotCanonical)
+		}
+	case ByteTag, Uint8Tag, Int8Tag:
+		r.next(t.op(), 1)
+	case Uint16Tag, Int16Tag:
+		r.next(t.op(), 2)
+	case Uint32Tag, Int32Tag:
+		r.next(t.op(), 4)
+	case Uint64Tag, Int64Tag:
+		r.next(t.op(), 8)
+	case Float32Tag:
+		if b := r.next("Float32", 4); b != nil {
+			r.canonicalFloat("Float32", start, uint64(binary.LittleEndian.Uint32(b)), 32)
+		}
+	case Float64Tag:
+		if b := r.next("Float64", 8); b != nil {
+			r.canonicalFloat("Float64", start, binary.LittleEndian.Uint64(b), 64)
+		}
+	case Complex64Tag:
+		if b := r.next("Complex64", 8); b != nil {
+			r.canonicalFloat("Complex64", start, uint64(binary.LittleEndian.Uint32(b)), 32)
+			r.canonicalFloat("Complex64", start, uint64(binary.LittleEndian.Uint32(b[4:])), 32)
+		}
+	case Complex128Tag:
+		if b := r.next("Complex128", 16); b != nil {
+			r.canonicalFloat("Complex128", start, binary.LittleEndian.Uint64(b), 64)
+			r.canonicalFloat("Complex128", start, binary.LittleEndian.Uint64(b[8:]), 64)
+		}
+	case TimeTag:
+		if b := r.next("Time", 16); b != nil && binary.LittleEndian.Uint32(b[8:]) >= 1e9 {
+			r.fail("Time", start, ErrNotCanonical)
+		}
+	case SizeTag, VarUintTag, VarIntTag, VarUint64Tag, VarInt64Tag:
+		r.canonicalVarUint64(t.op())
+	case VarFloatTag:
+		v := r.canonicalVarUint64("VarFloat")
+		r.canonicalFloat("VarFloat", start, bits.ReverseBytes64(v), 64)
+	case VarComplexTag:
+		v := r.canonicalVarUint64("VarComplex")
+		r.canonicalFloat("VarComplex", start, bits.ReverseBytes64(v), 64)
+		v = r.canonicalVarUint64("VarComplex")
+		r.canonicalFloat("VarComplex", start, bits.ReverseBytes64(v), 64)
+	case BlobTag, StringTag:
+		n := r.canonicalVarUint64(t.op())
+		if n > uint64(r.Len()) {
+			r.fail(t.op(), start, io.ErrUnexpectedEOF)
+			return
+		}
+		r.next(t.op(), int(n))
+	case DIRTag:
+		r.canonicalVarUint64("DIR")
+		r.off = start
+		r.DIR()
+	case VarTimeTag:
+		c := r.canonicalContent("VarTime")
+		c.canonicalVarUint64("VarTime")
+		if nano := c.canonicalVarUint64("VarTime"); nano >= 1e9 {
+			c.fail("VarTime", start, ErrNotCanonical)
+		}
+		if c.err == nil && c.Len() != 0 {
+			if c.canonicalVarUint64("VarTime") == 0 {
+				c.fail("VarTime", start, ErrNotCanonical)
+			}
+		}
+		r.end("VarTime", &c)
+	case ArrayTag:
+		c := r.canonicalContent("Array")
+		et := c.canonicalTag()
+		n := c.canonicalVarUint64("Array")
+		if et != NoneTag {
+			for i := uint64(0); i < n && c.err == nil; i++ {
+				c.canonicalValue(et)
+			}
+		}
+		r.end("Array", &c)
+	case ListTag:
+		c := r.canonicalContent("List")
+		n := c.canonicalVarUint64("List")
+		for i := uint64(0); i < n && c.err == nil; i++ {
+			c.canonicalValue(c.canonicalTag())
+		}
+		r.end("List", &c)
+	case MapTag:
+		c := r.canonicalContent("Map")
+		kt := c.canonicalTag()
+		vt := c.canonicalTag()
+		n := c.canonicalVarUint64("Map")
+		if kt == NoneTag && vt == NoneTag {
+			if n > 1 {
+				c.fail("Map", start, ErrNotCanonical)
+			}
+			n = 0
+		}
+		var prev []byte
+		for i := uint64(0); i < n && c.err == nil; i++ {
+			off := c.off
+			c.canonicalValue(kt)
+			key := c.b[off:c.off]
+			if i > 0 && c.err == nil && bytes.Compare(prev, key) >= 0 {
+				c.fail("Map", off, ErrNotCanonical)
+			}
+			prev = key
+			c.canonicalValue(vt)
+		}
+		r.end("Map", &c)
+	case RecordTag:
+		c := r.canonicalContent("Record")
+		var prev uint64
+		for i := 0; c.Len() > 0 && c.err == nil; i++ {
+			off := c.off
+			num := c.canonicalVarUint64("Field")
+			if i > 0 && c.err == nil && num <= prev {
+				c.fail("Field", off, ErrNotCanonical)
+			}
+			prev = num
+			c.canonicalValue(c.canonicalTag())
+		}
+		r.end("Record", &c)
+	case DeltaInt64Tag, DeltaUint64Tag, MonoInt64Tag, MonoUint64Tag, DeltaDeltaInt64Tag:
+		c := r.canonicalContent(t.op())
+		n := c.canonicalVarUint64(t.op())
+		for i := uint64(0); i < n && c.err == nil; i++ {
+			c.canonicalVarUint64(t.op())
+		}
+		r.end(t.op(), &c)
+	default:
+		r.fail("Value", start, ErrInvalid)
+	}
+}
+
+// op returns the name of the type of the tag t used in errors.
+func (t TagT) op() string {
+	return t.String()[:len(t.String())-len("Tag")]
+}
+
+// Canonical returns the canonical encoding of the tagged value b. It
+// fails with ErrInvalid when a map has duplicate keys or a record has
+// duplicate field numbers.
+func Canonical(b []byte) ([]byte, error) {
+	r := NewReader(b)
+	t := r.Tag()
+	v := r.Value(t)
+	if r.err == nil && r.Len() != 0 {
+		r.fail("Value", r.off, ErrInvalid)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	v, err := canonical(t, v)
+	if err != nil {
+		return nil, err
+	}
+	return AppendValue(make(Encoder, 0, len(b)), t, v), nil
+}
+
+// canonical returns the canonical form of the value v of type t as
+// returned by Value.
+func canonical(t TagT, v any) (any, error) {
+	var err error
+	switch t {
+	case Float32Tag:
+		if x := v.(float32); x != x {
+			return math.Float32frombits(canonicalNaN32), nil
+		}
+	case Float64Tag, VarFloatTag:
+		return canonicalFloat64(v.(float64)), nil
+	case Complex64Tag:
+		x := v.(complex64)
+		re, _ := canonical(Float32Tag, real(x))
+		im, _ := canonical(Float32Tag, imag(x))
+		return complex(re.(float32), im.(float32)), nil
+	case Complex128Tag, VarComplexTag:
+		x := v.(complex128)
+		return complex(canonicalFloat64(real(x)), canonicalFloat64(imag(x))), nil
+	case VarTimeTag:
+		x := v.(time.Time)
+		if _, offset := x.Zone(); offset == 0 {
+			return x.UTC(), nil
+		}
+	case ArrayTag:
+		x := v.(ArrayValue)
+		for i := range x.Elems {
+			if x.Elems[i], err = canonical(x.Tag, x.Elems[i]); err != nil {
+				return nil, err
+			}
+		}
+	case ListTag:
+		x := v.(ListValue)
+		for i := range x {
+			if x[i].Value, err = canonical(x[i].Tag, x[i].Value); err != nil {
+				return nil, err
+			}
+		}
+	case MapTag:
+		return canonicalMap(v.(MapValue))
+	case RecordTag:
+		x := v.(RecordValue)
+		for i := range x {
+			if x[i].Value, err = canonical(x[i].Tag, x[i].Value); err != nil {
+				return nil, err
+			}
+		}
+		slices.SortStableFunc(x, func(a, b FieldValue) int {
+			return cmp.Compare(a.Num, b.Num)
+		})
+		for i := 1; i < len(x); i++ {
+			if x[i].Num == x[i-1].Num {
+				return nil, fmt.Errorf("%w: duplicate record field number %d", ErrInvalid, x[i].Num)
+			}
+		}
+	}
+	return v, nil
+}
+
+func canonicalFloat64(v float64) float64 {
+	if v != v {
+		return math.Float64frombits(canonicalNaN64)
+	}
+	return v
+}
+
+// canonicalMap returns the map x with its canonical keys and values, and
+// its entries sorted by the bytewise order of the key encodings.
+func canonicalMap(x MapValue) (MapValue, error) {
+	type entry struct {
+		key  []byte
+		k, v any
+	}
+	entries := make([]entry, len(x.Keys))
+	var err error
+	for i := range x.Keys {
+		e := &entries[i]
+		if e.k, err = canonical(x.KeyTag, x.Keys[i]); err != nil {
+			return x, err
+		}
+		if e.v, err = canonical(x.ValueTag, x.Values[i]); err != nil {
+			return x, err
+		}
+		e.key = appendValue(nil, x.KeyTag, e.k)
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return bytes.Compare(a.key, b.key)
+	})
+	for i := range entries {
+		if i > 0 && bytes.Equal(entries[i].key, entries[i-1].key) {
+			return x, fmt.Errorf("%w: duplicate map key %v", ErrInvalid, entries[i].k)
+		}
+		x.Keys[i], x.Values[i] = entries[i].k, entries[i].v
+	}
+	return x, nil
+}
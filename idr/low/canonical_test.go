@@ -0,0 +1,127 @@
+package low
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+)
+
+func TestCheckCanonical(t *testing.T) {
+	utc := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		b   []byte
+		err error
+		off int
+	}{
+		// 0
+		{b: AppendValue(nil, BoolTag, true)},
+		{b: AppendValue(nil, VarUint64Tag, uint64(math.MaxUint64))},
+		{b: AppendValue(nil, Float64Tag, math.NaN())},
+		{b: AppendValue(nil, VarTimeTag, utc)},
+		{b: AppendValue(nil, VarTimeTag, utc.In(time.FixedZone("", 3600)))},
+		// 5
+		{b: AppendValue(nil, DIRTag, dir.MustParse("dir:1.300"))},
+		{b: AppendValue(nil, MapTag, MapValue{KeyTag: StringTag, ValueTag: BoolTag, Keys: []any{"a", "b"}, Values: []any{true, false}})},
+		{b: AppendValue(nil, RecordTag, RecordValue{{Num: 1, Tag: NoneTag}, {Num: 200, Tag: ArrayTag, Value: ArrayValue{Tag: NoneTag, Elems: []any{nil, nil}}}})},
+		{b: []byte{byte(BoolTag), 2}, err: ErrNotCanonical, off: 1},
+		{b: []byte{byte(VarUintTag), 0x81, 0x00}, err: ErrNotCanonical, off: 1},
+		// 10
+		{b: []byte{0x81, 0x00, 1}, err: ErrNotCanonical, off: 0},
+		{b: AppendValue(nil, Float64Tag, math.Float64frombits(0x7ff8000000000002)), err: ErrNotCanonical, off: 1},
+		{b: AppendValue(nil, Float32Tag, math.Float32frombits(0x7fc00001)), err: ErrNotCanonical, off: 1},
+		{b: AppendValue(nil, VarComplexTag, complex(1, math.Float64frombits(0x7ff0000000000001))), err: ErrNotCanonical, off: 1},
+		{b: AppendValue(nil, VarTimeTag, utc.In(time.FixedZone("", 0))), err: ErrNotCanonical, off: 1},
+		// 15
+		{b: append([]byte{byte(VarTimeTag), byte(1 + SizeVarUint64(1e9)), 0}, AppendVarUint64(nil, 1e9)...), err: ErrNotCanonical, off: 1},
+		{b: append(AppendValue(nil, TimeTag, utc)[:9], 0, 0xCA, 0x9A, 0x3B, 0, 0, 0, 0), err: ErrNotCanonical, off: 1},
+		{b: []byte{byte(DIRTag), 0x82, 0x00, 1, 2}, err: ErrNotCanonical, off: 1},
+		{b: []byte{byte(StringTag), 0x81, 0x00, 'a'}, err: ErrNotCanonical, off: 1},
+		{b: AppendValue(nil, MapTag, MapValue{KeyTag: StringTag, ValueTag: BoolTag, Keys: []any{"b", "a"}, Values: []any{true, false}}), err: ErrNotCanonical, off: 8},
+		// 20
+		{b: AppendValue(nil, MapTag, MapValue{KeyTag: VarIntTag, ValueTag: NoneTag, Keys: []any{1, 1}, Values: []any{nil, nil}}), err: ErrNotCanonical, off: 6},
+		{b: AppendValue(nil, MapTag, MapValue{KeyTag: NoneTag, ValueTag: NoneTag, Keys: []any{nil, nil}, Values: []any{nil, nil}}), err: ErrNotCanonical, off: 1},
+		{b: AppendValue(nil, RecordTag, RecordValue{{Num: 2, Tag: NoneTag}, {Num: 1, Tag: NoneTag}}), err: ErrNotCanonical, off: 4},
+		{b: AppendValue(nil, RecordTag, RecordValue{{Num: 1, Tag: NoneTag}, {Num: 1, Tag: NoneTag}}), err: ErrNotCanonical, off: 4},
+		{b: []byte{byte(ListTag), 3, 1, byte(NoneTag), 0}, err: ErrNotCanonical, off: 4},
+		// 25
+		{b: []byte{byte(ListTag), 3, 1, byte(BoolTag), 2}, err: ErrNotCanonical, off: 4},
+		{b: []byte{byte(ArrayTag), 3, byte(Uint16Tag), 1, 0}, err: io.ErrUnexpectedEOF, off: 4},
+		{b: []byte{byte(BytesTag)}, err: ErrInvalid, off: 1},
+		{b: []byte{byte(BoolTag), 1, 0}, err: ErrInvalid, off: 2},
+	}
+	for i, test := range tests {
+		err := CheckCanonical(test.b)
+		if test.err == nil {
+			if err != nil || !IsCanonical(test.b) {
+				t.Errorf("%d unexpected error: %v", i, err)
+			}
+			continue
+		}
+		var de *DecodeError
+		if !errors.Is(err, test.err) || !errors.As(err, &de) || de.Offset != test.off {
+			t.Errorf("%d expect %v at offset %d, got %v", i, test.err, test.off, err)
+		}
+		if IsCanonical(test.b) {
+			t.Errorf("%d expect not canonical", i)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	utc := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	nan := math.Float64frombits(0x7ff8000000000002)
+	tests := []struct {
+		b, o []byte
+	}{
+		// 0
+		{b: []byte{byte(BoolTag), 2}, o: AppendValue(nil, BoolTag, true)},
+		{b: []byte{byte(VarUintTag), 0x81, 0x00}, o: AppendValue(nil, VarUintTag, uint(1))},
+		{b: AppendValue(nil, Float64Tag, nan), o: AppendValue(nil, Float64Tag, math.NaN())},
+		{b: AppendValue(nil, Complex64Tag, complex(float32(nan), 1)), o: AppendValue(nil, Complex64Tag, complex(float32(math.NaN()), 1))},
+		{b: AppendValue(nil, VarTimeTag, utc.In(time.FixedZone("", 0))), o: AppendValue(nil, VarTimeTag, utc)},
+		// 5
+		{
+			b: AppendValue(nil, MapTag, MapValue{KeyTag: StringTag, ValueTag: VarFloatTag, Keys: []any{"b", "a"}, Values: []any{nan, 1.0}}),
+			o: AppendValue(nil, MapTag, MapValue{KeyTag: StringTag, ValueTag: VarFloatTag, Keys: []any{"a", "b"}, Values: []any{1.0, math.NaN()}}),
+		},
+		{
+			b: AppendValue(nil, RecordTag, RecordValue{{Num: 300, Tag: BoolTag, Value: true}, {Num: 2, Tag: ListTag, Value: ListValue{{Tag: Float64Tag, Value: nan}}}}),
+			o: AppendValue(nil, RecordTag, RecordValue{{Num: 2, Tag: ListTag, Value: ListValue{{Tag: Float64Tag, Value: math.NaN()}}}, {Num: 300, Tag: BoolTag, Value: true}}),
+		},
+		{
+			b: AppendValue(nil, ArrayTag, ArrayValue{Tag: MapTag, Elems: []any{MapValue{KeyTag: VarIntTag, ValueTag: NoneTag, Keys: []any{1, -1}, Values: []any{nil, nil}}}}),
+			o: AppendValue(nil, ArrayTag, ArrayValue{Tag: MapTag, Elems: []any{MapValue{KeyTag: VarIntTag, ValueTag: NoneTag, Keys: []any{-1, 1}, Values: []any{nil, nil}}}}),
+		},
+	}
+	for i, test := range tests {
+		o, err := Canonical(test.b)
+		if err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(o, test.o) {
+			t.Errorf("%d expect %x, got %x", i, test.o, o)
+		}
+		if err := CheckCanonical(o); err != nil {
+			t.Errorf("%d unexpected error: %v", i, err)
+		}
+	}
+
+	errTests := [][]byte{
+		// 0
+		AppendValue(nil, MapTag, MapValue{KeyTag: StringTag, ValueTag: NoneTag, Keys: []any{"a", "a"}, Values: []any{nil, nil}}),
+		AppendValue(nil, RecordTag, RecordValue{{Num: 1, Tag: NoneTag}, {Num: 1, Tag: BoolTag, Value: true}}),
+		{byte(BoolTag), 1, 0},
+		{byte(BytesTag)},
+	}
+	for i, b := range errTests {
+		if _, err := Canonical(b); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%d expect ErrInvalid, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package low
+
+import "io"
+
+// Composite values are prefixed with the byte size of their content so
+// that they can be skipped without decoding their elements. The content
+// of the composite values is encoded as follows:
+//
+//	Array:  elemTag count elem*          elements of the same type
+//	List:   count (tag value)*           elements of any type
+//	Map:    keyTag valueTag count (key value)*
+//	Record: (fieldNumber tag value)*     fields up to the end of the content
+//
+// The tags, counts and field numbers are encoded as VarUint.
+
+// BeginArray appends the header of an array of count elements encoded as
+// specified by the tag t. It returns the position to give to EndComposite
+// once the elements have been appended without their tag.
+func BeginArray(e Encoder, t TagT, count int) (Encoder, int) {
+	p := len(e)
+	e = AppendTag(e, t)
+	return AppendVarUint(e, uint(count)), p
+}
+
+// BeginList appends the header of a list of count elements. It returns
+// the position to give to EndComposite once the elements have been
+// appended with their tag.
+func BeginList(e Encoder, count int) (Encoder, int) {
+	p := len(e)
+	return AppendVarUint(e, uint(count)), p
+}
+
+// BeginMap appends the header of a map of count entries whose keys and
+// values are encoded as specified by the tags kt and vt. It returns the
+// position to give to EndComposite once the keys and values have been
+// appended in sequence without their tag.
+func BeginMap(e Encoder, kt, vt TagT, count int) (Encoder, int) {
+	p := len(e)
+	e = AppendTag(e, kt)
+	e = AppendTag(e, vt)
+	return AppendVarUint(e, uint(count)), p
+}
+
+// BeginRecord starts a record. It returns the position to give to
+// EndComposite once the fields have been appended with AppendField
+// followed by their value.
+func BeginRecord(e Encoder) (Encoder, int) {
+	return e, len(e)
+}
+
+// AppendField appends the field number n and the tag t of a record field.
+// The field value encoded as specified by t must follow.
+func AppendField(e Encoder, n uint64, t TagT) Encoder {
+	e = AppendVarUint64(e, n)
+	return AppendTag(e, t)
+}
+
+// EndComposite ends the composite value started at position p by inserting
+// the byte size of its content.
+func EndComposite(e Encoder, p int) Encoder {
+	n := uint64(len(e) - p)
+	l := SizeVarUint64(n)
+	e = append(e, make([]byte, l)...)
+	copy(e[p+l:], e[p:len(e)-l])
+	AppendVarUint64(e[:p], n)
+	return e
+}
+
+// content returns the remaining bytes after the composite value in front
+// of d, and its content.
+func content(d Decoder) (Decoder, Decoder) {
+	d, n := Size(d)
+	return d[n:], d[:n:n]
+}
+
+// Array returns the array in front of the remaining bytes. It returns
+// the bytes following the array, a decoder of the count elements, and
+// the tag of the elements.
+func Array(d Decoder) (Decoder, Decoder, TagT, uint64) {
+	d, c := content(d)
+	c, t := Tag(c)
+	c, n := VarUint64(c)
+	return d, c, t, n
+}
+
+// List returns the list in front of the remaining bytes. It returns the
+// bytes following the list and a decoder of the count tagged elements.
+func List(d Decoder) (Decoder, Decoder, uint64) {
+	d, c := content(d)
+	c, n := VarUint64(c)
+	return d, c, n
+}
+
+// Map returns the map in front of the remaining bytes. It returns the
+// bytes following the map, a decoder of the count entries, and the tags
+// of the keys and values.
+func Map(d Decoder) (Decoder, Decoder, TagT, TagT, uint64) {
+	d, c := content(d)
+	c, kt := Tag(c)
+	c, vt := Tag(c)
+	c, n := VarUint64(c)
+	return d, c, kt, vt, n
+}
+
+// Record returns the record in front of the remaining bytes. It returns
+// the bytes following the record and a decoder of its fields. The fields
+// are decoded with Field followed by their value until the decoder is
+// empty.
+func Record(d Decoder) (Decoder, Decoder) {
+	return content(d)
+}
+
+// Field returns the field number and tag in front of the remaining bytes.
+// The value of the field follows.
+func Field(d Decoder) (Decoder, uint64, TagT) {
+	d, n := VarUint64(d)
+	d, t := Tag(d)
+	return d, n, t
+}
+
+// SkipArray skips an array value.
+func SkipArray(d Decoder) Decoder {
+	d, _ = content(d)
+	return d
+}
+
+// SkipList skips a list value.
+func SkipList(d Decoder) Decoder {
+	d, _ = content(d)
+	return d
+}
+
+// SkipMap skips a map value.
+func SkipMap(d Decoder) Decoder {
+	d, _ = content(d)
+	return d
+}
+
+// SkipRecord skips a record value.
+func SkipRecord(d Decoder) Decoder {
+	d, _ = content(d)
+	return d
+}
+
+// content returns a Reader of the content of the next composite value.
+// The offsets of the returned Reader are relative to r.
+func (r *Reader) content(op string) Reader {
+	start := r.off
+	n := r.varUint64(op)
+	if r.err != nil {
+		return Reader{err: r.err}
+	}
+	if n > uint64(r.Len()) {
+		r.fail(op, start, io.ErrUnexpectedEOF)
+		return Reader{err: r.err}
+	}
+	return r.sub(op, start, int(n))
+}
+
+// sub returns a Reader of the next n bytes which are the content of the
+// composite value of type op at offset start. The content is nested one
+// level deeper than r.
+func (r *Reader) sub(op string, start, n int) Reader {
+	lim := r.budget()
+	if r.depth >= lim.MaxDepth {
+		r.fail(op, start, ErrTooDeep)
+		return Reader{err: r.err}
+	}
+	c := Reader{b: r.b[:r.off+n], off: r.off, depth: r.depth + 1, lim: lim}
+	r.off += n
+	return c
+}
+
+// count returns the next element count of a composite value with at least
+// min bytes per element. The count is invalid if the content is too small.
+// The count of elements without encoding may not exceed the MaxNoneCount
+// limit.
+func (r *Reader) count(op string, min int) uint64 {
+	start := r.off
+	n := r.varUint64(op)
+	switch {
+	case r.err != nil:
+	case min > 0 && n > uint64(r.Len()/min):
+		r.fail(op, start, ErrInvalid)
+		return 0
+	case min == 0 && n > r.budget().MaxNoneCount:
+		r.fail(op, start, ErrAllocLimit)
+		return 0
+	}
+	return n
+}
+
+// Array returns a Reader of the elements of the next array, the tag of
+// the elements and their count. The elements are decoded without their
+// tag with the returned Reader whose errors are not reported to r.
+func (r *Reader) Array() (Reader, TagT, uint64) {
+	c := r.content("Array")
+	t := c.Tag()
+	min := 1
+	if t == NoneTag {
+		min = 0
+	}
+	n := c.count("Array", min)
+	return c, t, n
+}
+
+// List returns a Reader of the tagged elements of the next list, and their
+// count. Errors of the returned Reader are not reported to r.
+func (r *Reader) List() (Reader, uint64) {
+	c := r.content("List")
+	return c, c.count("List", 1)
+}
+
+// Map returns a Reader of the entries of the next map, the tags of the
+// keys and values, and the number of entries. The keys and values are
+// decoded in sequence without their tag with the returned Reader whose
+// errors are not reported to r.
+func (r *Reader) Map() (Reader, TagT, TagT, uint64) {
+	c := r.content("Map")
+	kt := c.Tag()
+	vt := c.Tag()
+	min := 2
+	if kt == NoneTag || vt == NoneTag {
+		min = 1
+	}
+	if kt == NoneTag && vt == NoneTag {
+		min = 0
+	}
+	return c, kt, vt, c.count("Map", min)
+}
+
+// Record returns a Reader of the fields of the next record. The fields
+// are decoded with Field followed by their value until the Reader is
+// empty. Errors of the returned Reader are not reported to r.
+func (r *Reader) Record() Reader {
+	return r.content("Record")
+}
+
+// Field returns the next field number and tag. The value of the field
+// follows.
+func (r *Reader) Field() (uint64, TagT) {
+	n := r.varUint64("Field")
+	return n, r.Tag()
+}
+
+// SkipArray skips an array value.
+func (r *Reader) SkipArray() {
+	r.content("Array")
+}
+
+// SkipList skips a list value.
+func (r *Reader) SkipList() {
+	r.content("List")
+}
+
+// SkipMap skips a map value.
+func (r *Reader) SkipMap() {
+	r.content("Map")
+}
+
+// SkipRecord skips a record value.
+func (r *Reader) SkipRecord() {
+	r.content("Record")
+}
@@ -0,0 +1,268 @@
+package low
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/chmike/ditp/dir"
+)
+
+func TestComposite(t *testing.T) {
+	tests := []struct {
+		f func(e Encoder) Encoder
+		o []byte
+	}{
+		// 0
+		{
+			f: func(e Encoder) Encoder {
+				e, p := BeginArray(e, DIRTag, 2)
+				e = AppendDIR(e, dir.MustMake(1, 0))
+				e = AppendDIR(e, dir.MustMake(1, 2))
+				return EndComposite(e, p)
+			},
+			o: []byte{8, byte(DIRTag), 2, 2, 1, 0, 2, 1, 2},
+		},
+		{
+			f: func(e Encoder) Encoder {
+				e, p := BeginList(e, 2)
+				e = AppendValue(e, BoolTag, true)
+				e = AppendValue(e, StringTag, "a")
+				return EndComposite(e, p)
+			},
+			o: []byte{6, 2, byte(BoolTag), 1, byte(StringTag), 1, 'a'},
+		},
+		{
+			f: func(e Encoder) Encoder {
+				e, p := BeginMap(e, StringTag, Uint8Tag, 1)
+				e = AppendString(e, "a")
+				e = AppendUint8(e, 7)
+				return EndComposite(e, p)
+			},
+			o: []byte{6, byte(StringTag), byte(Uint8Tag), 1, 1, 'a', 7},
+		},
+		{
+			f: func(e Encoder) Encoder {
+				e, p := BeginRecord(e)
+				e = AppendField(e, 1, VarIntTag)
+				e = AppendVarInt(e, -1)
+				e = AppendField(e, 300, BoolTag)
+				e = AppendBool(e, true)
+				return EndComposite(e, p)
+			},
+			o: []byte{7, 1, byte(VarIntTag), 1, 0xAC, 0x02, byte(BoolTag), 1},
+		},
+		{
+			f: func(e Encoder) Encoder {
+				e, p := BeginArray(e, Uint8Tag, 200)
+				for i := 0; i < 200; i++ {
+					e = AppendUint8(e, byte(i))
+				}
+				return EndComposite(e, p)
+			},
+			o: func() []byte {
+				b := []byte{0xCB, 0x01, byte(Uint8Tag), 0xC8, 0x01}
+				for i := 0; i < 200; i++ {
+					b = append(b, byte(i))
+				}
+				return b
+			}(),
+		},
+	}
+	for i, test := range tests {
+		e := test.f(Encoder{0xFF})
+		if !bytes.Equal(e[1:], test.o) || e[0] != 0xFF {
+			t.Errorf("%d expect %#v, got %#v", i, test.o, e[1:])
+		}
+		d := append(Decoder(e[1:]), 0xEE)
+		if r := SkipValue(d, ArrayTag); len(r) != 1 || r[0] != 0xEE {
+			t.Errorf("%d expect 1 byte left after skip, got %#v", i, r)
+		}
+		r := NewReader(d)
+		r.SkipRecord()
+		if r.Err() != nil || r.Len() != 1 {
+			t.Errorf("%d expect 1 byte left after skip, got %d (%v)", i, r.Len(), r.Err())
+		}
+	}
+
+	// decoding
+	d := Decoder(tests[0].f(nil))
+	d, c, tag, n := Array(d)
+	if len(d) != 0 || tag != DIRTag || n != 2 {
+		t.Errorf("expect DIR array of 2 elements, got %v %d", tag, n)
+	}
+	c, v1 := DIR(c)
+	c, v2 := DIR(c)
+	if len(c) != 0 || v1 != dir.MustMake(1, 0) || v2 != dir.MustMake(1, 2) {
+		t.Errorf("unexpected array elements %v %v", v1, v2)
+	}
+
+	d = Decoder(tests[1].f(nil))
+	d, c, n = List(d)
+	if len(d) != 0 || n != 2 {
+		t.Errorf("expect list of 2 elements, got %d", n)
+	}
+	c, tag, v := Value(c)
+	if tag != BoolTag || v != true {
+		t.Errorf("expect true, got %v %v", tag, v)
+	}
+	c, tag, v = Value(c)
+	if len(c) != 0 || tag != StringTag || v != "a" {
+		t.Errorf("expect \"a\", got %v %v", tag, v)
+	}
+
+	d = Decoder(tests[2].f(nil))
+	d, c, kt, vt, n := Map(d)
+	if len(d) != 0 || kt != StringTag || vt != Uint8Tag || n != 1 {
+		t.Errorf("expect map of 1 entry, got %v %v %d", kt, vt, n)
+	}
+	c, k := String(c, 10)
+	c, x := Uint8(c)
+	if len(c) != 0 || k != "a" || x != 7 {
+		t.Errorf("expect a:7, got %s:%d", k, x)
+	}
+
+	d = Decoder(tests[3].f(nil))
+	d, c = Record(d)
+	c, fn, tag := Field(c)
+	c, i := VarInt(c)
+	if fn != 1 || tag != VarIntTag || i != -1 {
+		t.Errorf("expect field 1 -1, got %d %v %d", fn, tag, i)
+	}
+	c, fn, tag = Field(c)
+	c, b := Bool(c)
+	if len(d) != 0 || len(c) != 0 || fn != 300 || tag != BoolTag || !b {
+		t.Errorf("expect field 300 true, got %d %v %v", fn, tag, b)
+	}
+}
+
+func TestCompositeValue(t *testing.T) {
+	in := RecordValue{
+		{Num: 1, Tag: StringTag, Value: "node"},
+		{Num: 2, Tag: ArrayTag, Value: ArrayValue{Tag: DIRTag, Elems: []any{dir.MustMake(1, 0), dir.MustMake(1, 2)}}},
+		{Num: 3, Tag: MapTag, Value: MapValue{KeyTag: StringTag, ValueTag: ListTag,
+			Keys: []any{"a", "b"},
+			Values: []any{
+				ListValue{{Tag: BoolTag, Value: true}, {Tag: NoneTag}},
+				ListValue{{Tag: ArrayTag, Value: ArrayValue{Tag: NoneTag, Elems: []any{nil, nil}}}},
+			},
+		}},
+		{Num: 4, Tag: RecordTag, Value: RecordValue{}},
+		{Num: 5, Tag: ArrayTag, Value: ArrayValue{Tag: ArrayTag, Elems: []any{
+			ArrayValue{Tag: VarIntTag, Elems: []any{1, -2}},
+			ArrayValue{Tag: VarIntTag, Elems: []any{}},
+		}}},
+	}
+	e := AppendValue(nil, RecordTag, in)
+	d, tag, out := Value(Decoder(e))
+	if len(d) != 0 || tag != RecordTag {
+		t.Errorf("expect record, got %v with %d bytes left", tag, len(d))
+	}
+	in[3].Value = RecordValue(nil)
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("expect %#v, got %#v", in, out)
+	}
+	if d = SkipValue(Decoder(e[1:]), RecordTag); len(d) != 0 {
+		t.Errorf("expect no bytes left, got %d", len(d))
+	}
+	r := NewReader(e)
+	if v := r.Value(r.Tag()); r.Err() != nil || r.Len() != 0 || !reflect.DeepEqual(v, in) {
+		t.Errorf("expect %#v, got %#v (%v)", in, v, r.Err())
+	}
+	r = NewReader(e[:len(e)-1])
+	if r.Value(r.Tag()); !errors.Is(r.Err(), io.ErrUnexpectedEOF) {
+		t.Errorf("expect ErrUnexpectedEOF, got %v", r.Err())
+	}
+	r = NewReader(append(e[:len(e):len(e)], byte(ListTag), 2, 1, byte(MaxTag)))
+	r.Value(r.Tag())
+	if r.Value(r.Tag()); !errors.Is(r.Err(), ErrInvalid) {
+		t.Errorf("expect ErrInvalid, got %v", r.Err())
+	}
+	if !doesPanic(func() { AppendValue(nil, MapTag, MapValue{Keys: []any{nil}}) }) {
+		t.Error("expect panic with keys and values count mismatch")
+	}
+	huge := AppendVarUint64(nil, 1<<60)
+	for i, b := range [][]byte{
+		append([]byte{byte(ArrayTag), 10, byte(Uint8Tag)}, huge...),
+		append([]byte{byte(ArrayTag), 10, byte(NoneTag)}, huge...),
+		append([]byte{byte(ArrayTag), 5, byte(NoneTag)}, AppendVarUint64(nil, 100_000_000)...),
+		append([]byte{byte(MapTag), 4, byte(NoneTag), byte(NoneTag)}, AppendVarUint64(nil, DefaultMaxNoneCount+1)...),
+		append([]byte{byte(ListTag), 9}, huge...),
+		append([]byte{byte(MapTag), 11, byte(NoneTag), byte(BoolTag)}, huge...),
+		nested(DefaultMaxDepth + 1),
+	} {
+		if !doesPanic(func() { Value(Decoder(b)) }) {
+			t.Errorf("%d expect panic", i)
+		}
+	}
+	none := append([]byte{byte(ArrayTag), 3, byte(NoneTag)}, AppendVarUint64(nil, DefaultMaxNoneCount)...)
+	if _, _, v := Value(Decoder(none)); len(v.(ArrayValue).Elems) != DefaultMaxNoneCount {
+		t.Errorf("expect %d elements, got %d", DefaultMaxNoneCount, len(v.(ArrayValue).Elems))
+	}
+	if d, _, _ := Value(Decoder(nested(DefaultMaxDepth))); len(d) != 0 {
+		t.Errorf("expect no bytes left, got %d", len(d))
+	}
+}
+
+func TestReaderComposite(t *testing.T) {
+	e := AppendByte(nil, 0xFF)
+	e, p := BeginMap(e, StringTag, ArrayTag, 1)
+	e = AppendString(e, "k")
+	e, q := BeginArray(e, Uint16Tag, 2)
+	e = AppendUint16(e, 1)
+	e = AppendUint16(e, 2)
+	e = EndComposite(e, q)
+	e = EndComposite(e, p)
+	e, p = BeginRecord(e)
+	e = AppendField(e, 9, ListTag)
+	e, q = BeginList(e, 1)
+	e = AppendValue(e, StringTag, "v")
+	e = EndComposite(e, q)
+	e = EndComposite(e, p)
+
+	r := NewReader(e)
+	r.Byte()
+	m, kt, vt, n := r.Map()
+	if kt != StringTag || vt != ArrayTag || n != 1 {
+		t.Errorf("expect map of 1 entry, got %v %v %d", kt, vt, n)
+	}
+	if k := m.String(10); k != "k" {
+		t.Errorf("expect %q, got %q", "k", k)
+	}
+	a, at, an := m.Array()
+	if at != Uint16Tag || an != 2 || a.Uint16() != 1 || a.Uint16() != 2 || a.Len() != 0 || a.Err() != nil {
+		t.Errorf("expect array of 2 uint16, got %v %d (%v)", at, an, a.Err())
+	}
+	if m.Len() != 0 || m.Err() != nil {
+		t.Errorf("expect map fully decoded, got %d bytes left (%v)", m.Len(), m.Err())
+	}
+	rec := r.Record()
+	fn, ft := rec.Field()
+	l, ln := rec.List()
+	if fn != 9 || ft != ListTag || ln != 1 || l.Tag() != StringTag || l.String(10) != "v" || l.Len() != 0 {
+		t.Errorf("expect field 9 with list, got %d %v %d", fn, ft, ln)
+	}
+	if rec.Len() != 0 || r.Len() != 0 || r.Err() != nil {
+		t.Errorf("expect all decoded, got %d bytes left (%v)", r.Len(), r.Err())
+	}
+
+	// the element count must be consistent with the content size
+	r = NewReader([]byte{3, byte(Uint8Tag), 3, 1})
+	if _, _, n := r.Array(); n != 0 || r.Err() != nil {
+		t.Errorf("expect invalid array count, got %d (%v)", n, r.Err())
+	}
+	c, _, _ := NewReader([]byte{3, byte(Uint8Tag), 3, 1}).Array()
+	if !errors.Is(c.Err(), ErrInvalid) {
+		t.Errorf("expect ErrInvalid, got %v", c.Err())
+	}
+	r = NewReader([]byte{4, 1, byte(BoolTag)})
+	if r.SkipList(); !errors.Is(r.Err(), io.ErrUnexpectedEOF) {
+		t.Errorf("expect ErrUnexpectedEOF, got %v", r.Err())
+	}
+	r = NewReader([]byte{1, 0xFF})
+	if r.Record(); r.Err() != nil || r.Len() != 0 {
+		t.Errorf("expect record skipped, got %d bytes left (%v)", r.Len(), r.Err())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"math"
 	"math/bits"
 	"time"
+	"unsafe"
 
 	"github.com/chmike/ditp/dir"
 )
@@ -173,14 +174,36 @@ func Blob(d Decoder, max uint64) (Decoder, []byte) {
 // String returns a copy of the string in front of the remaining bytes.
 func String(d Decoder, max uint64) (Decoder, string) {
 	d, n := VarUint64(d)
+	if n > max {
+		panic("IDR decoder: data too big")
+	}
 	return d[n:], string(d[:n])
 }
 
+// UnsafeString returns the string in front of the remaining bytes without
+// making a copy. The string shares its bytes with d, so d must not be
+// modified while the string is in use.
+func UnsafeString(d Decoder, max uint64) (Decoder, string) {
+	d, n := VarUint64(d)
+	if n > max {
+		panic("IDR decoder: data too big")
+	}
+	return d[n:], unsafeString(d[:n])
+}
+
+// unsafeString returns the string sharing its bytes with b.
+func unsafeString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(&b[0], len(b))
+}
+
 // DIR returns the DIR in front of the remaining bytes and store it in b.
 func DIR(d Decoder) (Decoder, dir.DIR) {
 	l := int(d[0])
 	d = d[1:]
-	v, err := dir.DecodeBinary(d)
+	v, err := dir.DecodeBinary(d[:l])
 	if err != nil {
 		panic(err)
 	}
@@ -2,6 +2,7 @@ package low
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -196,12 +197,28 @@ func TestPanics(t *testing.T) {
 	if !doesPanic(func() { SkipBlob(d, 3) }) {
 		t.Error("expect SkipBlob panics")
 	}
+
+	d = Decoder([]byte{4, 'a', 'b', 'c', 'd'})
+	if !doesPanic(func() { String(d, 3) }) {
+		t.Error("expect String panics")
+	}
 	d = Decoder([]byte{0x11, 0xa0, 0xf4, 0x81, 0xd2, 0xc, 0x0, 0x0, 0x0, 0x0, 0xdf, 0x89, 0x3, 0x3, 0x4d, 0x44, 0x54, 0x00})
 	if !doesPanic(func() { VarTime(d) }) {
 		t.Error("expect VarTime panics")
 	}
 }
 
+func TestDIRFollowedByData(t *testing.T) {
+	d := Decoder([]byte{2, 1, 0, 0xFF})
+	d, v := DIR(d)
+	if v != dir.MustMake(1, 0) {
+		t.Errorf("expect %v, got %v", dir.MustMake(1, 0), v)
+	}
+	if len(d) != 1 || d[0] != 0xFF {
+		t.Errorf("expect %#v, got %#v", []byte{0xFF}, d)
+	}
+}
+
 func TestSkip(t *testing.T) {
 	tests := []struct {
 		t TagT
@@ -334,3 +351,29 @@ func TestSkip(t *testing.T) {
 		}
 	}
 }
+
+func TestUnsafeString(t *testing.T) {
+	e := AppendString(nil, "hello")
+	e = AppendString(e, "")
+	d, s := UnsafeString(Decoder(e), 10)
+	d, s2 := UnsafeString(d, 10)
+	if s != "hello" || s2 != "" || len(d) != 0 {
+		t.Errorf("expect %q and %q, got %q and %q", "hello", "", s, s2)
+	}
+	r := NewReader(e)
+	if v := r.UnsafeString(10); v != "hello" || r.Err() != nil {
+		t.Errorf("expect %q, got %q (%v)", "hello", v, r.Err())
+	}
+	// the string shares its bytes with the decoded data
+	e[1] = 'j'
+	if s != "jello" {
+		t.Errorf("expect %q, got %q", "jello", s)
+	}
+	if !doesPanic(func() { UnsafeString(Decoder(e), 4) }) {
+		t.Error("expect UnsafeString panics")
+	}
+	r = NewLimitedReader(e, Limits{MaxStringSize: 4})
+	if r.UnsafeString(10); !errors.Is(r.Err(), ErrTooBig) {
+		t.Errorf("expect ErrTooBig, got %v", r.Err())
+	}
+}
@@ -212,7 +212,7 @@ func SizeVarUint64(v uint64) int {
 	if v < 0x80 {
 		return 1
 	}
-	return (bits.Len64((v<<1)>>8) + 13) / 7
+	return min((bits.Len64(v)+6)/7, 9)
 }
 
 // SizeVarUint returns the size of a compact encoded uint value.
@@ -227,12 +227,16 @@ func SizeSize(v int) int {
 
 // SizeVarInt64 returns the size of a compact encoded int64 value.
 func SizeVarInt64(v int64) int {
-	return SizeVarUint64(uint64(v << 1))
+	x := uint64(v) << 1
+	if v < 0 {
+		x = ^x
+	}
+	return SizeVarUint64(x)
 }
 
 // SizeVarInt returns the size of a compact encoded int value.
 func SizeVarInt(v int) int {
-	return SizeVarUint(uint(v))
+	return SizeVarInt64(int64(v))
 }
 
 // SizeVarFloat returns the size of a compact encoded float value.
@@ -325,7 +329,7 @@ func SizeDIR(d dir.DIR) int {
 
 // SizeVarTime returns the size of a time value.
 func SizeVarTime(t time.Time) int {
-	l := 1 + SizeVarInt(t.Nanosecond()) + SizeVarUint64(uint64(t.Nanosecond()))
+	l := 1 + SizeVarInt64(t.Unix()) + SizeVarUint64(uint64(t.Nanosecond()))
 	if t.Location() != time.UTC {
 		_, offset := t.Zone()
 		l += SizeVarInt(offset)
@@ -2,6 +2,7 @@ package low
 
 import (
 	"bytes"
+	"math"
 	"testing"
 	"time"
 
@@ -155,3 +156,49 @@ func TestEncoder(t *testing.T) {
 		}
 	}
 }
+
+func TestSize(t *testing.T) {
+	var vars []uint64
+	for i := 0; i < 64; i++ {
+		vars = append(vars, 1<<i, 1<<i-1)
+	}
+	vars = append(vars, math.MaxUint64)
+	for _, v := range vars {
+		if n := len(AppendVarUint64(nil, v)); SizeVarUint64(v) != n {
+			t.Errorf("VarUint64 %#x expect size %d, got %d", v, n, SizeVarUint64(v))
+		}
+		if n := len(AppendVarInt64(nil, int64(v))); SizeVarInt64(int64(v)) != n {
+			t.Errorf("VarInt64 %d expect size %d, got %d", int64(v), n, SizeVarInt64(int64(v)))
+		}
+		if n := len(AppendVarInt(nil, -int(v))); SizeVarInt(-int(v)) != n {
+			t.Errorf("VarInt %d expect size %d, got %d", -int(v), n, SizeVarInt(-int(v)))
+		}
+		f := math.Float64frombits(v)
+		if n := len(AppendVarFloat(nil, f)); SizeVarFloat(f) != n {
+			t.Errorf("VarFloat %g expect size %d, got %d", f, n, SizeVarFloat(f))
+		}
+	}
+	times := []time.Time{
+		time.Unix(0, 0).UTC(),
+		time.Unix(-1e10, 999999999).UTC(),
+		time.Unix(1700000000, 5).In(time.FixedZone("", -7*3600)),
+		time.Now(),
+	}
+	for _, v := range times {
+		if n := len(AppendVarTime(nil, v)); SizeVarTime(v) != n {
+			t.Errorf("VarTime %v expect size %d, got %d", v, n, SizeVarTime(v))
+		}
+		if n := len(AppendTime(nil, v)); SizeTime() != n {
+			t.Errorf("Time %v expect size %d, got %d", v, n, SizeTime())
+		}
+	}
+	if n := len(AppendVarComplex(nil, 1+5i)); SizeVarComplex(1+5i) != n {
+		t.Errorf("VarComplex expect size %d, got %d", n, SizeVarComplex(1+5i))
+	}
+	if n := len(AppendString(nil, string(make([]byte, 200)))); SizeString(string(make([]byte, 200))) != n {
+		t.Errorf("String expect size %d, got %d", n, SizeString(string(make([]byte, 200))))
+	}
+	if n := len(AppendDIR(nil, dir.MustMake(1, 300, 0))); SizeDIR(dir.MustMake(1, 300, 0)) != n {
+		t.Errorf("DIR expect size %d, got %d", n, SizeDIR(dir.MustMake(1, 300, 0)))
+	}
+}
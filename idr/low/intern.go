@@ -0,0 +1,80 @@
+package low
+
+// DefaultMaxInterned is the maximum number of strings of an Interner used
+// when the given maximum is 0.
+const DefaultMaxInterned = 4096
+
+// DefaultMaxInternedSize is the maximum byte size of an interned string
+// used when the given maximum is 0.
+const DefaultMaxInternedSize = 64
+
+// Interner is a bounded table of strings used to decode repeated strings,
+// such as field names or MIME types, as a single shared copy. When the
+// table is full, new strings are returned as copies and not added to the
+// table. An Interner is not safe for concurrent use.
+type Interner struct {
+	m       map[string]string
+	max     int
+	maxSize int
+}
+
+// NewInterner returns an Interner holding at most max strings whose byte
+// size doesn't exceed maxSize. A zero value selects the default value.
+func NewInterner(max, maxSize int) *Interner {
+	if max <= 0 {
+		max = DefaultMaxInterned
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxInternedSize
+	}
+	return &Interner{m: make(map[string]string), max: max, maxSize: maxSize}
+}
+
+// Len returns the number of interned strings.
+func (in *Interner) Len() int {
+	return len(in.m)
+}
+
+// Reset removes all the strings from the table.
+func (in *Interner) Reset() {
+	clear(in.m)
+}
+
+// String returns the interned string equal to b. It returns a copy of b
+// when the string is not in the table and can't be added.
+func (in *Interner) String(b []byte) string {
+	if s, ok := in.m[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	if len(b) <= in.maxSize && len(in.m) < in.max {
+		in.m[s] = s
+	}
+	return s
+}
+
+// InternString returns the string in front of the remaining bytes,
+// interned by in.
+func InternString(d Decoder, max uint64, in *Interner) (Decoder, string) {
+	d, n := VarUint64(d)
+	if n > max {
+		panic("IDR decoder: data too big")
+	}
+	return d[n:], in.String(d[:n])
+}
+
+// InternString returns the next string interned by in. The string size
+// may not exceed max and the MaxStringSize limit. New string copies are
+// accounted as allocated bytes.
+func (r *Reader) InternString(max uint64, in *Interner) string {
+	start := r.off
+	b := r.str(max)
+	if r.err != nil {
+		return ""
+	}
+	if _, ok := in.m[string(b)]; !ok && !r.budget().charge(uint64(len(b))) {
+		r.fail("String", start, ErrAllocLimit)
+		return ""
+	}
+	return in.String(b)
+}
@@ -0,0 +1,60 @@
+package low
+
+import (
+	"errors"
+	"testing"
+	"unsafe"
+)
+
+func TestInterner(t *testing.T) {
+	in := NewInterner(2, 8)
+	a := in.String([]byte("text/html"))
+	b := in.String([]byte("text/html"))
+	if a != "text/html" || unsafe.StringData(a) == unsafe.StringData(b) {
+		t.Errorf("expect strings longer than 8 bytes not interned")
+	}
+	a = in.String([]byte("image"))
+	b = in.String([]byte("image"))
+	if b != "image" || unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Errorf("expect interned string")
+	}
+	in.String([]byte("video"))
+	a = in.String([]byte("audio"))
+	b = in.String([]byte("audio"))
+	if in.Len() != 2 || b != "audio" || unsafe.StringData(a) == unsafe.StringData(b) {
+		t.Errorf("expect 2 interned strings, got %d", in.Len())
+	}
+	in.Reset()
+	if in.Len() != 0 {
+		t.Errorf("expect empty table, got %d strings", in.Len())
+	}
+	if in = NewInterner(0, 0); in.max != DefaultMaxInterned || in.maxSize != DefaultMaxInternedSize {
+		t.Errorf("expect default limits, got %d %d", in.max, in.maxSize)
+	}
+
+	e := AppendString(nil, "name")
+	e = AppendString(e, "name")
+	d, a := InternString(Decoder(e), 10, in)
+	d, b = InternString(d, 10, in)
+	if len(d) != 0 || b != "name" || unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Errorf("expect interned string")
+	}
+	if !doesPanic(func() { InternString(Decoder(e), 3, in) }) {
+		t.Error("expect InternString panics")
+	}
+
+	in.Reset()
+	r := NewLimitedReader(e, Limits{MaxAlloc: 4})
+	a, b = r.InternString(10, in), r.InternString(10, in)
+	if r.Err() != nil || b != "name" || unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Errorf("expect interned string, got %q (%v)", b, r.Err())
+	}
+	r = NewLimitedReader(e, Limits{MaxAlloc: 3})
+	if r.InternString(10, NewInterner(0, 0)); !errors.Is(r.Err(), ErrAllocLimit) {
+		t.Errorf("expect ErrAllocLimit, got %v", r.Err())
+	}
+	r = NewReader(e)
+	if r.InternString(3, in); !errors.Is(r.Err(), ErrTooBig) {
+		t.Errorf("expect ErrTooBig, got %v", r.Err())
+	}
+}
@@ -0,0 +1,99 @@
+package low
+
+import "errors"
+
+// ErrTooDeep is the error returned when composite values are nested
+// deeper than the MaxDepth limit.
+var ErrTooDeep = errors.New("nesting too deep")
+
+// ErrAllocLimit is the error returned when decoding would allocate more
+// than the MaxAlloc limit.
+var ErrAllocLimit = errors.New("allocation limit exceeded")
+
+// DefaultMaxStringSize is the maximum string size used when the
+// corresponding Limits field is 0.
+const DefaultMaxStringSize = 1 << 20
+
+// DefaultMaxBlobSize is the maximum blob size used when the corresponding
+// Limits field is 0.
+const DefaultMaxBlobSize = 1 << 30
+
+// DefaultMaxAlloc is the maximum number of bytes allocated by a decoder
+// used when the corresponding Limits field is 0.
+const DefaultMaxAlloc = 1 << 26
+
+// DefaultMaxMessageSize is the maximum byte size of the decoded data used
+// when the corresponding Limits field is 0.
+const DefaultMaxMessageSize = 1 << 32
+
+// DefaultMaxNoneCount is the maximum number of NoneTag elements of an
+// array, or of entries of a map with NoneTag keys and values, used when
+// the corresponding Limits field is 0.
+const DefaultMaxNoneCount = 1 << 10
+
+// DefaultMaxDepth is the maximum nesting depth of composite values used
+// when the corresponding Limits field is 0.
+const DefaultMaxDepth = 100
+
+// Limits are the limits applied when decoding values. A zero field
+// selects the default value. A string or blob exceeding its maximum
+// size, or data exceeding the maximum message size, is reported with the
+// error ErrTooBig. With ValidUTF8, a decoded string that is not valid
+// UTF-8 is reported with the error ErrInvalidUTF8.
+//
+// The allocated bytes are an estimate of the memory allocated by the
+// decoder for the decoded values: the copies of strings and the slices of
+// composite values. Blobs are returned without a copy by a Reader and are
+// not accounted. The nesting depth of a value which is not in a composite
+// value is 0. NoneTag elements have no encoding, so that their count is
+// bounded by MaxNoneCount. Exceeding it is reported with ErrAllocLimit.
+type Limits struct {
+	MaxStringSize  uint64 // maximum byte size of a string
+	MaxBlobSize    uint64 // maximum byte size of a blob
+	MaxAlloc       uint64 // maximum number of allocated bytes
+	MaxMessageSize uint64 // maximum byte size of the decoded data
+	MaxNoneCount   uint64 // maximum number of NoneTag elements
+	MaxDepth       int    // maximum nesting depth of composite values
+	ValidUTF8      bool   // strings must be valid UTF-8
+}
+
+// withDefaults returns l with the zero fields set to their default value.
+func (l Limits) withDefaults() Limits {
+	if l.MaxStringSize == 0 {
+		l.MaxStringSize = DefaultMaxStringSize
+	}
+	if l.MaxBlobSize == 0 {
+		l.MaxBlobSize = DefaultMaxBlobSize
+	}
+	if l.MaxAlloc == 0 {
+		l.MaxAlloc = DefaultMaxAlloc
+	}
+	if l.MaxMessageSize == 0 {
+		l.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if l.MaxNoneCount == 0 {
+		l.MaxNoneCount = DefaultMaxNoneCount
+	}
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultMaxDepth
+	}
+	return l
+}
+
+// budget holds the limits of a decoder and the number of bytes it
+// allocated. It is shared by a Reader and the Readers of the composite
+// values it contains.
+type budget struct {
+	Limits
+	alloc uint64
+}
+
+// charge adds n to the allocated bytes and returns false when this
+// exceeds the MaxAlloc limit.
+func (b *budget) charge(n uint64) bool {
+	if n > b.MaxAlloc-b.alloc {
+		return false
+	}
+	b.alloc += n
+	return true
+}
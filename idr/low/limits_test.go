@@ -0,0 +1,130 @@
+package low
+
+import (
+	"errors"
+	"testing"
+)
+
+// nested returns the encoding of n arrays nested in each other.
+func nested(n int) []byte {
+	v := ArrayValue{Tag: BoolTag, Elems: []any{true}}
+	for i := 1; i < n; i++ {
+		v = ArrayValue{Tag: ArrayTag, Elems: []any{v}}
+	}
+	return AppendValue(nil, ArrayTag, v)
+}
+
+func TestReaderLimits(t *testing.T) {
+	hello := AppendString(nil, "hello")
+	// arrays of 1<<60 and 100M NoneTag elements
+	none := append([]byte{byte(ArrayTag), 10, byte(NoneTag)}, AppendVarUint64(nil, 1<<60)...)
+	none7 := append([]byte{byte(ArrayTag), 5, byte(NoneTag)}, AppendVarUint64(nil, 100_000_000)...)
+	fields := AppendValue(nil, RecordTag, RecordValue{{Num: 1, Tag: NoneTag}, {Num: 2, Tag: NoneTag}, {Num: 3, Tag: NoneTag}})
+	tests := []struct {
+		i   []byte
+		l   Limits
+		f   func(r *Reader)
+		e   string
+		err error
+	}{
+		// 0
+		{
+			i: hello, l: Limits{MaxStringSize: 4},
+			f: func(r *Reader) { r.String(10) }, err: ErrTooBig,
+			e: "IDR decoder: String at offset 0: data too big",
+		},
+		{
+			i: hello, l: Limits{MaxStringSize: 4},
+			f: func(r *Reader) { r.SkipString(10) }, err: ErrTooBig,
+			e: "IDR decoder: String at offset 0: data too big",
+		},
+		{
+			i: hello, l: Limits{MaxBlobSize: 4},
+			f: func(r *Reader) { r.Blob(10) }, err: ErrTooBig,
+			e: "IDR decoder: Blob at offset 0: data too big",
+		},
+		{
+			i: hello, l: Limits{MaxMessageSize: 5},
+			f: func(r *Reader) {}, err: ErrTooBig,
+			e: "IDR decoder: Message at offset 0: data too big",
+		},
+		{
+			i: hello, l: Limits{MaxAlloc: 4},
+			f: func(r *Reader) { r.String(10) }, err: ErrAllocLimit,
+			e: "IDR decoder: String at offset 0: allocation limit exceeded",
+		},
+		// 5
+		{
+			i: append(hello, hello...), l: Limits{MaxAlloc: 9},
+			f: func(r *Reader) { r.String(10); r.String(10) }, err: ErrAllocLimit,
+			e: "IDR decoder: String at offset 6: allocation limit exceeded",
+		},
+		{
+			i: none, f: func(r *Reader) { r.Value(r.Tag()) }, err: ErrAllocLimit,
+			e: "IDR decoder: Array at offset 3: allocation limit exceeded",
+		},
+		{
+			i: fields, l: Limits{MaxAlloc: 2 * fieldSize},
+			f: func(r *Reader) { r.Value(r.Tag()) }, err: ErrAllocLimit,
+			e: "IDR decoder: Alloc at offset 6: allocation limit exceeded",
+		},
+		{
+			i: nested(3), l: Limits{MaxDepth: 2},
+			f: func(r *Reader) { r.Value(r.Tag()) }, err: ErrTooDeep,
+			e: "IDR decoder: Array at offset 7: nesting too deep",
+		},
+		{
+			i: nested(DefaultMaxDepth + 1),
+			f: func(r *Reader) { r.SkipValue(r.Tag()); r.Reset(r.b); r.Value(r.Tag()) }, err: ErrTooDeep,
+			e: "IDR decoder: Array at offset 360: nesting too deep",
+		},
+		// 10
+		{
+			i: none7, f: func(r *Reader) { r.Value(r.Tag()) }, err: ErrAllocLimit,
+			e: "IDR decoder: Array at offset 3: allocation limit exceeded",
+		},
+		{
+			i: none7, l: Limits{MaxNoneCount: 1 << 30},
+			f: func(r *Reader) { r.Value(r.Tag()) }, err: ErrAllocLimit,
+			e: "IDR decoder: Alloc at offset 7: allocation limit exceeded",
+		},
+	}
+	for i, test := range tests {
+		r := NewLimitedReader(test.i, test.l)
+		test.f(r)
+		if r.Err() == nil {
+			t.Errorf("%d expect error %q", i, test.e)
+			continue
+		}
+		if r.Err().Error() != test.e {
+			t.Errorf("%d expect error %q, got %q", i, test.e, r.Err())
+		}
+		if !errors.Is(r.Err(), test.err) {
+			t.Errorf("%d expect error is %v", i, test.err)
+		}
+	}
+
+	// limits are met
+	r := NewLimitedReader(nested(3), Limits{MaxDepth: 3, MaxMessageSize: uint64(len(nested(3)))})
+	if r.Value(r.Tag()); r.Err() != nil {
+		t.Errorf("unexpected error: %v", r.Err())
+	}
+	r = NewLimitedReader(hello, Limits{MaxStringSize: 5, MaxAlloc: 5})
+	for i := 0; i < 2; i++ {
+		if v := r.String(5); v != "hello" || r.Err() != nil {
+			t.Errorf("%d expect %q, got %q (%v)", i, "hello", v, r.Err())
+		}
+		// the allocated bytes are cleared by Reset
+		r.Reset(hello)
+	}
+	if l := r.Limits(); l.MaxAlloc != 5 || l.MaxDepth != DefaultMaxDepth {
+		t.Errorf("expect limits to be kept, got %+v", l)
+	}
+	r = NewReader(append([]byte{byte(ArrayTag), 3, byte(NoneTag)}, AppendVarUint64(nil, DefaultMaxNoneCount)...))
+	if v := r.Value(r.Tag()); r.Err() != nil || len(v.(ArrayValue).Elems) != DefaultMaxNoneCount {
+		t.Errorf("expect %d elements, got %v", DefaultMaxNoneCount, r.Err())
+	}
+	if err := CheckCanonical(nested(DefaultMaxDepth + 1)); !errors.Is(err, ErrTooDeep) {
+		t.Errorf("expect ErrTooDeep, got %v", err)
+	}
+}
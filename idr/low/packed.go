@@ -0,0 +1,269 @@
+package low
+
+import (
+	"encoding/binary"
+	"time"
+	"unsafe"
+)
+
+// Packed is the type set of the elements of packed arrays. They are
+// encoded as arrays of elements of fixed size. The elements of type uint8
+// are encoded with Uint8Tag and those of type time.Time with TimeTag.
+type Packed interface {
+	bool | uint8 | uint16 | uint32 | uint64 | int8 | int16 | int32 | int64 |
+		float32 | float64 | complex64 | complex128 | time.Time
+}
+
+// littleEndian is true when the byte order of the host is little endian
+// like the IDR encoding.
+var littleEndian = binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+// packedTag returns the tag and the encoded byte size of the elements of
+// type T.
+func packedTag[T Packed]() (TagT, int) {
+	var v T
+	switch any(v).(type) {
+	case bool:
+		return BoolTag, 1
+	case uint8:
+		return Uint8Tag, 1
+	case uint16:
+		return Uint16Tag, 2
+	case uint32:
+		return Uint32Tag, 4
+	case uint64:
+		return Uint64Tag, 8
+	case int8:
+		return Int8Tag, 1
+	case int16:
+		return Int16Tag, 2
+	case int32:
+		return Int32Tag, 4
+	case int64:
+		return Int64Tag, 8
+	case float32:
+		return Float32Tag, 4
+	case float64:
+		return Float64Tag, 8
+	case complex64:
+		return Complex64Tag, 8
+	case complex128:
+		return Complex128Tag, 16
+	}
+	return TimeTag, 16
+}
+
+// packedMatch returns true if the elements of an array of tag t can be
+// decoded as elements of tag exp.
+func packedMatch(t, exp TagT) bool {
+	return t == exp || t == ByteTag && exp == Uint8Tag
+}
+
+// bulk returns true if the elements of type T are copied in bulk because
+// their memory layout is their encoding. This is the case of numbers on
+// little endian hosts.
+func bulk[T Packed]() bool {
+	var v T
+	switch any(v).(type) {
+	case bool, time.Time:
+		return false
+	}
+	return littleEndian
+}
+
+// bytesOf returns the memory of the elements of v.
+func bytesOf[T Packed](v []T) []byte {
+	var z T
+	return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(v))), len(v)*int(unsafe.Sizeof(z)))
+}
+
+// SizePackedArray returns the size of the packed array v.
+func SizePackedArray[T Packed](v []T) int {
+	t, size := packedTag[T]()
+	n := SizeVarUint64(uint64(t)) + SizeVarUint(uint(len(v))) + len(v)*size
+	return SizeVarUint64(uint64(n)) + n
+}
+
+// AppendPackedArray appends the array of the elements of v. The encoding
+// is the one of an array whose elements are encoded as specified by the
+// tag of T. On little endian hosts, numbers are appended with a single
+// copy.
+func AppendPackedArray[T Packed](e Encoder, v []T) Encoder {
+	t, size := packedTag[T]()
+	e = AppendSize(e, uint64(SizeVarUint64(uint64(t))+SizeVarUint(uint(len(v)))+len(v)*size))
+	e = AppendTag(e, t)
+	e = AppendVarUint(e, uint(len(v)))
+	if bulk[T]() {
+		return append(e, bytesOf(v)...)
+	}
+	return appendPacked(e, v)
+}
+
+// appendPacked appends the elements of v one by one.
+func appendPacked[T Packed](e Encoder, v []T) Encoder {
+	switch x := any(v).(type) {
+	case []bool:
+		for _, v := range x {
+			e = AppendBool(e, v)
+		}
+	case []uint8:
+		e = append(e, x...)
+	case []uint16:
+		for _, v := range x {
+			e = AppendUint16(e, v)
+		}
+	case []uint32:
+		for _, v := range x {
+			e = AppendUint32(e, v)
+		}
+	case []uint64:
+		for _, v := range x {
+			e = AppendUint64(e, v)
+		}
+	case []int8:
+		for _, v := range x {
+			e = AppendInt8(e, v)
+		}
+	case []int16:
+		for _, v := range x {
+			e = AppendInt16(e, v)
+		}
+	case []int32:
+		for _, v := range x {
+			e = AppendInt32(e, v)
+		}
+	case []int64:
+		for _, v := range x {
+			e = AppendInt64(e, v)
+		}
+	case []float32:
+		for _, v := range x {
+			e = AppendFloat32(e, v)
+		}
+	case []float64:
+		for _, v := range x {
+			e = AppendFloat64(e, v)
+		}
+	case []complex64:
+		for _, v := range x {
+			e = AppendComplex64(e, v)
+		}
+	case []complex128:
+		for _, v := range x {
+			e = AppendComplex128(e, v)
+		}
+	case []time.Time:
+		for _, v := range x {
+			e = AppendTime(e, v)
+		}
+	}
+	return e
+}
+
+// decodePacked decodes the elements of x from their encoding b. On little
+// endian hosts, numbers are decoded with a single copy.
+func decodePacked[T Packed](x []T, b []byte) {
+	if bulk[T]() {
+		copy(bytesOf(x), b)
+		return
+	}
+	d := Decoder(b)
+	switch x := any(x).(type) {
+	case []bool:
+		for i := range x {
+			d, x[i] = Bool(d)
+		}
+	case []uint8:
+		copy(x, d)
+	case []uint16:
+		for i := range x {
+			d, x[i] = Uint16(d)
+		}
+	case []uint32:
+		for i := range x {
+			d, x[i] = Uint32(d)
+		}
+	case []uint64:
+		for i := range x {
+			d, x[i] = Uint64(d)
+		}
+	case []int8:
+		for i := range x {
+			d, x[i] = Int8(d)
+		}
+	case []int16:
+		for i := range x {
+			d, x[i] = Int16(d)
+		}
+	case []int32:
+		for i := range x {
+			d, x[i] = Int32(d)
+		}
+	case []int64:
+		for i := range x {
+			d, x[i] = Int64(d)
+		}
+	case []float32:
+		for i := range x {
+			d, x[i] = Float32(d)
+		}
+	case []float64:
+		for i := range x {
+			d, x[i] = Float64(d)
+		}
+	case []complex64:
+		for i := range x {
+			d, x[i] = Complex64(d)
+		}
+	case []complex128:
+		for i := range x {
+			d, x[i] = Complex128(d)
+		}
+	case []time.Time:
+		for i := range x {
+			d, x[i] = Time(d)
+		}
+	}
+}
+
+// PackedArray returns the packed array in front of the remaining bytes.
+// The elements of a non-empty array must be encoded as specified by the
+// tag of T, and ByteTag elements may be decoded as uint8. Panics if the
+// tag or the size of the array doesn't match T.
+func PackedArray[T Packed](d Decoder) (Decoder, []T) {
+	d, c, t, n := Array(d)
+	tag, size := packedTag[T]()
+	if n > 0 && !packedMatch(t, tag) || n != uint64(len(c)/size) || len(c)%size != 0 {
+		panic("IDR decoder: packed array mismatch")
+	}
+	x := make([]T, n)
+	decodePacked(x, c)
+	return d, x
+}
+
+// ReadPackedArray returns the next packed array decoded with r. The
+// elements of a non-empty array must be encoded as specified by the tag
+// of T, and ByteTag elements may be decoded as uint8. The encoding is
+// invalid if the tag or the size of the array doesn't match T. The
+// elements are accounted as allocated bytes.
+func ReadPackedArray[T Packed](r *Reader) []T {
+	start := r.off
+	c, t, n := r.Array()
+	r.adopt(&c)
+	if r.err != nil {
+		return nil
+	}
+	tag, size := packedTag[T]()
+	b := c.Peek()
+	if n > 0 && !packedMatch(t, tag) || n != uint64(len(b)/size) || len(b)%size != 0 {
+		r.fail("Array", start, ErrInvalid)
+		return nil
+	}
+	var z T
+	if !r.Alloc(n, uint64(unsafe.Sizeof(z))) {
+		return nil
+	}
+	x := make([]T, n)
+	decodePacked(x, b)
+	return x
+}
@@ -0,0 +1,104 @@
+package low
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testPacked checks the encoding and decoding of the packed array v.
+func testPacked[T Packed](t *testing.T, v []T) {
+	t.Helper()
+	tag, _ := packedTag[T]()
+	elems := make([]any, len(v))
+	for i := range v {
+		elems[i] = v[i]
+	}
+	exp := AppendValue(nil, ArrayTag, ArrayValue{Tag: tag, Elems: elems})[1:]
+	e := AppendPackedArray(nil, v)
+	if !bytes.Equal(e, exp) {
+		t.Errorf("%T expect %x, got %x", v, exp, e)
+	}
+	if n := SizePackedArray(v); n != len(exp) {
+		t.Errorf("%T expect size %d, got %d", v, len(exp), n)
+	}
+	d, x := PackedArray[T](Decoder(append(e, 1)))
+	if len(d) != 1 || !reflect.DeepEqual(x, v) {
+		t.Errorf("%T expect %v, got %v", v, v, x)
+	}
+	r := NewReader(e)
+	if x := ReadPackedArray[T](r); r.Err() != nil || r.Len() != 0 || !reflect.DeepEqual(x, v) {
+		t.Errorf("%T expect %v, got %v (%v)", v, v, x, r.Err())
+	}
+}
+
+func TestPackedArray(t *testing.T) {
+	defer func(le bool) { littleEndian = le }(littleEndian)
+	for _, le := range []bool{littleEndian, false} {
+		littleEndian = le
+		testPacked(t, []bool{true, false, true})
+		testPacked(t, []uint8{1, 2, 255})
+		testPacked(t, []uint16{1, 0x1234, math.MaxUint16})
+		testPacked(t, []uint32{1, 0x12345678, math.MaxUint32})
+		testPacked(t, []uint64{1, 0x123456789abcdef0, math.MaxUint64})
+		testPacked(t, []int8{-1, 2, math.MinInt8})
+		testPacked(t, []int16{-1, 0x1234, math.MinInt16})
+		testPacked(t, []int32{-1, 0x12345678, math.MinInt32})
+		testPacked(t, []int64{-1, 0x123456789abcdef0, math.MinInt64})
+		testPacked(t, []float32{-1.5, 3.25, float32(math.Inf(1))})
+		testPacked(t, []float64{-1.5, math.Pi, math.Inf(-1)})
+		testPacked(t, []complex64{complex(1, -2), 0})
+		testPacked(t, []complex128{complex(math.Pi, -2), 0})
+		testPacked(t, []time.Time{time.Date(2024, 5, 1, 12, 30, 0, 5, time.UTC)})
+		testPacked(t, []uint16{})
+	}
+
+	// NaN bits are kept
+	nan := math.Float64frombits(0x7ff8000000000002)
+	_, x := PackedArray[float64](Decoder(AppendPackedArray(nil, []float64{nan})))
+	if math.Float64bits(x[0]) != 0x7ff8000000000002 {
+		t.Errorf("expect NaN bits %x, got %x", uint64(0x7ff8000000000002), math.Float64bits(x[0]))
+	}
+
+	// ByteTag elements are decoded as uint8, and empty arrays of any tag
+	e := AppendValue(nil, ArrayTag, ArrayValue{Tag: ByteTag, Elems: []any{byte(1), byte(2)}})[1:]
+	e = append(e, AppendValue(nil, ArrayTag, ArrayValue{Tag: StringTag})[1:]...)
+	r := NewReader(e)
+	if x := ReadPackedArray[uint8](r); !bytes.Equal(x, []byte{1, 2}) {
+		t.Errorf("expect [1 2], got %v (%v)", x, r.Err())
+	}
+	if x := ReadPackedArray[int32](r); len(x) != 0 || r.Err() != nil {
+		t.Errorf("expect empty array, got %v (%v)", x, r.Err())
+	}
+}
+
+func TestPackedArrayErrors(t *testing.T) {
+	tests := []struct {
+		b   []byte
+		f   func(r *Reader)
+		err error
+	}{
+		// 0
+		{b: AppendPackedArray(nil, []uint16{1}), f: func(r *Reader) { ReadPackedArray[int16](r) }, err: ErrInvalid},
+		{b: AppendPackedArray(nil, []uint32{1}), f: func(r *Reader) { ReadPackedArray[uint16](r) }, err: ErrInvalid},
+		{b: []byte{5, byte(Uint16Tag), 1, 0, 0, 0}, f: func(r *Reader) { ReadPackedArray[uint16](r) }, err: ErrInvalid},
+		{b: []byte{3, byte(Uint16Tag), 1, 0}, f: func(r *Reader) { ReadPackedArray[uint16](r) }, err: ErrInvalid},
+		{b: []byte{4, byte(Uint16Tag), 1, 0}, f: func(r *Reader) { ReadPackedArray[uint16](r) }, err: io.ErrUnexpectedEOF},
+		// 5
+		{b: AppendPackedArray(nil, []uint64{1, 2}), f: func(r *Reader) { r.Alloc(DefaultMaxAlloc-8, 1); ReadPackedArray[uint64](r) }, err: ErrAllocLimit},
+	}
+	for i, test := range tests {
+		r := NewReader(test.b)
+		test.f(r)
+		if !errors.Is(r.Err(), test.err) {
+			t.Errorf("%d expect %v, got %v", i, test.err, r.Err())
+		}
+		if i > 0 && i < 4 && !doesPanic(func() { PackedArray[uint16](Decoder(test.b)) }) {
+			t.Errorf("%d expect PackedArray panics", i)
+		}
+	}
+}
@@ -0,0 +1,630 @@
+package low
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+)
+
+// ErrTooBig is the error returned when a size exceeds the given maximum.
+var ErrTooBig = errors.New("data too big")
+
+// ErrInvalid is the error returned when an encoding is invalid.
+var ErrInvalid = errors.New("invalid encoding")
+
+// maxVarTimeSize is the maximum byte length of a VarTime value without its
+// length prefix.
+const maxVarTimeSize = 30
+
+// DecodeError is the error returned by a Reader. Truncated data is
+// reported with the error io.ErrUnexpectedEOF.
+type DecodeError struct {
+	Op     string // decoded type
+	Offset int    // byte offset of the value in the decoded data
+	Err    error  // cause of the error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("IDR decoder: %s at offset %d: %v", e.Op, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Reader is a checked low level IDR decoder. Unlike the Decoder functions,
+// it doesn't panic on truncated or invalid data. The first error met is
+// retained and returned by Err. Once an error occurred, the decoding
+// methods return zero values. The Decoder functions are faster and may be
+// used with trusted data.
+//
+// The decoding is bounded by Limits. The string and blob sizes are
+// bounded by the max argument of the methods and by the limits. Strings
+// must be valid UTF-8 with the ValidUTF8 limit.
+type Reader struct {
+	b     []byte
+	off   int
+	err   error
+	depth int     // nesting depth of the decoded values
+	lim   *budget // nil for the default limits
+}
+
+// NewReader returns a Reader decoding b with the default limits.
+func NewReader(b []byte) *Reader {
+	return NewLimitedReader(b, Limits{})
+}
+
+// NewLimitedReader returns a Reader decoding b with the given limits.
+func NewLimitedReader(b []byte, limits Limits) *Reader {
+	r := &Reader{lim: &budget{Limits: limits.withDefaults()}}
+	r.Reset(b)
+	return r
+}
+
+// Reset sets r to decode b and clears the error and the allocated bytes.
+// The limits are kept.
+func (r *Reader) Reset(b []byte) {
+	lim := r.budget()
+	*r = Reader{b: b, lim: lim}
+	lim.alloc = 0
+	if uint64(len(b)) > lim.MaxMessageSize {
+		r.fail("Message", 0, ErrTooBig)
+	}
+}
+
+// Limits returns the limits applied by r.
+func (r *Reader) Limits() Limits {
+	return r.budget().Limits
+}
+
+// Alloc accounts the allocation of n elements of the given byte size and
+// returns true when the MaxAlloc limit is not exceeded. Otherwise the
+// error ErrAllocLimit is recorded. It is used by decoders allocating
+// memory for the values decoded with r.
+func (r *Reader) Alloc(n, size uint64) bool {
+	if r.err != nil {
+		return false
+	}
+	hi, lo := bits.Mul64(n, size)
+	if hi != 0 || !r.budget().charge(lo) {
+		r.fail("Alloc", r.off, ErrAllocLimit)
+		return false
+	}
+	return true
+}
+
+// budget returns the budget of r, set to the default limits if r has
+// none.
+func (r *Reader) budget() *budget {
+	if r.lim == nil {
+		r.lim = &budget{Limits: Limits{}.withDefaults()}
+	}
+	return r.lim
+}
+
+// Err returns the first error met, or nil.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Offset returns the byte offset of the next value to decode.
+func (r *Reader) Offset() int {
+	return r.off
+}
+
+// Len returns the number of bytes left to decode.
+func (r *Reader) Len() int {
+	return len(r.b) - r.off
+}
+
+// Peek returns the bytes left to decode without making a copy.
+func (r *Reader) Peek() []byte {
+	return r.b[r.off:]
+}
+
+// fail records the error err for the value of type op at offset off when
+// no error was recorded yet.
+func (r *Reader) fail(op string, off int, err error) {
+	if r.err == nil {
+		r.err = &DecodeError{Op: op, Offset: off, Err: err}
+	}
+}
+
+// next returns the next n bytes or nil in case of error.
+func (r *Reader) next(op string, n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 {
+		r.fail(op, r.off, ErrInvalid)
+		return nil
+	}
+	if n > len(r.b)-r.off {
+		r.fail(op, r.off, io.ErrUnexpectedEOF)
+		return nil
+	}
+	b := r.b[r.off : r.off+n : r.off+n]
+	r.off += n
+	return b
+}
+
+// varUint64 returns the VarUint64 in front of b and its byte length, or
+// 0 and 0 when b is truncated.
+func varUint64(b []byte) (uint64, int) {
+	var v uint64
+	var s byte
+	for i, c := range b {
+		if c < 0x80 || i == 8 {
+			return v | uint64(c)<<s, i + 1
+		}
+		v |= uint64(c&0x7F) << s
+		s += 7
+	}
+	return 0, 0
+}
+
+// varInt64 returns the VarInt64 in front of b and its byte length, or
+// 0 and 0 when b is truncated.
+func varInt64(b []byte) (int64, int) {
+	x, n := varUint64(b)
+	if x&1 != 0 {
+		return int64(^(x >> 1)), n
+	}
+	return int64(x >> 1), n
+}
+
+// varUint64 returns the next VarUint64 value.
+func (r *Reader) varUint64(op string) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := varUint64(r.b[r.off:])
+	if n == 0 {
+		r.fail(op, r.off, io.ErrUnexpectedEOF)
+		return 0
+	}
+	r.off += n
+	return v
+}
+
+// blob returns the next size prefixed bytes without making a copy.
+func (r *Reader) blob(op string, max uint64) []byte {
+	start := r.off
+	n := r.varUint64(op)
+	if r.err != nil {
+		return nil
+	}
+	if n > max {
+		r.fail(op, start, ErrTooBig)
+		return nil
+	}
+	if n > uint64(r.Len()) {
+		r.fail(op, start, io.ErrUnexpectedEOF)
+		return nil
+	}
+	return r.next(op, int(n))
+}
+
+// Byte returns the next byte.
+func (r *Reader) Byte() byte {
+	if b := r.next("Byte", 1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+// Bool returns the next bool.
+func (r *Reader) Bool() bool {
+	if b := r.next("Bool", 1); b != nil {
+		return b[0] != 0
+	}
+	return false
+}
+
+// Bytes returns the next n bytes without making a copy.
+func (r *Reader) Bytes(n int) []byte {
+	return r.next("Bytes", n)
+}
+
+// VarUint64 returns the next compact encoded uint64.
+func (r *Reader) VarUint64() uint64 {
+	return r.varUint64("VarUint64")
+}
+
+// VarUint returns the next compact encoded uint.
+func (r *Reader) VarUint() uint {
+	return uint(r.varUint64("VarUint"))
+}
+
+// Tag returns the next TagT.
+func (r *Reader) Tag() TagT {
+	return TagT(r.varUint64("Tag"))
+}
+
+// Size returns the next size value.
+func (r *Reader) Size() uint64 {
+	return r.varUint64("Size")
+}
+
+// VarInt64 returns the next compact encoded int64.
+func (r *Reader) VarInt64() int64 {
+	x := r.varUint64("VarInt64")
+	if x&1 != 0 {
+		return int64(^(x >> 1))
+	}
+	return int64(x >> 1)
+}
+
+// VarInt returns the next compact encoded int.
+func (r *Reader) VarInt() int {
+	return int(r.VarInt64())
+}
+
+// VarFloat returns the next compact encoded float64.
+func (r *Reader) VarFloat() float64 {
+	return math.Float64frombits(bits.ReverseBytes64(r.varUint64("VarFloat")))
+}
+
+// VarComplex returns the next compact encoded complex128.
+func (r *Reader) VarComplex() complex128 {
+	if r.err != nil {
+		return 0
+	}
+	x1, n1 := varUint64(r.b[r.off:])
+	x2, n2 := varUint64(r.b[r.off+n1:])
+	if n1 == 0 || n2 == 0 {
+		r.fail("VarComplex", r.off, io.ErrUnexpectedEOF)
+		return 0
+	}
+	r.off += n1 + n2
+	v1 := math.Float64frombits(bits.ReverseBytes64(x1))
+	v2 := math.Float64frombits(bits.ReverseBytes64(x2))
+	return complex(v1, v2)
+}
+
+// Uint8 returns the next uint8.
+func (r *Reader) Uint8() uint8 {
+	if b := r.next("Uint8", 1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+// Uint16 returns the next uint16.
+func (r *Reader) Uint16() uint16 {
+	if b := r.next("Uint16", 2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+// Uint32 returns the next uint32.
+func (r *Reader) Uint32() uint32 {
+	if b := r.next("Uint32", 4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+// Uint64 returns the next uint64.
+func (r *Reader) Uint64() uint64 {
+	if b := r.next("Uint64", 8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// Int8 returns the next int8.
+func (r *Reader) Int8() int8 {
+	if b := r.next("Int8", 1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+// Int16 returns the next int16.
+func (r *Reader) Int16() int16 {
+	if b := r.next("Int16", 2); b != nil {
+		return int16(binary.LittleEndian.Uint16(b))
+	}
+	return 0
+}
+
+// Int32 returns the next int32.
+func (r *Reader) Int32() int32 {
+	if b := r.next("Int32", 4); b != nil {
+		return int32(binary.LittleEndian.Uint32(b))
+	}
+	return 0
+}
+
+// Int64 returns the next int64.
+func (r *Reader) Int64() int64 {
+	if b := r.next("Int64", 8); b != nil {
+		return int64(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+// Float32 returns the next float32.
+func (r *Reader) Float32() float32 {
+	if b := r.next("Float32", 4); b != nil {
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	}
+	return 0
+}
+
+// Float64 returns the next float64.
+func (r *Reader) Float64() float64 {
+	if b := r.next("Float64", 8); b != nil {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+// Complex64 returns the next complex64.
+func (r *Reader) Complex64() complex64 {
+	if b := r.next("Complex64", 8); b != nil {
+		return complex(math.Float32frombits(binary.LittleEndian.Uint32(b)),
+			math.Float32frombits(binary.LittleEndian.Uint32(b[4:])))
+	}
+	return 0
+}
+
+// Complex128 returns the next complex128.
+func (r *Reader) Complex128() complex128 {
+	if b := r.next("Complex128", 16); b != nil {
+		return complex(math.Float64frombits(binary.LittleEndian.Uint64(b)),
+			math.Float64frombits(binary.LittleEndian.Uint64(b[8:])))
+	}
+	return 0
+}
+
+// Blob returns the next blob without making a copy. The blob size may not
+// exceed max and the MaxBlobSize limit.
+func (r *Reader) Blob(max uint64) []byte {
+	return r.blob("Blob", min(max, r.budget().MaxBlobSize))
+}
+
+// String returns a copy of the next string. The string size may not
+// exceed max and the MaxStringSize limit. The copy is accounted as
+// allocated bytes.
+func (r *Reader) String(max uint64) string {
+	start := r.off
+	b := r.str(max)
+	if r.err == nil && !r.budget().charge(uint64(len(b))) {
+		r.fail("String", start, ErrAllocLimit)
+		return ""
+	}
+	return string(b)
+}
+
+// UnsafeString returns the next string without making a copy. The string
+// shares its bytes with the decoded data, so they must not be modified
+// while the string is in use. The string size may not exceed max and the
+// MaxStringSize limit.
+func (r *Reader) UnsafeString(max uint64) string {
+	return unsafeString(r.str(max))
+}
+
+// str returns the bytes of the next string whose size may not exceed max
+// and the MaxStringSize limit. They must be valid UTF-8 with the
+// ValidUTF8 limit.
+func (r *Reader) str(max uint64) []byte {
+	b := r.blob("String", min(max, r.budget().MaxStringSize))
+	if r.err == nil && r.lim.ValidUTF8 {
+		if i := invalidUTF8(b); i >= 0 {
+			r.fail("String", r.off-len(b)+i, ErrInvalidUTF8)
+			return nil
+		}
+	}
+	return b
+}
+
+// DIR returns the next DIR.
+func (r *Reader) DIR() dir.DIR {
+	start := r.off
+	b := r.blob("DIR", dir.MaxBinaryLen)
+	if r.err != nil {
+		return dir.DIR{}
+	}
+	v, err := dir.DecodeBinary(b)
+	if err != nil {
+		r.fail("DIR", start, err)
+		return dir.DIR{}
+	}
+	return v
+}
+
+// VarTime returns the next compact encoded time.
+func (r *Reader) VarTime() time.Time {
+	start := r.off
+	b := r.blob("VarTime", maxVarTimeSize)
+	if r.err != nil {
+		return time.Time{}
+	}
+	utcsec, n1 := varInt64(b)
+	if n1 == 0 {
+		r.fail("VarTime", start, ErrInvalid)
+		return time.Time{}
+	}
+	nano, n2 := varUint64(b[n1:])
+	if n2 == 0 {
+		r.fail("VarTime", start, ErrInvalid)
+		return time.Time{}
+	}
+	b = b[n1+n2:]
+	if len(b) == 0 {
+		return time.Unix(utcsec, int64(nano)).UTC()
+	}
+	offset, n3 := varInt64(b)
+	if n3 != len(b) {
+		r.fail("VarTime", start, ErrInvalid)
+		return time.Time{}
+	}
+	return time.Unix(utcsec, int64(nano)).In(time.FixedZone("", int(offset)))
+}
+
+// Time returns the next time.
+func (r *Reader) Time() time.Time {
+	b := r.next("Time", 16)
+	if b == nil {
+		return time.Time{}
+	}
+	utcsec := int64(binary.LittleEndian.Uint64(b))
+	nano := binary.LittleEndian.Uint32(b[8:])
+	offset := int32(binary.LittleEndian.Uint32(b[12:]))
+	if offset == 0 {
+		return time.Unix(utcsec, int64(nano)).UTC()
+	}
+	return time.Unix(utcsec, int64(nano)).In(time.FixedZone("", int(offset)))
+}
+
+// skipping methods
+
+// SkipByte skips a byte value.
+func (r *Reader) SkipByte() {
+	r.next("Byte", 1)
+}
+
+// SkipBytes skips n bytes.
+func (r *Reader) SkipBytes(n uint64) {
+	if n > uint64(r.Len()) {
+		r.fail("Bytes", r.off, io.ErrUnexpectedEOF)
+		return
+	}
+	r.next("Bytes", int(n))
+}
+
+// SkipBool skips a bool value.
+func (r *Reader) SkipBool() {
+	r.next("Bool", 1)
+}
+
+// SkipVarUint64 skips a compact encoded uint64 value.
+func (r *Reader) SkipVarUint64() {
+	r.varUint64("VarUint64")
+}
+
+// SkipVarUint skips a compact encoded uint value.
+func (r *Reader) SkipVarUint() {
+	r.varUint64("VarUint")
+}
+
+// SkipSize skips a Size value.
+func (r *Reader) SkipSize() {
+	r.varUint64("Size")
+}
+
+// SkipVarInt64 skips a compact encoded int64 value.
+func (r *Reader) SkipVarInt64() {
+	r.varUint64("VarInt64")
+}
+
+// SkipVarInt skips a compact encoded int value.
+func (r *Reader) SkipVarInt() {
+	r.varUint64("VarInt")
+}
+
+// SkipVarFloat skips a compact encoded float value.
+func (r *Reader) SkipVarFloat() {
+	r.varUint64("VarFloat")
+}
+
+// SkipVarComplex skips a compact encoded complex value.
+func (r *Reader) SkipVarComplex() {
+	r.VarComplex()
+}
+
+// SkipUint8 skips a uint8 value.
+func (r *Reader) SkipUint8() {
+	r.next("Uint8", 1)
+}
+
+// SkipUint16 skips a uint16 value.
+func (r *Reader) SkipUint16() {
+	r.next("Uint16", 2)
+}
+
+// SkipUint32 skips a uint32 value.
+func (r *Reader) SkipUint32() {
+	r.next("Uint32", 4)
+}
+
+// SkipUint64 skips a uint64 value.
+func (r *Reader) SkipUint64() {
+	r.next("Uint64", 8)
+}
+
+// SkipInt8 skips a int8 value.
+func (r *Reader) SkipInt8() {
+	r.next("Int8", 1)
+}
+
+// SkipInt16 skips a int16 value.
+func (r *Reader) SkipInt16() {
+	r.next("Int16", 2)
+}
+
+// SkipInt32 skips a int32 value.
+func (r *Reader) SkipInt32() {
+	r.next("Int32", 4)
+}
+
+// SkipInt64 skips a int64 value.
+func (r *Reader) SkipInt64() {
+	r.next("Int64", 8)
+}
+
+// SkipFloat32 skips a float32 value.
+func (r *Reader) SkipFloat32() {
+	r.next("Float32", 4)
+}
+
+// SkipFloat64 skips a float64 value.
+func (r *Reader) SkipFloat64() {
+	r.next("Float64", 8)
+}
+
+// SkipComplex64 skips a complex64 value.
+func (r *Reader) SkipComplex64() {
+	r.next("Complex64", 8)
+}
+
+// SkipComplex128 skips a complex128 value.
+func (r *Reader) SkipComplex128() {
+	r.next("Complex128", 16)
+}
+
+// SkipBlob skips a blob value whose size may not exceed max and the
+// MaxBlobSize limit.
+func (r *Reader) SkipBlob(max uint64) {
+	r.blob("Blob", min(max, r.budget().MaxBlobSize))
+}
+
+// SkipString skips a string value whose size may not exceed max and the
+// MaxStringSize limit.
+func (r *Reader) SkipString(max uint64) {
+	r.blob("String", min(max, r.budget().MaxStringSize))
+}
+
+// SkipDIR skips a DIR value.
+func (r *Reader) SkipDIR() {
+	r.blob("DIR", dir.MaxBinaryLen)
+}
+
+// SkipVarTime skips a compact encoded time value.
+func (r *Reader) SkipVarTime() {
+	r.blob("VarTime", maxVarTimeSize)
+}
+
+// SkipTime skips a time value.
+func (r *Reader) SkipTime() {
+	r.next("Time", 16)
+}
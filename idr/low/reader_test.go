@@ -0,0 +1,384 @@
+package low
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+)
+
+// readerValues are values of each type with their tag.
+var readerValues = []struct {
+	t TagT
+	v any
+}{
+	{t: BoolTag, v: true},
+	{t: ByteTag, v: byte(0xFE)},
+	{t: VarUint64Tag, v: uint64(0xffff_ffff_ffff_ffff)},
+	{t: VarUintTag, v: uint(300)},
+	{t: NoneTag, v: TagT(12345)},
+	{t: SizeTag, v: uint64(0xFF)},
+	{t: VarInt64Tag, v: int64(-63)},
+	{t: VarIntTag, v: int(-1234)},
+	{t: VarFloatTag, v: float64(.5)},
+	{t: VarComplexTag, v: 1 + 5i},
+	{t: Uint8Tag, v: uint8(0x80)},
+	{t: Uint16Tag, v: uint16(0x8180)},
+	{t: Uint32Tag, v: uint32(0x83828180)},
+	{t: Uint64Tag, v: uint64(0x8786858483828180)},
+	{t: Int8Tag, v: int8(-1)},
+	{t: Int16Tag, v: int16(-256)},
+	{t: Int32Tag, v: int32(-55555)},
+	{t: Int64Tag, v: int64(-876543210)},
+	{t: Float32Tag, v: float32(2.)},
+	{t: Float64Tag, v: float64(-2.)},
+	{t: Complex64Tag, v: complex64(1. + 5i)},
+	{t: Complex128Tag, v: 1. + 5i},
+	{t: BlobTag, v: []byte{1, 2, 3, 4}},
+	{t: StringTag, v: "hello"},
+	{t: DIRTag, v: dir.MustMake(1, 2, 3, 4, 5, 6, 7)},
+	{t: DIRTag, v: dir.DIR{}},
+	{t: VarTimeTag, v: tme("2023-10-06T10:00:00.5+01:00")},
+	{t: VarTimeTag, v: tme("2023-10-06T10:00:00Z")},
+	{t: TimeTag, v: tme("2023-10-06T10:00:00-07:00")},
+	{t: BytesTag, v: []byte{1, 2}},
+}
+
+// appendReaderValue appends v with the encoding identified by t.
+func appendReaderValue(e Encoder, t TagT, v any) Encoder {
+	switch t {
+	case NoneTag:
+		return AppendTag(e, v.(TagT))
+	case BoolTag:
+		return AppendBool(e, v.(bool))
+	case ByteTag:
+		return AppendByte(e, v.(byte))
+	case BytesTag:
+		return AppendBytes(e, v.([]byte)...)
+	case VarUintTag:
+		return AppendVarUint(e, v.(uint))
+	case VarIntTag:
+		return AppendVarInt(e, v.(int))
+	case VarUint64Tag:
+		return AppendVarUint64(e, v.(uint64))
+	case VarInt64Tag:
+		return AppendVarInt64(e, v.(int64))
+	case SizeTag:
+		return AppendSize(e, v.(uint64))
+	case VarFloatTag:
+		return AppendVarFloat(e, v.(float64))
+	case VarComplexTag:
+		return AppendVarComplex(e, v.(complex128))
+	case Uint8Tag:
+		return AppendUint8(e, v.(uint8))
+	case Uint16Tag:
+		return AppendUint16(e, v.(uint16))
+	case Uint32Tag:
+		return AppendUint32(e, v.(uint32))
+	case Uint64Tag:
+		return AppendUint64(e, v.(uint64))
+	case Int8Tag:
+		return AppendInt8(e, v.(int8))
+	case Int16Tag:
+		return AppendInt16(e, v.(int16))
+	case Int32Tag:
+		return AppendInt32(e, v.(int32))
+	case Int64Tag:
+		return AppendInt64(e, v.(int64))
+	case Float32Tag:
+		return AppendFloat32(e, v.(float32))
+	case Float64Tag:
+		return AppendFloat64(e, v.(float64))
+	case Complex64Tag:
+		return AppendComplex64(e, v.(complex64))
+	case Complex128Tag:
+		return AppendComplex128(e, v.(complex128))
+	case BlobTag:
+		return AppendBlob(e, v.([]byte))
+	case StringTag:
+		return AppendString(e, v.(string))
+	case DIRTag:
+		return AppendDIR(e, v.(dir.DIR))
+	case VarTimeTag:
+		return AppendVarTime(e, v.(time.Time))
+	case TimeTag:
+		return AppendTime(e, v.(time.Time))
+	}
+	panic("unsupported tag " + t.String())
+}
+
+// readValue reads the value with the encoding identified by t. When skip
+// is true, the value is skipped and nil is returned.
+func readValue(r *Reader, t TagT, n int, skip bool) any {
+	if skip {
+		switch t {
+		case NoneTag, VarUintTag:
+			r.SkipVarUint()
+		case BoolTag:
+			r.SkipBool()
+		case ByteTag:
+			r.SkipByte()
+		case BytesTag:
+			r.SkipBytes(uint64(n))
+		case VarIntTag:
+			r.SkipVarInt()
+		case VarUint64Tag:
+			r.SkipVarUint64()
+		case VarInt64Tag:
+			r.SkipVarInt64()
+		case SizeTag:
+			r.SkipSize()
+		case VarFloatTag:
+			r.SkipVarFloat()
+		case VarComplexTag:
+			r.SkipVarComplex()
+		case Uint8Tag:
+			r.SkipUint8()
+		case Uint16Tag:
+			r.SkipUint16()
+		case Uint32Tag:
+			r.SkipUint32()
+		case Uint64Tag:
+			r.SkipUint64()
+		case Int8Tag:
+			r.SkipInt8()
+		case Int16Tag:
+			r.SkipInt16()
+		case Int32Tag:
+			r.SkipInt32()
+		case Int64Tag:
+			r.SkipInt64()
+		case Float32Tag:
+			r.SkipFloat32()
+		case Float64Tag:
+			r.SkipFloat64()
+		case Complex64Tag:
+			r.SkipComplex64()
+		case Complex128Tag:
+			r.SkipComplex128()
+		case BlobTag:
+			r.SkipBlob(10)
+		case StringTag:
+			r.SkipString(10)
+		case DIRTag:
+			r.SkipDIR()
+		case VarTimeTag:
+			r.SkipVarTime()
+		case TimeTag:
+			r.SkipTime()
+		}
+		return nil
+	}
+	switch t {
+	case NoneTag:
+		return r.Tag()
+	case BoolTag:
+		return r.Bool()
+	case ByteTag:
+		return r.Byte()
+	case BytesTag:
+		return r.Bytes(n)
+	case VarUintTag:
+		return r.VarUint()
+	case VarIntTag:
+		return r.VarInt()
+	case VarUint64Tag:
+		return r.VarUint64()
+	case VarInt64Tag:
+		return r.VarInt64()
+	case SizeTag:
+		return r.Size()
+	case VarFloatTag:
+		return r.VarFloat()
+	case VarComplexTag:
+		return r.VarComplex()
+	case Uint8Tag:
+		return r.Uint8()
+	case Uint16Tag:
+		return r.Uint16()
+	case Uint32Tag:
+		return r.Uint32()
+	case Uint64Tag:
+		return r.Uint64()
+	case Int8Tag:
+		return r.Int8()
+	case Int16Tag:
+		return r.Int16()
+	case Int32Tag:
+		return r.Int32()
+	case Int64Tag:
+		return r.Int64()
+	case Float32Tag:
+		return r.Float32()
+	case Float64Tag:
+		return r.Float64()
+	case Complex64Tag:
+		return r.Complex64()
+	case Complex128Tag:
+		return r.Complex128()
+	case BlobTag:
+		return r.Blob(10)
+	case StringTag:
+		return r.String(10)
+	case DIRTag:
+		return r.DIR()
+	case VarTimeTag:
+		return r.VarTime()
+	case TimeTag:
+		return r.Time()
+	}
+	return nil
+}
+
+func TestReader(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		for i, test := range readerValues {
+			b := appendReaderValue(nil, test.t, test.v)
+			n := len(b)
+			r := NewReader(b)
+			v := readValue(r, test.t, n, skip)
+			if r.Err() != nil {
+				t.Errorf("%3d unexpected error: %v", i, r.Err())
+				continue
+			}
+			if r.Len() != 0 || r.Offset() != n {
+				t.Errorf("%3d expect all %d bytes decoded, got %d", i, n, r.Offset())
+			}
+			if !skip {
+				if tm, ok := test.v.(time.Time); ok {
+					if !tm.Equal(v.(time.Time)) || tm.Format(time.RFC3339) != v.(time.Time).Format(time.RFC3339) {
+						t.Errorf("%3d expected value %v, got %v", i, test.v, v)
+					}
+				} else if !reflect.DeepEqual(v, test.v) {
+					t.Errorf("%3d expected value %#v, got %#v", i, test.v, v)
+				}
+			}
+
+			// truncated data
+			for l := 0; l < n; l++ {
+				r.Reset(b[:l])
+				readValue(r, test.t, n, skip)
+				var err *DecodeError
+				if !errors.As(r.Err(), &err) {
+					t.Errorf("%3d expect DecodeError for length %d, got %v", i, l, r.Err())
+					continue
+				}
+				if err.Offset != 0 {
+					t.Errorf("%3d expect offset 0 for length %d, got %d", i, l, err.Offset)
+				}
+				if !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Errorf("%3d expect ErrUnexpectedEOF for length %d, got %v", i, l, err)
+				}
+			}
+		}
+	}
+}
+
+func TestReaderSequence(t *testing.T) {
+	var e Encoder
+	for _, test := range readerValues {
+		e = appendReaderValue(e, test.t, test.v)
+	}
+	r := NewReader(e)
+	for i, test := range readerValues {
+		off := r.Offset()
+		if i%2 == 0 {
+			readValue(r, test.t, 2, true)
+		} else {
+			readValue(r, test.t, 2, false)
+		}
+		if r.Err() != nil {
+			t.Fatalf("%3d unexpected error: %v", i, r.Err())
+		}
+		if sz := len(appendReaderValue(nil, test.t, test.v)); r.Offset()-off != sz {
+			t.Errorf("%3d expect size %d, got %d", i, sz, r.Offset()-off)
+		}
+	}
+	if r.Len() != 0 || len(r.Peek()) != 0 {
+		t.Errorf("expect no bytes left, got %d", r.Len())
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		i   []byte
+		f   func(r *Reader) any
+		e   string
+		err error
+	}{
+		// 0
+		{
+			i: []byte{8, 1, 2, 3, 4, 5, 6, 7, 8},
+			f: func(r *Reader) any { return r.DIR() }, err: dir.ErrInvalid,
+			e: "IDR decoder: DIR at offset 0: invalid dir: too many identifiers",
+		},
+		{
+			i: []byte{0x40, 1},
+			f: func(r *Reader) any { return r.DIR() }, err: ErrTooBig,
+			e: "IDR decoder: DIR at offset 0: data too big",
+		},
+		{
+			i: []byte{0xb, 0xc0, 0xea, 0xfe, 0xd1, 0xc, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1},
+			f: func(r *Reader) any { return r.VarTime() }, err: ErrInvalid,
+			e: "IDR decoder: VarTime at offset 0: invalid encoding",
+		},
+		{
+			i: []byte{0x2, 0xc0, 0xea},
+			f: func(r *Reader) any { return r.VarTime() }, err: ErrInvalid,
+			e: "IDR decoder: VarTime at offset 0: invalid encoding",
+		},
+		{
+			i: []byte{4, 1, 2, 3, 4},
+			f: func(r *Reader) any { return r.Blob(3) }, err: ErrTooBig,
+			e: "IDR decoder: Blob at offset 0: data too big",
+		},
+		// 5
+		{
+			i: []byte{4, 1, 2, 3, 4},
+			f: func(r *Reader) any { return r.String(3) }, err: ErrTooBig,
+			e: "IDR decoder: String at offset 0: data too big",
+		},
+		{
+			i: []byte{1, 4, 1, 2},
+			f: func(r *Reader) any { r.Byte(); return r.Blob(10) }, err: io.ErrUnexpectedEOF,
+			e: "IDR decoder: Blob at offset 1: unexpected EOF",
+		},
+		{
+			i: []byte{1, 2},
+			f: func(r *Reader) any { return r.Bytes(-1) }, err: ErrInvalid,
+			e: "IDR decoder: Bytes at offset 0: invalid encoding",
+		},
+		{
+			i: []byte{1, 2},
+			f: func(r *Reader) any { r.SkipBytes(3); return nil }, err: io.ErrUnexpectedEOF,
+			e: "IDR decoder: Bytes at offset 0: unexpected EOF",
+		},
+	}
+	for i, test := range tests {
+		r := NewReader(test.i)
+		test.f(r)
+		if r.Err() == nil {
+			t.Errorf("%d expect error %q", i, test.e)
+			continue
+		}
+		if r.Err().Error() != test.e {
+			t.Errorf("%d expect error %q, got %q", i, test.e, r.Err())
+		}
+		if !errors.Is(r.Err(), test.err) {
+			t.Errorf("%d expect error is %v", i, test.err)
+		}
+	}
+
+	// the first error is sticky
+	r := NewReader([]byte{1, 2, 3})
+	r.Uint32()
+	if v := r.Uint8(); v != 0 {
+		t.Errorf("expect zero value after error, got %d", v)
+	}
+	if r.Offset() != 0 || r.Err().Error() != "IDR decoder: Uint32 at offset 0: unexpected EOF" {
+		t.Errorf("expect first error retained, got %v", r.Err())
+	}
+}
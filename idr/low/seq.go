@@ -0,0 +1,266 @@
+package low
+
+import "math"
+
+// Sequences are compact encodings of int64 and uint64 slices whose
+// elements are close to their predecessor, like time series or sorted
+// identifiers. A sequence is prefixed with the byte size of its content
+// so that it can be skipped without decoding it. The content is the
+// element count followed by the first element, encoded as VarInt64 for
+// int64 and VarUint64 for uint64, and by the differences between the
+// subsequent elements encoded as follows:
+//
+//	DeltaInt64, DeltaUint64:  difference as VarInt64
+//	MonoInt64, MonoUint64:    difference as VarUint64
+//	DeltaDeltaInt64:          difference minus the previous one as VarInt64
+//
+// The differences are computed modulo 2^64 so that any slice can be
+// encoded, but the encoding is only compact when the differences are
+// small. The Mono sequences are for non-decreasing slices, and the
+// DeltaDelta sequence for regularly spaced timestamps.
+
+// SeqInt is the type set of the elements of sequences.
+type SeqInt interface {
+	int64 | uint64
+}
+
+// seqState holds the previous element and difference of a sequence of
+// type t.
+type seqState[T SeqInt] struct {
+	t     TagT
+	prev  T
+	delta T
+}
+
+// signed returns true if the element at index i is encoded as VarInt64
+// and false if it is encoded as VarUint64.
+func (s *seqState[T]) signed(i int) bool {
+	if i == 0 {
+		return ^T(0) < 0
+	}
+	return s.t != MonoInt64Tag && s.t != MonoUint64Tag
+}
+
+// encode returns the number encoding x, the element at index i.
+func (s *seqState[T]) encode(i int, x T) T {
+	v := x
+	switch {
+	case i == 0:
+	case s.t == DeltaDeltaInt64Tag:
+		d := x - s.prev
+		v = d - s.delta
+		s.delta = d
+	default:
+		v = x - s.prev
+	}
+	s.prev = x
+	return v
+}
+
+// decode returns the element at index i encoded by the number v.
+func (s *seqState[T]) decode(i int, v T) T {
+	x := v
+	switch {
+	case i == 0:
+	case s.t == DeltaDeltaInt64Tag:
+		s.delta += v
+		x = s.prev + s.delta
+	default:
+		x = s.prev + v
+	}
+	s.prev = x
+	return x
+}
+
+// deltaTag returns the tag of the Delta sequences of elements of type T.
+func deltaTag[T SeqInt]() TagT {
+	if ^T(0) < 0 {
+		return DeltaInt64Tag
+	}
+	return DeltaUint64Tag
+}
+
+// monoTag returns the tag of the Mono sequences of elements of type T.
+func monoTag[T SeqInt]() TagT {
+	if ^T(0) < 0 {
+		return MonoInt64Tag
+	}
+	return MonoUint64Tag
+}
+
+// seqContentSize returns the byte size of the content of the sequence v
+// of type t.
+func seqContentSize[T SeqInt](v []T, t TagT) int {
+	s := seqState[T]{t: t}
+	n := SizeVarUint(uint(len(v)))
+	for i, x := range v {
+		if w := s.encode(i, x); s.signed(i) {
+			n += SizeVarInt64(int64(w))
+		} else {
+			n += SizeVarUint64(uint64(w))
+		}
+	}
+	return n
+}
+
+// sizeSeq returns the byte size of the sequence v of type t.
+func sizeSeq[T SeqInt](v []T, t TagT) int {
+	n := seqContentSize(v, t)
+	return SizeVarUint64(uint64(n)) + n
+}
+
+// appendSeq appends the sequence v of type t.
+func appendSeq[T SeqInt](e Encoder, v []T, t TagT) Encoder {
+	e = AppendSize(e, uint64(seqContentSize(v, t)))
+	e = AppendVarUint(e, uint(len(v)))
+	s := seqState[T]{t: t}
+	for i, x := range v {
+		if w := s.encode(i, x); s.signed(i) {
+			e = AppendVarInt64(e, int64(w))
+		} else {
+			e = AppendVarUint64(e, uint64(w))
+		}
+	}
+	return e
+}
+
+// seq returns the sequence of type t in front of the remaining bytes.
+// Panics if the element count exceeds the byte size of the content.
+func seq[T SeqInt](d Decoder, t TagT) (Decoder, []T) {
+	d, c := content(d)
+	c, n := VarUint64(c)
+	if n > uint64(len(c)) {
+		// each element is encoded in at least one byte
+		panic("IDR decoder: data too big")
+	}
+	x := make([]T, n)
+	s := seqState[T]{t: t}
+	for i := range x {
+		var w T
+		if s.signed(i) {
+			var v int64
+			c, v = VarInt64(c)
+			w = T(v)
+		} else {
+			var v uint64
+			c, v = VarUint64(c)
+			w = T(v)
+		}
+		x[i] = s.decode(i, w)
+	}
+	return d, x
+}
+
+// readSeq returns the next sequence of type t decoded with r. The
+// elements are accounted as allocated bytes.
+func readSeq[T SeqInt](r *Reader, t TagT) []T {
+	op := t.op()
+	b := r.blob(op, math.MaxUint64)
+	c := Reader{b: r.b[:r.off], off: r.off - len(b), err: r.err, depth: r.depth, lim: r.budget()}
+	n := c.count(op, 1)
+	if !c.Alloc(n, 8) {
+		r.adopt(&c)
+		return nil
+	}
+	x := make([]T, n)
+	s := seqState[T]{t: t}
+	for i := range x {
+		v := c.varUint64(op)
+		if s.signed(i) {
+			v = uint64(unzigzag(v))
+		}
+		x[i] = s.decode(i, T(v))
+	}
+	r.adopt(&c)
+	if r.err != nil {
+		return nil
+	}
+	return x
+}
+
+// unzigzag returns the int64 encoded by x as a VarInt64.
+func unzigzag(x uint64) int64 {
+	if x&1 != 0 {
+		return int64(^(x >> 1))
+	}
+	return int64(x >> 1)
+}
+
+// SizeDeltaSeq returns the size of the Delta sequence v.
+func SizeDeltaSeq[T SeqInt](v []T) int {
+	return sizeSeq(v, deltaTag[T]())
+}
+
+// SizeMonoSeq returns the size of the Mono sequence v.
+func SizeMonoSeq[T SeqInt](v []T) int {
+	return sizeSeq(v, monoTag[T]())
+}
+
+// SizeDeltaDeltaSeq returns the size of the DeltaDelta sequence v.
+func SizeDeltaDeltaSeq(v []int64) int {
+	return sizeSeq(v, DeltaDeltaInt64Tag)
+}
+
+// AppendDeltaSeq appends v as a Delta sequence whose differences are
+// encoded as VarInt64.
+func AppendDeltaSeq[T SeqInt](e Encoder, v []T) Encoder {
+	return appendSeq(e, v, deltaTag[T]())
+}
+
+// AppendMonoSeq appends v as a Mono sequence whose differences are
+// encoded as VarUint64. The encoding is compact when v is non-decreasing.
+func AppendMonoSeq[T SeqInt](e Encoder, v []T) Encoder {
+	return appendSeq(e, v, monoTag[T]())
+}
+
+// AppendDeltaDeltaSeq appends v as a DeltaDelta sequence whose
+// differences of differences are encoded as VarInt64. The encoding is
+// compact when the elements, like timestamps, are nearly equally spaced.
+func AppendDeltaDeltaSeq(e Encoder, v []int64) Encoder {
+	return appendSeq(e, v, DeltaDeltaInt64Tag)
+}
+
+// DeltaSeq returns the Delta sequence in front of the remaining bytes.
+func DeltaSeq[T SeqInt](d Decoder) (Decoder, []T) {
+	return seq[T](d, deltaTag[T]())
+}
+
+// MonoSeq returns the Mono sequence in front of the remaining bytes.
+func MonoSeq[T SeqInt](d Decoder) (Decoder, []T) {
+	return seq[T](d, monoTag[T]())
+}
+
+// DeltaDeltaSeq returns the DeltaDelta sequence in front of the remaining
+// bytes.
+func DeltaDeltaSeq(d Decoder) (Decoder, []int64) {
+	return seq[int64](d, DeltaDeltaInt64Tag)
+}
+
+// SkipSeq skips a sequence value.
+func SkipSeq(d Decoder) Decoder {
+	d, _ = content(d)
+	return d
+}
+
+// ReadDeltaSeq returns the next Delta sequence decoded with r. The
+// elements are accounted as allocated bytes.
+func ReadDeltaSeq[T SeqInt](r *Reader) []T {
+	return readSeq[T](r, deltaTag[T]())
+}
+
+// ReadMonoSeq returns the next Mono sequence decoded with r. The elements
+// are accounted as allocated bytes.
+func ReadMonoSeq[T SeqInt](r *Reader) []T {
+	return readSeq[T](r, monoTag[T]())
+}
+
+// ReadDeltaDeltaSeq returns the next DeltaDelta sequence decoded with r.
+// The elements are accounted as allocated bytes.
+func ReadDeltaDeltaSeq(r *Reader) []int64 {
+	return readSeq[int64](r, DeltaDeltaInt64Tag)
+}
+
+// SkipSeq skips a sequence value of tag t.
+func (r *Reader) SkipSeq(t TagT) {
+	r.blob(t.op(), math.MaxUint64)
+}
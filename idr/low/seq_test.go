@@ -0,0 +1,153 @@
+package low
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// testSeq checks the encoding and decoding of the slice v as a sequence of
+// type t.
+func testSeq[T SeqInt](t *testing.T, i int, tag TagT, v []T) {
+	t.Helper()
+	e := AppendValue(nil, tag, v)
+	if n := 1 + sizeSeq(v, tag); n != len(e) {
+		t.Errorf("%d %v expect size %d, got %d", i, tag, len(e), n)
+	}
+	d, tt, x := Value(Decoder(append(e, 1)))
+	if len(d) != 1 || tt != tag || !reflect.DeepEqual(x, v) {
+		t.Errorf("%d %v expect %v, got %v", i, tag, v, x)
+	}
+	if d = SkipValue(Decoder(append(e, 1))[1:], tag); len(d) != 1 {
+		t.Errorf("%d %v expect 1 byte left, got %d", i, tag, len(d))
+	}
+	r := NewReader(e)
+	if x := r.Value(r.Tag()); r.Err() != nil || r.Len() != 0 || !reflect.DeepEqual(x, v) {
+		t.Errorf("%d %v expect %v, got %v (%v)", i, tag, v, x, r.Err())
+	}
+	r = NewReader(e)
+	if r.SkipValue(r.Tag()); r.Err() != nil || r.Len() != 0 {
+		t.Errorf("%d %v expect value skipped, got %d bytes left (%v)", i, tag, r.Len(), r.Err())
+	}
+	if err := CheckCanonical(e); err != nil {
+		t.Errorf("%d %v unexpected error: %v", i, tag, err)
+	}
+}
+
+func TestSeq(t *testing.T) {
+	tests := [][]int64{
+		// 0
+		{},
+		{math.MinInt64},
+		{-5, -3, 0, 7, 7, 1000},
+		{1000, 10, -1000, 3},
+		{math.MinInt64, math.MaxInt64, math.MinInt64, 0},
+	}
+	for i, v := range tests {
+		testSeq(t, i, DeltaInt64Tag, v)
+		testSeq(t, i, MonoInt64Tag, v)
+		testSeq(t, i, DeltaDeltaInt64Tag, v)
+		u := make([]uint64, len(v))
+		for j := range v {
+			u[j] = uint64(v[j])
+		}
+		testSeq(t, i, DeltaUint64Tag, u)
+		testSeq(t, i, MonoUint64Tag, u)
+	}
+
+	v := []int64{1000, 1010, 1020, 1031}
+	exp := AppendVarUint64(AppendVarUint64(nil, 6), 4)
+	for _, x := range []int64{1000, 10, 0, 1} {
+		exp = AppendVarInt64(exp, x)
+	}
+	if e := AppendDeltaDeltaSeq(nil, v); !bytes.Equal(e, exp) || SizeDeltaDeltaSeq(v) != len(exp) {
+		t.Errorf("expect %x, got %x", exp, e)
+	}
+	if d, x := DeltaDeltaSeq(Decoder(exp)); len(d) != 0 || !reflect.DeepEqual(x, v) {
+		t.Errorf("expect %v, got %v", v, x)
+	}
+	if x := ReadDeltaDeltaSeq(NewReader(exp)); !reflect.DeepEqual(x, v) {
+		t.Errorf("expect %v, got %v", v, x)
+	}
+
+	// sorted identifiers and timestamps are encoded with 1 byte per element
+	ids := make([]uint64, 1000)
+	stamps := make([]int64, 1000)
+	for i := range ids {
+		ids[i] = 1<<40 + uint64(i)*3
+		stamps[i] = 1714566600e9 + int64(i)*1e9
+	}
+	e := AppendMonoSeq(nil, ids)
+	if n := SizeMonoSeq(ids); n != len(e) || n > 1010 {
+		t.Errorf("expect size %d of at most 1010 bytes, got %d", len(e), n)
+	}
+	if d, x := MonoSeq[uint64](Decoder(e)); len(d) != 0 || !reflect.DeepEqual(x, ids) {
+		t.Errorf("expect identifiers decoded")
+	}
+	if x := ReadMonoSeq[uint64](NewReader(e)); !reflect.DeepEqual(x, ids) {
+		t.Errorf("expect identifiers decoded")
+	}
+	e = AppendDeltaDeltaSeq(nil, stamps)
+	if n := SizeDeltaDeltaSeq(stamps); n != len(e) || n > 1020 || n >= SizeDeltaSeq(stamps) {
+		t.Errorf("expect size %d of at most 1020 bytes, got %d", len(e), n)
+	}
+	e = AppendDeltaSeq(nil, stamps)
+	if d, x := DeltaSeq[int64](Decoder(e)); len(d) != 0 || !reflect.DeepEqual(x, stamps) {
+		t.Errorf("expect timestamps decoded")
+	}
+	if x := ReadDeltaSeq[int64](NewReader(e)); !reflect.DeepEqual(x, stamps) {
+		t.Errorf("expect timestamps decoded")
+	}
+	if d := SkipSeq(Decoder(e)); len(d) != 0 {
+		t.Errorf("expect sequence skipped, got %d bytes left", len(d))
+	}
+}
+
+func TestSeqErrors(t *testing.T) {
+	tests := []struct {
+		b   []byte
+		err error
+		off int
+	}{
+		// 0
+		{b: []byte{byte(DeltaInt64Tag), 3, 2, 1}, err: io.ErrUnexpectedEOF, off: 1},
+		{b: []byte{byte(DeltaInt64Tag), 2, 2, 1}, err: ErrInvalid, off: 2},
+		{b: []byte{byte(MonoUint64Tag), 3, 2, 1, 0x80}, err: io.ErrUnexpectedEOF, off: 4},
+		{b: []byte{byte(MonoUint64Tag), 3, 1, 1, 0}, err: ErrNotCanonical, off: 4},
+		{b: []byte{byte(DeltaDeltaInt64Tag), 3, 1, 0x81, 0}, err: ErrNotCanonical, off: 3},
+	}
+	for i, test := range tests {
+		var err error
+		if test.err == ErrNotCanonical {
+			err = CheckCanonical(test.b)
+		} else {
+			r := NewReader(test.b)
+			r.Value(r.Tag())
+			err = r.Err()
+		}
+		var de *DecodeError
+		if !errors.Is(err, test.err) || !errors.As(err, &de) || de.Offset != test.off {
+			t.Errorf("%d expect %v at offset %d, got %v", i, test.err, test.off, err)
+		}
+	}
+
+	r := NewLimitedReader(AppendDeltaSeq(nil, []int64{1, 2}), Limits{MaxAlloc: 15})
+	if ReadDeltaSeq[int64](r); !errors.Is(r.Err(), ErrAllocLimit) {
+		t.Errorf("expect ErrAllocLimit, got %v", r.Err())
+	}
+	r = NewReader([]byte{3, 1})
+	if r.SkipSeq(MonoUint64Tag); r.Err() == nil || r.Err().Error() != "IDR decoder: MonoUint64 at offset 0: unexpected EOF" {
+		t.Errorf("expect MonoUint64 error, got %v", r.Err())
+	}
+	huge := append([]byte{9}, AppendVarUint64(nil, 1<<60)...)
+	if !doesPanic(func() { DeltaSeq[int64](Decoder(huge)) }) {
+		t.Error("expect panic with a huge element count")
+	}
+	r = NewReader(huge)
+	if ReadDeltaSeq[int64](r); !errors.Is(r.Err(), ErrInvalid) {
+		t.Errorf("expect ErrInvalid, got %v", r.Err())
+	}
+}
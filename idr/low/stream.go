@@ -0,0 +1,521 @@
+package low
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+)
+
+// StreamReader is a low level IDR decoder reading the encoded values from
+// an io.Reader. The bytes are read on demand. Blob contents are returned
+// as an io.Reader so that they don't need to be held in memory. Errors
+// are handled as with Reader.
+type StreamReader struct {
+	r    *bufio.Reader
+	lim  budget
+	off  int64
+	err  error
+	blob blobReader
+	buf  [maxValueSize]byte
+}
+
+// messageReader reads at most n bytes from r. Reading more returns the
+// error ErrTooBig.
+type messageReader struct {
+	r io.Reader
+	n uint64
+}
+
+// Read implements io.Reader.
+func (m *messageReader) Read(p []byte) (int, error) {
+	if m.n == 0 {
+		return 0, ErrTooBig
+	}
+	if uint64(len(p)) > m.n {
+		p = p[:m.n]
+	}
+	n, err := m.r.Read(p)
+	m.n -= uint64(n)
+	return n, err
+}
+
+// blobReader reads the content of the last blob returned by a
+// StreamReader. It returns io.EOF when the blob has been read.
+type blobReader struct {
+	s     *StreamReader
+	n     int64
+	start int64
+}
+
+// Read implements io.Reader.
+func (b *blobReader) Read(p []byte) (int, error) {
+	if b.n <= 0 {
+		return 0, io.EOF
+	}
+	if b.s.err != nil {
+		return 0, b.s.err
+	}
+	if int64(len(p)) > b.n {
+		p = p[:b.n]
+	}
+	n, err := b.s.r.Read(p)
+	b.n -= int64(n)
+	b.s.off += int64(n)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		b.s.fail("Blob", b.start, err)
+		return n, b.s.err
+	}
+	return n, nil
+}
+
+// NewStreamReader returns a StreamReader reading from r with the given
+// limits. Reading more than MaxMessageSize bytes from r fails with the
+// error ErrTooBig.
+func NewStreamReader(r io.Reader, limits Limits) *StreamReader {
+	limits = limits.withDefaults()
+	m := &messageReader{r: r, n: limits.MaxMessageSize}
+	s := &StreamReader{r: bufio.NewReader(m), lim: budget{Limits: limits}}
+	s.blob.s = s
+	return s
+}
+
+// Err returns the first error met, or nil.
+func (s *StreamReader) Err() error {
+	return s.err
+}
+
+// Offset returns the byte offset of the next value to decode.
+func (s *StreamReader) Offset() int64 {
+	return s.off
+}
+
+// fail records the error err for the value of type op at offset off when
+// no error was recorded yet.
+func (s *StreamReader) fail(op string, off int64, err error) {
+	if s.err == nil {
+		s.err = &DecodeError{Op: op, Offset: int(off), Err: err}
+	}
+}
+
+// start skips the unread content of the last blob and returns true if
+// no error occurred.
+func (s *StreamReader) start() bool {
+	if s.blob.n > 0 && s.err == nil {
+		io.Copy(io.Discard, &s.blob)
+	}
+	return s.err == nil
+}
+
+// read reads n bytes into the buffer b and returns true if no error
+// occurred. A truncated value starting at offset start is reported.
+func (s *StreamReader) read(op string, start int64, b []byte) bool {
+	n, err := io.ReadFull(s.r, b)
+	s.off += int64(n)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		s.fail(op, start, err)
+		return false
+	}
+	return true
+}
+
+// fixed returns the next n bytes in the internal buffer.
+func (s *StreamReader) fixed(op string, n int) []byte {
+	if !s.start() {
+		return nil
+	}
+	b := s.buf[:n]
+	if !s.read(op, s.off, b) {
+		return nil
+	}
+	return b
+}
+
+// varUint64 returns the next VarUint64 value.
+func (s *StreamReader) varUint64(op string) uint64 {
+	if !s.start() {
+		return 0
+	}
+	return s.readVarUint64(op, s.off)
+}
+
+// readVarUint64 reads the next VarUint64 value of the value of type op
+// starting at offset start.
+func (s *StreamReader) readVarUint64(op string, start int64) uint64 {
+	if s.err != nil {
+		return 0
+	}
+	for i := 0; i < 9; i++ {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			s.fail(op, start, err)
+			return 0
+		}
+		s.off++
+		s.buf[i] = c
+		if c < 0x80 {
+			break
+		}
+	}
+	_, v := VarUint64(Decoder(s.buf[:]))
+	return v
+}
+
+// size returns the next size and checks that it doesn't exceed max.
+func (s *StreamReader) size(op string, max uint64) (uint64, bool) {
+	start := s.off
+	n := s.varUint64(op)
+	if s.err != nil {
+		return 0, false
+	}
+	if n > max {
+		s.fail(op, start, ErrTooBig)
+		return 0, false
+	}
+	return n, true
+}
+
+// sized reads the next size prefixed value in the internal buffer
+// including its size prefix, and decodes it with the Reader method f.
+func (s *StreamReader) sized(op string, max uint64, f func(r *Reader)) {
+	start := s.off
+	n, ok := s.size(op, max)
+	if !ok {
+		return
+	}
+	s.buf[0] = byte(n)
+	if !s.read(op, start, s.buf[1:1+n]) {
+		return
+	}
+	r := Reader{b: s.buf[:1+n]}
+	f(&r)
+	if r.err != nil {
+		s.fail(op, start, r.err.(*DecodeError).Err)
+	}
+}
+
+// Byte returns the next byte.
+func (s *StreamReader) Byte() byte {
+	if b := s.fixed("Byte", 1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+// Bool returns the next bool.
+func (s *StreamReader) Bool() bool {
+	if b := s.fixed("Bool", 1); b != nil {
+		return b[0] != 0
+	}
+	return false
+}
+
+// Bytes returns a copy of the next n bytes. The copy is accounted as
+// allocated bytes.
+func (s *StreamReader) Bytes(n int) []byte {
+	if !s.start() {
+		return nil
+	}
+	if n < 0 {
+		s.fail("Bytes", s.off, ErrInvalid)
+		return nil
+	}
+	if !s.lim.charge(uint64(n)) {
+		s.fail("Bytes", s.off, ErrAllocLimit)
+		return nil
+	}
+	b := make([]byte, n)
+	if !s.read("Bytes", s.off, b) {
+		return nil
+	}
+	return b
+}
+
+// VarUint64 returns the next compact encoded uint64.
+func (s *StreamReader) VarUint64() uint64 {
+	return s.varUint64("VarUint64")
+}
+
+// VarUint returns the next compact encoded uint.
+func (s *StreamReader) VarUint() uint {
+	return uint(s.varUint64("VarUint"))
+}
+
+// Tag returns the next TagT.
+func (s *StreamReader) Tag() TagT {
+	return TagT(s.varUint64("Tag"))
+}
+
+// Size returns the next size value.
+func (s *StreamReader) Size() uint64 {
+	return s.varUint64("Size")
+}
+
+// VarInt64 returns the next compact encoded int64.
+func (s *StreamReader) VarInt64() int64 {
+	x := s.varUint64("VarInt64")
+	if x&1 != 0 {
+		return int64(^(x >> 1))
+	}
+	return int64(x >> 1)
+}
+
+// VarInt returns the next compact encoded int.
+func (s *StreamReader) VarInt() int {
+	return int(s.VarInt64())
+}
+
+// VarFloat returns the next compact encoded float64.
+func (s *StreamReader) VarFloat() float64 {
+	return math.Float64frombits(bits.ReverseBytes64(s.varUint64("VarFloat")))
+}
+
+// VarComplex returns the next compact encoded complex128.
+func (s *StreamReader) VarComplex() complex128 {
+	if !s.start() {
+		return 0
+	}
+	start := s.off
+	v1 := math.Float64frombits(bits.ReverseBytes64(s.readVarUint64("VarComplex", start)))
+	v2 := math.Float64frombits(bits.ReverseBytes64(s.readVarUint64("VarComplex", start)))
+	if s.err != nil {
+		return 0
+	}
+	return complex(v1, v2)
+}
+
+// Uint8 returns the next uint8.
+func (s *StreamReader) Uint8() uint8 {
+	if b := s.fixed("Uint8", 1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+// Uint16 returns the next uint16.
+func (s *StreamReader) Uint16() uint16 {
+	if b := s.fixed("Uint16", 2); b != nil {
+		_, v := Uint16(b)
+		return v
+	}
+	return 0
+}
+
+// Uint32 returns the next uint32.
+func (s *StreamReader) Uint32() uint32 {
+	if b := s.fixed("Uint32", 4); b != nil {
+		_, v := Uint32(b)
+		return v
+	}
+	return 0
+}
+
+// Uint64 returns the next uint64.
+func (s *StreamReader) Uint64() uint64 {
+	if b := s.fixed("Uint64", 8); b != nil {
+		_, v := Uint64(b)
+		return v
+	}
+	return 0
+}
+
+// Int8 returns the next int8.
+func (s *StreamReader) Int8() int8 {
+	return int8(s.Uint8())
+}
+
+// Int16 returns the next int16.
+func (s *StreamReader) Int16() int16 {
+	if b := s.fixed("Int16", 2); b != nil {
+		_, v := Int16(b)
+		return v
+	}
+	return 0
+}
+
+// Int32 returns the next int32.
+func (s *StreamReader) Int32() int32 {
+	if b := s.fixed("Int32", 4); b != nil {
+		_, v := Int32(b)
+		return v
+	}
+	return 0
+}
+
+// Int64 returns the next int64.
+func (s *StreamReader) Int64() int64 {
+	if b := s.fixed("Int64", 8); b != nil {
+		_, v := Int64(b)
+		return v
+	}
+	return 0
+}
+
+// Float32 returns the next float32.
+func (s *StreamReader) Float32() float32 {
+	if b := s.fixed("Float32", 4); b != nil {
+		_, v := Float32(b)
+		return v
+	}
+	return 0
+}
+
+// Float64 returns the next float64.
+func (s *StreamReader) Float64() float64 {
+	if b := s.fixed("Float64", 8); b != nil {
+		_, v := Float64(b)
+		return v
+	}
+	return 0
+}
+
+// Complex64 returns the next complex64.
+func (s *StreamReader) Complex64() complex64 {
+	if b := s.fixed("Complex64", 8); b != nil {
+		_, v := Complex64(b)
+		return v
+	}
+	return 0
+}
+
+// Complex128 returns the next complex128.
+func (s *StreamReader) Complex128() complex128 {
+	if b := s.fixed("Complex128", 16); b != nil {
+		_, v := Complex128(b)
+		return v
+	}
+	return 0
+}
+
+// Blob returns a reader of the content of the next blob and its size. The
+// size may not exceed the MaxBlobSize limit. The returned reader is valid
+// until the next value is decoded, and the unread content is then skipped.
+func (s *StreamReader) Blob() (io.Reader, int64) {
+	if !s.start() {
+		return &s.blob, 0
+	}
+	start := s.off
+	n, ok := s.size("Blob", s.lim.MaxBlobSize)
+	if !ok {
+		return &s.blob, 0
+	}
+	s.blob.n = int64(n)
+	s.blob.start = start
+	return &s.blob, int64(n)
+}
+
+// String returns the next string. The string size may not exceed the
+// MaxStringSize limit and it must be valid UTF-8 with the ValidUTF8
+// limit. The string is accounted as allocated bytes.
+func (s *StreamReader) String() string {
+	if !s.start() {
+		return ""
+	}
+	start := s.off
+	n, ok := s.size("String", s.lim.MaxStringSize)
+	if !ok {
+		return ""
+	}
+	if !s.lim.charge(n) {
+		s.fail("String", start, ErrAllocLimit)
+		return ""
+	}
+	b := make([]byte, n)
+	if !s.read("String", start, b) {
+		return ""
+	}
+	if s.lim.ValidUTF8 {
+		if i := invalidUTF8(b); i >= 0 {
+			s.fail("String", s.off-int64(n)+int64(i), ErrInvalidUTF8)
+			return ""
+		}
+	}
+	// b is not retained and may be shared without copy
+	return unsafeString(b)
+}
+
+// DIR returns the next DIR.
+func (s *StreamReader) DIR() (v dir.DIR) {
+	if s.start() {
+		s.sized("DIR", dir.MaxBinaryLen, func(r *Reader) { v = r.DIR() })
+	}
+	return
+}
+
+// VarTime returns the next compact encoded time.
+func (s *StreamReader) VarTime() (v time.Time) {
+	if s.start() {
+		s.sized("VarTime", maxVarTimeSize, func(r *Reader) { v = r.VarTime() })
+	}
+	return
+}
+
+// Time returns the next time.
+func (s *StreamReader) Time() time.Time {
+	if b := s.fixed("Time", 16); b != nil {
+		_, v := Time(b)
+		return v
+	}
+	return time.Time{}
+}
+
+// Skip skips n bytes.
+func (s *StreamReader) Skip(n int64) {
+	if !s.start() {
+		return
+	}
+	start := s.off
+	m, err := s.r.Discard(int(n))
+	s.off += int64(m)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		s.fail("Bytes", start, err)
+	}
+}
+
+// SkipBlob skips a blob value.
+func (s *StreamReader) SkipBlob() {
+	s.Blob()
+}
+
+// SkipString skips a string value.
+func (s *StreamReader) SkipString() {
+	if !s.start() {
+		return
+	}
+	start := s.off
+	if n, ok := s.size("String", s.lim.MaxStringSize); ok {
+		s.blob.n = int64(n)
+		s.blob.start = start
+	}
+}
+
+// SkipVarUint64 skips a compact encoded value.
+func (s *StreamReader) SkipVarUint64() {
+	s.varUint64("VarUint64")
+}
+
+// SkipDIR skips a DIR value.
+func (s *StreamReader) SkipDIR() {
+	s.DIR()
+}
+
+// SkipVarTime skips a compact encoded time value.
+func (s *StreamReader) SkipVarTime() {
+	s.VarTime()
+}
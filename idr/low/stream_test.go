@@ -0,0 +1,246 @@
+package low
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// streamValue reads the value with the encoding identified by t.
+func streamValue(s *StreamReader, t TagT, n int) any {
+	switch t {
+	case NoneTag:
+		return s.Tag()
+	case BoolTag:
+		return s.Bool()
+	case ByteTag:
+		return s.Byte()
+	case BytesTag:
+		return s.Bytes(n)
+	case VarUintTag:
+		return s.VarUint()
+	case VarIntTag:
+		return s.VarInt()
+	case VarUint64Tag:
+		return s.VarUint64()
+	case VarInt64Tag:
+		return s.VarInt64()
+	case SizeTag:
+		return s.Size()
+	case VarFloatTag:
+		return s.VarFloat()
+	case VarComplexTag:
+		return s.VarComplex()
+	case Uint8Tag:
+		return s.Uint8()
+	case Uint16Tag:
+		return s.Uint16()
+	case Uint32Tag:
+		return s.Uint32()
+	case Uint64Tag:
+		return s.Uint64()
+	case Int8Tag:
+		return s.Int8()
+	case Int16Tag:
+		return s.Int16()
+	case Int32Tag:
+		return s.Int32()
+	case Int64Tag:
+		return s.Int64()
+	case Float32Tag:
+		return s.Float32()
+	case Float64Tag:
+		return s.Float64()
+	case Complex64Tag:
+		return s.Complex64()
+	case Complex128Tag:
+		return s.Complex128()
+	case BlobTag:
+		r, _ := s.Blob()
+		b, _ := io.ReadAll(r)
+		return b
+	case StringTag:
+		return s.String()
+	case DIRTag:
+		return s.DIR()
+	case VarTimeTag:
+		return s.VarTime()
+	case TimeTag:
+		return s.Time()
+	}
+	return nil
+}
+
+func TestStreamReader(t *testing.T) {
+	for i, test := range readerValues {
+		b := appendReaderValue(nil, test.t, test.v)
+		n := len(b)
+		s := NewStreamReader(iotest.OneByteReader(bytes.NewReader(b)), Limits{})
+		v := streamValue(s, test.t, n)
+		if s.Err() != nil {
+			t.Errorf("%3d unexpected error: %v", i, s.Err())
+			continue
+		}
+		if s.Offset() != int64(n) {
+			t.Errorf("%3d expect all %d bytes decoded, got %d", i, n, s.Offset())
+		}
+		if tm, ok := test.v.(time.Time); ok {
+			if !tm.Equal(v.(time.Time)) || tm.Format(time.RFC3339) != v.(time.Time).Format(time.RFC3339) {
+				t.Errorf("%3d expected value %v, got %v", i, test.v, v)
+			}
+		} else if !reflect.DeepEqual(v, test.v) {
+			t.Errorf("%3d expected value %#v, got %#v", i, test.v, v)
+		}
+
+		// truncated data
+		for l := 0; l < n; l++ {
+			s = NewStreamReader(bytes.NewReader(b[:l]), Limits{})
+			streamValue(s, test.t, n)
+			var err *DecodeError
+			if !errors.As(s.Err(), &err) {
+				t.Errorf("%3d expect DecodeError for length %d, got %v", i, l, s.Err())
+				continue
+			}
+			if err.Offset != 0 {
+				t.Errorf("%3d expect offset 0 for length %d, got %d", i, l, err.Offset)
+			}
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("%3d expect ErrUnexpectedEOF for length %d, got %v", i, l, err)
+			}
+		}
+	}
+}
+
+func TestStreamReaderString(t *testing.T) {
+	var e Encoder
+	for i := 0; i < 110; i++ {
+		e = AppendString(e, "hello world")
+	}
+	s := NewStreamReader(bytes.NewReader(e), Limits{})
+	_ = s.String()
+	allocs := testing.AllocsPerRun(100, func() {
+		if v := s.String(); v != "hello world" {
+			t.Errorf("expect hello world, got %q", v)
+		}
+	})
+	if allocs != 1 {
+		t.Errorf("expect 1 allocation per string, got %v", allocs)
+	}
+}
+
+func TestStreamReaderBlob(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789"), 1000)
+	e := AppendBlob(nil, big)
+	e = AppendString(e, "hello")
+	e = AppendBlob(e, big)
+	e = AppendBlob(e, big[:10])
+	e = AppendString(e, "world")
+	e = AppendUint16(e, 0x1234)
+
+	s := NewStreamReader(bytes.NewReader(e), Limits{})
+	r, n := s.Blob()
+	if n != int64(len(big)) {
+		t.Errorf("expect size %d, got %d", len(big), n)
+	}
+	buf := make([]byte, 100)
+	if _, err := io.ReadFull(r, buf); err != nil || !bytes.Equal(buf, big[:100]) {
+		t.Errorf("expect %q, got %q (%v)", big[:100], buf, err)
+	}
+	// the unread content of the blob is skipped
+	if v := s.String(); v != "hello" {
+		t.Errorf("expect %q, got %q", "hello", v)
+	}
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("expect EOF, got %d %v", n, err)
+	}
+	s.SkipBlob()
+	r, _ = s.Blob()
+	if b, err := io.ReadAll(r); err != nil || !bytes.Equal(b, big[:10]) {
+		t.Errorf("expect %q, got %q (%v)", big[:10], b, err)
+	}
+	s.SkipString()
+	if v := s.Uint16(); v != 0x1234 {
+		t.Errorf("expect 0x1234, got %#x", v)
+	}
+	if s.Err() != nil || s.Offset() != int64(len(e)) {
+		t.Errorf("expect offset %d, got %d (%v)", len(e), s.Offset(), s.Err())
+	}
+
+	// truncated blob content
+	s = NewStreamReader(bytes.NewReader(e[:50]), Limits{})
+	r, _ = s.Blob()
+	if _, err := io.ReadAll(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expect ErrUnexpectedEOF, got %v", err)
+	}
+	s = NewStreamReader(bytes.NewReader(e[:50]), Limits{})
+	s.SkipBlob()
+	s.Byte()
+	if !errors.Is(s.Err(), io.ErrUnexpectedEOF) {
+		t.Errorf("expect ErrUnexpectedEOF, got %v", s.Err())
+	}
+}
+
+func TestStreamReaderLimits(t *testing.T) {
+	// a hostile size prefix
+	e := AppendSize(nil, 1<<60)
+	e = append(e, "hello"...)
+
+	s := NewStreamReader(bytes.NewReader(e), Limits{})
+	if _ = s.String(); !errors.Is(s.Err(), ErrTooBig) {
+		t.Errorf("expect ErrTooBig, got %v", s.Err())
+	}
+	s = NewStreamReader(bytes.NewReader(e), Limits{})
+	if s.Blob(); !errors.Is(s.Err(), ErrTooBig) {
+		t.Errorf("expect ErrTooBig, got %v", s.Err())
+	}
+
+	e = AppendString(nil, "hello")
+	s = NewStreamReader(bytes.NewReader(e), Limits{MaxStringSize: 4})
+	if _ = s.String(); !errors.Is(s.Err(), ErrTooBig) {
+		t.Errorf("expect ErrTooBig, got %v", s.Err())
+	}
+	s = NewStreamReader(bytes.NewReader(e), Limits{MaxBlobSize: 4})
+	if s.Blob(); !errors.Is(s.Err(), ErrTooBig) {
+		t.Errorf("expect ErrTooBig, got %v", s.Err())
+	}
+	s = NewStreamReader(bytes.NewReader(e), Limits{MaxStringSize: 5})
+	if v := s.String(); v != "hello" || s.Err() != nil {
+		t.Errorf("expect %q, got %q (%v)", "hello", v, s.Err())
+	}
+	s = NewStreamReader(bytes.NewReader(e), Limits{MaxAlloc: 4})
+	if _ = s.String(); !errors.Is(s.Err(), ErrAllocLimit) {
+		t.Errorf("expect ErrAllocLimit, got %v", s.Err())
+	}
+	s = NewStreamReader(bytes.NewReader(e), Limits{MaxAlloc: 4})
+	if _ = s.Bytes(5); !errors.Is(s.Err(), ErrAllocLimit) {
+		t.Errorf("expect ErrAllocLimit, got %v", s.Err())
+	}
+	s = NewStreamReader(bytes.NewReader(append(e, e...)), Limits{MaxMessageSize: 10})
+	if _ = s.String(); s.Err() != nil {
+		t.Errorf("unexpected error: %v", s.Err())
+	}
+	if _ = s.String(); !errors.Is(s.Err(), ErrTooBig) || s.Err().(*DecodeError).Offset != 6 {
+		t.Errorf("expect ErrTooBig at offset 6, got %v", s.Err())
+	}
+	s = NewStreamReader(bytes.NewReader(append(e, e...)), Limits{MaxMessageSize: 10})
+	s.Skip(6)
+	if r, _ := s.Blob(); s.Err() != nil {
+		t.Errorf("unexpected error: %v", s.Err())
+	} else if _, err := io.ReadAll(r); !errors.Is(err, ErrTooBig) {
+		t.Errorf("expect ErrTooBig, got %v", err)
+	}
+
+	s = NewStreamReader(bytes.NewReader([]byte{8, 1, 2, 3, 4, 5, 6, 7, 8}), Limits{})
+	if s.DIR(); s.Err() == nil || s.Err().Error() != "IDR decoder: DIR at offset 0: invalid dir: too many identifiers" {
+		t.Errorf("expect invalid DIR error, got %v", s.Err())
+	}
+	s = NewStreamReader(bytes.NewReader([]byte{1, 0xb, 0xc0, 0xea, 0xfe, 0xd1, 0xc, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1}), Limits{})
+	s.Skip(1)
+	if s.VarTime(); s.Err() == nil || s.Err().Error() != "IDR decoder: VarTime at offset 1: invalid encoding" {
+		t.Errorf("expect invalid VarTime error, got %v", s.Err())
+	}
+}
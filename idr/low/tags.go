@@ -33,6 +33,15 @@ const (
 	VarFloatTag
 	VarComplexTag
 	VarTimeTag
+	ArrayTag
+	ListTag
+	MapTag
+	RecordTag
+	DeltaInt64Tag
+	DeltaUint64Tag
+	MonoInt64Tag
+	MonoUint64Tag
+	DeltaDeltaInt64Tag
 	MaxTag
 	InvalidTag = ^TagT(0)
 )
@@ -67,6 +76,15 @@ func (t TagT) String() string {
 		"VarFloatTag",
 		"VarComplexTag",
 		"VarTimeTag",
+		"ArrayTag",
+		"ListTag",
+		"MapTag",
+		"RecordTag",
+		"DeltaInt64Tag",
+		"DeltaUint64Tag",
+		"MonoInt64Tag",
+		"MonoUint64Tag",
+		"DeltaDeltaInt64Tag",
 		"InvalidTag",
 	}
 	if t < MaxTag {
@@ -81,35 +99,44 @@ func (t TagT) String() string {
 // TagFromString returns the TagT given the string.
 func TagFromString(s string) TagT {
 	var m = map[string]TagT{
-		"NoneTag":       0,
-		"BoolTag":       1,
-		"ByteTag":       2,
-		"Uint8Tag":      3,
-		"Uint16Tag":     4,
-		"Uint32Tag":     5,
-		"Uint64Tag":     6,
-		"Int8Tag":       7,
-		"Int16Tag":      8,
-		"Int32Tag":      9,
-		"Int64Tag":      10,
-		"Float32Tag":    11,
-		"Float64Tag":    12,
-		"Complex64Tag":  13,
-		"Complex128Tag": 14,
-		"TimeTag":       15,
-		"BytesTag":      16,
-		"SizeTag":       17,
-		"BlobTag":       18,
-		"StringTag":     19,
-		"DIRTag":        20,
-		"VarUintTag":    21,
-		"VarIntTag":     22,
-		"VarUint64Tag":  23,
-		"VarInt64Tag":   24,
-		"VarFloatTag":   25,
-		"VarComplexTag": 26,
-		"VarTimeTag":    27,
-		"InvalidTag":    ^TagT(0),
+		"NoneTag":            0,
+		"BoolTag":            1,
+		"ByteTag":            2,
+		"Uint8Tag":           3,
+		"Uint16Tag":          4,
+		"Uint32Tag":          5,
+		"Uint64Tag":          6,
+		"Int8Tag":            7,
+		"Int16Tag":           8,
+		"Int32Tag":           9,
+		"Int64Tag":           10,
+		"Float32Tag":         11,
+		"Float64Tag":         12,
+		"Complex64Tag":       13,
+		"Complex128Tag":      14,
+		"TimeTag":            15,
+		"BytesTag":           16,
+		"SizeTag":            17,
+		"BlobTag":            18,
+		"StringTag":          19,
+		"DIRTag":             20,
+		"VarUintTag":         21,
+		"VarIntTag":          22,
+		"VarUint64Tag":       23,
+		"VarInt64Tag":        24,
+		"VarFloatTag":        25,
+		"VarComplexTag":      26,
+		"VarTimeTag":         27,
+		"ArrayTag":           28,
+		"ListTag":            29,
+		"MapTag":             30,
+		"RecordTag":          31,
+		"DeltaInt64Tag":      32,
+		"DeltaUint64Tag":     33,
+		"MonoInt64Tag":       34,
+		"MonoUint64Tag":      35,
+		"DeltaDeltaInt64Tag": 36,
+		"InvalidTag":         ^TagT(0),
 	}
 	if t, OK := m[s]; OK {
 		return t
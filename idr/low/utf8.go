@@ -0,0 +1,48 @@
+package low
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF8 is the error returned when a string is not valid UTF-8.
+// The offset of the DecodeError is the one of the first invalid byte.
+var ErrInvalidUTF8 = errors.New("invalid UTF-8")
+
+// invalidUTF8 returns the index of the first invalid UTF-8 byte in b, or
+// -1 when b is valid UTF-8.
+func invalidUTF8(b []byte) int {
+	if utf8.Valid(b) {
+		return -1
+	}
+	for i := 0; i < len(b); {
+		r, n := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && n == 1 {
+			return i
+		}
+		i += n
+	}
+	return -1
+}
+
+// AppendValidString appends the string s prefixed with its size when s is
+// valid UTF-8. Otherwise e is returned unchanged with the error
+// ErrInvalidUTF8.
+func AppendValidString(e Encoder, s string) (Encoder, error) {
+	if !utf8.ValidString(s) {
+		return e, fmt.Errorf("%w: byte %d of string", ErrInvalidUTF8, invalidUTF8([]byte(s)))
+	}
+	return AppendString(e, s), nil
+}
+
+// AppendSanitizedString appends the string s prefixed with its size after
+// replacing each run of invalid UTF-8 bytes with the replacement
+// character U+FFFD.
+func AppendSanitizedString(e Encoder, s string) Encoder {
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	}
+	return AppendString(e, s)
+}
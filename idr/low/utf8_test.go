@@ -0,0 +1,78 @@
+package low
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAppendValidString(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp string // sanitized string
+		err bool
+	}{
+		// 0
+		{s: "", exp: ""},
+		{s: "héllo", exp: "héllo"},
+		{s: "a\xffb", exp: "a�b", err: true},
+		{s: "a\xc3", exp: "a�", err: true},
+		{s: "\xed\xa0\x80z", exp: "�z", err: true},
+	}
+	for i, test := range tests {
+		e, err := AppendValidString(Encoder{1}, test.s)
+		switch {
+		case test.err && (!errors.Is(err, ErrInvalidUTF8) || !bytes.Equal(e, []byte{1})):
+			t.Errorf("%d expect ErrInvalidUTF8, got %v", i, err)
+		case !test.err && (err != nil || !bytes.Equal(e, AppendString(Encoder{1}, test.s))):
+			t.Errorf("%d unexpected error: %v", i, err)
+		}
+		if e := AppendSanitizedString(nil, test.s); !bytes.Equal(e, AppendString(nil, test.exp)) {
+			t.Errorf("%d expect %q, got %q", i, test.exp, e)
+		}
+	}
+}
+
+func TestReaderValidUTF8(t *testing.T) {
+	e := AppendString(nil, "ok")
+	e = AppendString(e, "ab\xffc")
+	valid := Limits{ValidUTF8: true}
+	tests := []func(r *Reader){
+		func(r *Reader) { r.String(10) },
+		func(r *Reader) { r.UnsafeString(10) },
+		func(r *Reader) { r.InternString(10, NewInterner(0, 0)) },
+	}
+	for i, f := range tests {
+		r := NewLimitedReader(e, valid)
+		f(r)
+		if r.Err() != nil {
+			t.Errorf("%d unexpected error: %v", i, r.Err())
+		}
+		f(r)
+		var de *DecodeError
+		if !errors.Is(r.Err(), ErrInvalidUTF8) || !errors.As(r.Err(), &de) || de.Offset != 6 {
+			t.Errorf("%d expect ErrInvalidUTF8 at offset 6, got %v", i, r.Err())
+		}
+		// strings are not validated by default
+		r = NewReader(e)
+		f(r)
+		f(r)
+		if r.Err() != nil {
+			t.Errorf("%d unexpected error: %v", i, r.Err())
+		}
+	}
+
+	b := AppendValue(nil, ListTag, ListValue{{Tag: StringTag, Value: "a\xff"}})
+	r := NewLimitedReader(b, valid)
+	if r.Value(r.Tag()); !errors.Is(r.Err(), ErrInvalidUTF8) || r.Err().(*DecodeError).Offset != 6 {
+		t.Errorf("expect ErrInvalidUTF8 at offset 6, got %v", r.Err())
+	}
+
+	s := NewStreamReader(bytes.NewReader(e), valid)
+	if v := s.String(); v != "ok" || s.Err() != nil {
+		t.Errorf("expect %q, got %q (%v)", "ok", v, s.Err())
+	}
+	if _ = s.String(); !errors.Is(s.Err(), ErrInvalidUTF8) || s.Err().(*DecodeError).Offset != 6 {
+		t.Errorf("expect ErrInvalidUTF8 at offset 6, got %v", s.Err())
+	}
+}
@@ -0,0 +1,635 @@
+package low
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/chmike/ditp/dir"
+)
+
+// TaggedValue is a value with its tag.
+type TaggedValue struct {
+	Tag   TagT
+	Value any
+}
+
+// ArrayValue is the value of an ArrayTag. The elements are values of type
+// Tag.
+type ArrayValue struct {
+	Tag   TagT
+	Elems []any
+}
+
+// ListValue is the value of a ListTag.
+type ListValue []TaggedValue
+
+// MapValue is the value of a MapTag. Keys are values of type KeyTag and
+// Values are values of type ValueTag. The key at index i is associated to
+// the value at index i.
+type MapValue struct {
+	KeyTag, ValueTag TagT
+	Keys, Values     []any
+}
+
+// FieldValue is a field of a record.
+type FieldValue struct {
+	Num   uint64
+	Tag   TagT
+	Value any
+}
+
+// RecordValue is the value of a RecordTag.
+type RecordValue []FieldValue
+
+// Byte sizes of the elements of the decoded composite values accounted
+// as allocated bytes by Reader.Value.
+const (
+	anySize    = uint64(unsafe.Sizeof(any(nil)))
+	taggedSize = uint64(unsafe.Sizeof(TaggedValue{}))
+	fieldSize  = uint64(unsafe.Sizeof(FieldValue{}))
+)
+
+// AppendValue appends the tag t followed by the value v encoded as
+// specified by t. The type of v must match the tag: bool for BoolTag,
+// byte for ByteTag, uint for VarUintTag, uint64 for SizeTag, []byte for
+// BlobTag, dir.DIR for DIRTag, time.Time for TimeTag and VarTimeTag, etc.
+// The value of NoneTag is ignored. Panics if the type of v doesn't match,
+// or when t is BytesTag or an unknown tag, as their values can't be
+// decoded without a schema.
+//
+// The composite values are ArrayValue for ArrayTag, ListValue for ListTag,
+// MapValue for MapTag and RecordValue for RecordTag. The sequences are
+// []int64 for DeltaInt64Tag, MonoInt64Tag and DeltaDeltaInt64Tag, and
+// []uint64 for DeltaUint64Tag and MonoUint64Tag.
+func AppendValue(e Encoder, t TagT, v any) Encoder {
+	return appendValue(AppendTag(e, t), t, v)
+}
+
+// appendValue appends the value v encoded as specified by t.
+func appendValue(e Encoder, t TagT, v any) Encoder {
+	switch t {
+	case NoneTag:
+		return e
+	case BoolTag:
+		if x, ok := v.(bool); ok {
+			return AppendBool(e, x)
+		}
+	case ByteTag, Uint8Tag:
+		if x, ok := v.(byte); ok {
+			return AppendByte(e, x)
+		}
+	case Uint16Tag:
+		if x, ok := v.(uint16); ok {
+			return AppendUint16(e, x)
+		}
+	case Uint32Tag:
+		if x, ok := v.(uint32); ok {
+			return AppendUint32(e, x)
+		}
+	case Uint64Tag:
+		if x, ok := v.(uint64); ok {
+			return AppendUint64(e, x)
+		}
+	case Int8Tag:
+		if x, ok := v.(int8); ok {
+			return AppendInt8(e, x)
+		}
+	case Int16Tag:
+		if x, ok := v.(int16); ok {
+			return AppendInt16(e, x)
+		}
+	case Int32Tag:
+		if x, ok := v.(int32); ok {
+			return AppendInt32(e, x)
+		}
+	case Int64Tag:
+		if x, ok := v.(int64); ok {
+			return AppendInt64(e, x)
+		}
+	case Float32Tag:
+		if x, ok := v.(float32); ok {
+			return AppendFloat32(e, x)
+		}
+	case Float64Tag:
+		if x, ok := v.(float64); ok {
+			return AppendFloat64(e, x)
+		}
+	case Complex64Tag:
+		if x, ok := v.(complex64); ok {
+			return AppendComplex64(e, x)
+		}
+	case Complex128Tag:
+		if x, ok := v.(complex128); ok {
+			return AppendComplex128(e, x)
+		}
+	case TimeTag:
+		if x, ok := v.(time.Time); ok {
+			return AppendTime(e, x)
+		}
+	case SizeTag:
+		if x, ok := v.(uint64); ok {
+			return AppendSize(e, x)
+		}
+	case BlobTag:
+		if x, ok := v.([]byte); ok {
+			return AppendBlob(e, x)
+		}
+	case StringTag:
+		if x, ok := v.(string); ok {
+			return AppendString(e, x)
+		}
+	case DIRTag:
+		if x, ok := v.(dir.DIR); ok {
+			return AppendDIR(e, x)
+		}
+	case VarUintTag:
+		if x, ok := v.(uint); ok {
+			return AppendVarUint(e, x)
+		}
+	case VarIntTag:
+		if x, ok := v.(int); ok {
+			return AppendVarInt(e, x)
+		}
+	case VarUint64Tag:
+		if x, ok := v.(uint64); ok {
+			return AppendVarUint64(e, x)
+		}
+	case VarInt64Tag:
+		if x, ok := v.(int64); ok {
+			return AppendVarInt64(e, x)
+		}
+	case VarFloatTag:
+		if x, ok := v.(float64); ok {
+			return AppendVarFloat(e, x)
+		}
+	case VarComplexTag:
+		if x, ok := v.(complex128); ok {
+			return AppendVarComplex(e, x)
+		}
+	case VarTimeTag:
+		if x, ok := v.(time.Time); ok {
+			return AppendVarTime(e, x)
+		}
+	case ArrayTag:
+		if x, ok := v.(ArrayValue); ok {
+			e, p := BeginArray(e, x.Tag, len(x.Elems))
+			for _, v := range x.Elems {
+				e = appendValue(e, x.Tag, v)
+			}
+			return EndComposite(e, p)
+		}
+	case ListTag:
+		if x, ok := v.(ListValue); ok {
+			e, p := BeginList(e, len(x))
+			for _, v := range x {
+				e = AppendValue(e, v.Tag, v.Value)
+			}
+			return EndComposite(e, p)
+		}
+	case MapTag:
+		if x, ok := v.(MapValue); ok {
+			if len(x.Keys) != len(x.Values) {
+				panic("IDR encoder: map keys and values count mismatch")
+			}
+			e, p := BeginMap(e, x.KeyTag, x.ValueTag, len(x.Keys))
+			for i := range x.Keys {
+				e = appendValue(e, x.KeyTag, x.Keys[i])
+				e = appendValue(e, x.ValueTag, x.Values[i])
+			}
+			return EndComposite(e, p)
+		}
+	case RecordTag:
+		if x, ok := v.(RecordValue); ok {
+			e, p := BeginRecord(e)
+			for _, f := range x {
+				e = AppendField(e, f.Num, f.Tag)
+				e = appendValue(e, f.Tag, f.Value)
+			}
+			return EndComposite(e, p)
+		}
+	case DeltaInt64Tag, MonoInt64Tag, DeltaDeltaInt64Tag:
+		if x, ok := v.([]int64); ok {
+			return appendSeq(e, x, t)
+		}
+	case DeltaUint64Tag, MonoUint64Tag:
+		if x, ok := v.([]uint64); ok {
+			return appendSeq(e, x, t)
+		}
+	default:
+		panic(fmt.Sprintf("IDR encoder: %v is not a value tag", t))
+	}
+	panic(fmt.Sprintf("IDR encoder: type %T doesn't match %v", v, t))
+}
+
+// Value returns the tag and the value in front of the remaining bytes. The
+// type of the returned value is the one expected by AppendValue for the
+// tag. The value of NoneTag is nil. Panics if the data is invalid or
+// truncated, when composite values are nested deeper than DefaultMaxDepth,
+// or when the tag is BytesTag or an unknown tag.
+func Value(d Decoder) (Decoder, TagT, any) {
+	d, t := Tag(d)
+	d, v := value(d, t, 0)
+	return d, t, v
+}
+
+// checkCount panics if the n elements of a composite value can't be
+// encoded in size bytes, or if there are more than DefaultMaxNoneCount
+// elements without encoding.
+func checkCount(n uint64, size int, none bool) {
+	if none && n > DefaultMaxNoneCount || !none && n > uint64(size) {
+		panic("IDR decoder: data too big")
+	}
+}
+
+// value returns the value encoded as specified by t in front of the
+// remaining bytes, at the given nesting depth. The element count of a
+// composite value may not exceed the byte size of its content, except for
+// NoneTag elements which are bounded by DefaultMaxNoneCount, so that
+// invalid data can't trigger a huge allocation.
+func value(d Decoder, t TagT, depth int) (Decoder, any) {
+	switch t {
+	case ArrayTag, ListTag, MapTag, RecordTag:
+		if depth >= DefaultMaxDepth {
+			panic("IDR decoder: nesting too deep")
+		}
+	}
+	var v any
+	switch t {
+	case NoneTag:
+	case BoolTag:
+		d, v = Bool(d)
+	case ByteTag, Uint8Tag:
+		d, v = Byte(d)
+	case Uint16Tag:
+		d, v = Uint16(d)
+	case Uint32Tag:
+		d, v = Uint32(d)
+	case Uint64Tag:
+		d, v = Uint64(d)
+	case Int8Tag:
+		d, v = Int8(d)
+	case Int16Tag:
+		d, v = Int16(d)
+	case Int32Tag:
+		d, v = Int32(d)
+	case Int64Tag:
+		d, v = Int64(d)
+	case Float32Tag:
+		d, v = Float32(d)
+	case Float64Tag:
+		d, v = Float64(d)
+	case Complex64Tag:
+		d, v = Complex64(d)
+	case Complex128Tag:
+		d, v = Complex128(d)
+	case TimeTag:
+		d, v = Time(d)
+	case SizeTag:
+		d, v = Size(d)
+	case BlobTag:
+		d, v = Blob(d, uint64(len(d)))
+	case StringTag:
+		d, v = String(d, uint64(len(d)))
+	case DIRTag:
+		d, v = DIR(d)
+	case VarUintTag:
+		d, v = VarUint(d)
+	case VarIntTag:
+		d, v = VarInt(d)
+	case VarUint64Tag:
+		d, v = VarUint64(d)
+	case VarInt64Tag:
+		d, v = VarInt64(d)
+	case VarFloatTag:
+		d, v = VarFloat(d)
+	case VarComplexTag:
+		d, v = VarComplex(d)
+	case VarTimeTag:
+		d, v = VarTime(d)
+	case ArrayTag:
+		var c Decoder
+		var x ArrayValue
+		var n uint64
+		d, c, x.Tag, n = Array(d)
+		checkCount(n, len(c), x.Tag == NoneTag)
+		x.Elems = make([]any, n)
+		for i := range x.Elems {
+			c, x.Elems[i] = value(c, x.Tag, depth+1)
+		}
+		v = x
+	case ListTag:
+		var c Decoder
+		var n uint64
+		d, c, n = List(d)
+		checkCount(n, len(c), false)
+		x := make(ListValue, n)
+		for i := range x {
+			c, x[i].Tag = Tag(c)
+			c, x[i].Value = value(c, x[i].Tag, depth+1)
+		}
+		v = x
+	case MapTag:
+		var c Decoder
+		var x MapValue
+		var n uint64
+		d, c, x.KeyTag, x.ValueTag, n = Map(d)
+		checkCount(n, len(c), x.KeyTag == NoneTag && x.ValueTag == NoneTag)
+		x.Keys, x.Values = make([]any, n), make([]any, n)
+		for i := range x.Keys {
+			c, x.Keys[i] = value(c, x.KeyTag, depth+1)
+			c, x.Values[i] = value(c, x.ValueTag, depth+1)
+		}
+		v = x
+	case RecordTag:
+		var c Decoder
+		var x RecordValue
+		d, c = Record(d)
+		for len(c) > 0 {
+			var f FieldValue
+			c, f.Num, f.Tag = Field(c)
+			c, f.Value = value(c, f.Tag, depth+1)
+			x = append(x, f)
+		}
+		v = x
+	case DeltaInt64Tag, MonoInt64Tag, DeltaDeltaInt64Tag:
+		d, v = seq[int64](d, t)
+	case DeltaUint64Tag, MonoUint64Tag:
+		d, v = seq[uint64](d, t)
+	default:
+		panic(fmt.Sprintf("IDR decoder: %v is not a value tag", t))
+	}
+	return d, v
+}
+
+// SkipValue skips the value encoded as specified by the tag t. The tag
+// itself must already have been decoded. Panics if the data is truncated,
+// or when t is BytesTag or an unknown tag.
+func SkipValue(d Decoder, t TagT) Decoder {
+	switch t {
+	case NoneTag:
+		return d
+	case BoolTag:
+		return SkipBool(d)
+	case ByteTag:
+		return SkipByte(d)
+	case Uint8Tag:
+		return SkipUint8(d)
+	case Uint16Tag:
+		return SkipUint16(d)
+	case Uint32Tag:
+		return SkipUint32(d)
+	case Uint64Tag:
+		return SkipUint64(d)
+	case Int8Tag:
+		return SkipInt8(d)
+	case Int16Tag:
+		return SkipInt16(d)
+	case Int32Tag:
+		return SkipInt32(d)
+	case Int64Tag:
+		return SkipInt64(d)
+	case Float32Tag:
+		return SkipFloat32(d)
+	case Float64Tag:
+		return SkipFloat64(d)
+	case Complex64Tag:
+		return SkipComplex64(d)
+	case Complex128Tag:
+		return SkipComplex128(d)
+	case TimeTag:
+		return SkipTime(d)
+	case SizeTag:
+		return SkipSize(d)
+	case BlobTag:
+		return SkipBlob(d, uint64(len(d)))
+	case StringTag:
+		return SkipString(d, uint64(len(d)))
+	case DIRTag:
+		return SkipDIR(d)
+	case VarUintTag:
+		return SkipVarUint(d)
+	case VarIntTag:
+		return SkipVarInt(d)
+	case VarUint64Tag:
+		return SkipVarUint64(d)
+	case VarInt64Tag:
+		return SkipVarInt64(d)
+	case VarFloatTag:
+		return SkipVarFloat(d)
+	case VarComplexTag:
+		return SkipVarComplex(d)
+	case VarTimeTag:
+		return SkipVarTime(d)
+	case ArrayTag:
+		return SkipArray(d)
+	case ListTag:
+		return SkipList(d)
+	case MapTag:
+		return SkipMap(d)
+	case RecordTag:
+		return SkipRecord(d)
+	case DeltaInt64Tag, DeltaUint64Tag, MonoInt64Tag, MonoUint64Tag, DeltaDeltaInt64Tag:
+		return SkipSeq(d)
+	}
+	panic(fmt.Sprintf("IDR decoder: %v is not a value tag", t))
+}
+
+// SkipValue skips the value encoded as specified by the tag t. The tag
+// itself must already have been decoded. The encoding is invalid when t
+// is BytesTag or an unknown tag.
+func (r *Reader) SkipValue(t TagT) {
+	switch t {
+	case NoneTag:
+	case BoolTag:
+		r.SkipBool()
+	case ByteTag:
+		r.SkipByte()
+	case Uint8Tag:
+		r.SkipUint8()
+	case Uint16Tag:
+		r.SkipUint16()
+	case Uint32Tag:
+		r.SkipUint32()
+	case Uint64Tag:
+		r.SkipUint64()
+	case Int8Tag:
+		r.SkipInt8()
+	case Int16Tag:
+		r.SkipInt16()
+	case Int32Tag:
+		r.SkipInt32()
+	case Int64Tag:
+		r.SkipInt64()
+	case Float32Tag:
+		r.SkipFloat32()
+	case Float64Tag:
+		r.SkipFloat64()
+	case Complex64Tag:
+		r.SkipComplex64()
+	case Complex128Tag:
+		r.SkipComplex128()
+	case TimeTag:
+		r.SkipTime()
+	case SizeTag:
+		r.SkipSize()
+	case BlobTag:
+		r.SkipBlob(uint64(r.Len()))
+	case StringTag:
+		r.SkipString(uint64(r.Len()))
+	case DIRTag:
+		r.SkipDIR()
+	case VarUintTag:
+		r.SkipVarUint()
+	case VarIntTag:
+		r.SkipVarInt()
+	case VarUint64Tag:
+		r.SkipVarUint64()
+	case VarInt64Tag:
+		r.SkipVarInt64()
+	case VarFloatTag:
+		r.SkipVarFloat()
+	case VarComplexTag:
+		r.SkipVarComplex()
+	case VarTimeTag:
+		r.SkipVarTime()
+	case ArrayTag:
+		r.SkipArray()
+	case ListTag:
+		r.SkipList()
+	case MapTag:
+		r.SkipMap()
+	case RecordTag:
+		r.SkipRecord()
+	case DeltaInt64Tag, DeltaUint64Tag, MonoInt64Tag, MonoUint64Tag, DeltaDeltaInt64Tag:
+		r.SkipSeq(t)
+	default:
+		r.fail("Value", r.off, ErrInvalid)
+	}
+}
+
+// Value returns the value encoded as specified by the tag t. The tag
+// itself must already have been decoded. The type of the returned value
+// is the one returned by the Value function. The encoding is invalid when
+// t is BytesTag or an unknown tag.
+func (r *Reader) Value(t TagT) any {
+	switch t {
+	case NoneTag:
+		return nil
+	case BoolTag:
+		return r.Bool()
+	case ByteTag:
+		return r.Byte()
+	case Uint8Tag:
+		return r.Uint8()
+	case Uint16Tag:
+		return r.Uint16()
+	case Uint32Tag:
+		return r.Uint32()
+	case Uint64Tag:
+		return r.Uint64()
+	case Int8Tag:
+		return r.Int8()
+	case Int16Tag:
+		return r.Int16()
+	case Int32Tag:
+		return r.Int32()
+	case Int64Tag:
+		return r.Int64()
+	case Float32Tag:
+		return r.Float32()
+	case Float64Tag:
+		return r.Float64()
+	case Complex64Tag:
+		return r.Complex64()
+	case Complex128Tag:
+		return r.Complex128()
+	case TimeTag:
+		return r.Time()
+	case SizeTag:
+		return r.Size()
+	case BlobTag:
+		return r.Blob(uint64(r.Len()))
+	case StringTag:
+		return r.String(uint64(r.Len()))
+	case DIRTag:
+		return r.DIR()
+	case VarUintTag:
+		return r.VarUint()
+	case VarIntTag:
+		return r.VarInt()
+	case VarUint64Tag:
+		return r.VarUint64()
+	case VarInt64Tag:
+		return r.VarInt64()
+	case VarFloatTag:
+		return r.VarFloat()
+	case VarComplexTag:
+		return r.VarComplex()
+	case VarTimeTag:
+		return r.VarTime()
+	case ArrayTag:
+		c, tag, n := r.Array()
+		if !c.Alloc(n, anySize) {
+			r.adopt(&c)
+			return ArrayValue{Tag: tag}
+		}
+		x := ArrayValue{Tag: tag, Elems: make([]any, 0, min(n, uint64(c.Len())))}
+		for i := uint64(0); i < n && c.err == nil; i++ {
+			x.Elems = append(x.Elems, c.Value(tag))
+		}
+		r.adopt(&c)
+		return x
+	case ListTag:
+		c, n := r.List()
+		if !c.Alloc(n, taggedSize) {
+			r.adopt(&c)
+			return ListValue(nil)
+		}
+		x := make(ListValue, 0, min(n, uint64(c.Len())))
+		for i := uint64(0); i < n && c.err == nil; i++ {
+			tag := c.Tag()
+			x = append(x, TaggedValue{Tag: tag, Value: c.Value(tag)})
+		}
+		r.adopt(&c)
+		return x
+	case MapTag:
+		c, kt, vt, n := r.Map()
+		if !c.Alloc(n, 2*anySize) {
+			r.adopt(&c)
+			return MapValue{KeyTag: kt, ValueTag: vt}
+		}
+		m := min(n, uint64(c.Len()))
+		x := MapValue{KeyTag: kt, ValueTag: vt, Keys: make([]any, 0, m), Values: make([]any, 0, m)}
+		for i := uint64(0); i < n && c.err == nil; i++ {
+			x.Keys = append(x.Keys, c.Value(kt))
+			x.Values = append(x.Values, c.Value(vt))
+		}
+		r.adopt(&c)
+		return x
+	case RecordTag:
+		c := r.Record()
+		var x RecordValue
+		for c.Len() > 0 && c.Alloc(1, fieldSize) {
+			var f FieldValue
+			f.Num, f.Tag = c.Field()
+			f.Value = c.Value(f.Tag)
+			x = append(x, f)
+		}
+		r.adopt(&c)
+		return x
+	case DeltaInt64Tag, MonoInt64Tag, DeltaDeltaInt64Tag:
+		return readSeq[int64](r, t)
+	case DeltaUint64Tag, MonoUint64Tag:
+		return readSeq[uint64](r, t)
+	}
+	r.fail("Value", r.off, ErrInvalid)
+	return nil
+}
+
+// adopt reports the error of the Reader c of a composite value content.
+func (r *Reader) adopt(c *Reader) {
+	if r.err == nil {
+		r.err = c.err
+	}
+}
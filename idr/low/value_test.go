@@ -0,0 +1,139 @@
+package low
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValue(t *testing.T) {
+	var e Encoder
+	var n int
+	for i, test := range readerValues {
+		if test.t == BytesTag {
+			continue
+		}
+		b := AppendValue(nil, test.t, test.v)
+		exp := appendReaderValue(AppendTag(nil, test.t), test.t, test.v)
+		if test.t == NoneTag {
+			exp = AppendTag(nil, NoneTag)
+		}
+		if !bytes.Equal(b, exp) {
+			t.Errorf("%3d expect %#v, got %#v", i, exp, b)
+		}
+		e = append(e, b...)
+		n++
+	}
+
+	d := Decoder(e)
+	for i, test := range readerValues {
+		if test.t == BytesTag {
+			continue
+		}
+		var tag TagT
+		var v any
+		d, tag, v = Value(d)
+		if tag != test.t {
+			t.Errorf("%3d expect tag %v, got %v", i, test.t, tag)
+		}
+		switch {
+		case test.t == NoneTag:
+			if v != nil {
+				t.Errorf("%3d expect nil, got %#v", i, v)
+			}
+		case test.t == VarTimeTag || test.t == TimeTag:
+			if !test.v.(time.Time).Equal(v.(time.Time)) {
+				t.Errorf("%3d expect %v, got %v", i, test.v, v)
+			}
+		case !reflect.DeepEqual(v, test.v):
+			t.Errorf("%3d expect %#v, got %#v", i, test.v, v)
+		}
+	}
+	if len(d) != 0 {
+		t.Errorf("expect no bytes left, got %d", len(d))
+	}
+
+	d = Decoder(e)
+	for i := 0; i < n; i++ {
+		var tag TagT
+		d, tag = Tag(d)
+		d = SkipValue(d, tag)
+	}
+	if len(d) != 0 {
+		t.Errorf("expect no bytes left, got %d", len(d))
+	}
+
+	r := NewReader(e)
+	for i := 0; i < n; i++ {
+		r.SkipValue(r.Tag())
+	}
+	if r.Err() != nil || r.Len() != 0 {
+		t.Errorf("expect no bytes left, got %d (%v)", r.Len(), r.Err())
+	}
+	if r.SkipValue(BytesTag); !errors.Is(r.Err(), ErrInvalid) {
+		t.Errorf("expect ErrInvalid, got %v", r.Err())
+	}
+
+	r = NewReader(e)
+	for i, test := range readerValues {
+		if test.t == BytesTag {
+			continue
+		}
+		tag := r.Tag()
+		v := r.Value(tag)
+		switch {
+		case test.t == NoneTag:
+			if v != nil {
+				t.Errorf("%3d expect nil, got %#v", i, v)
+			}
+		case test.t == VarTimeTag || test.t == TimeTag:
+			if !test.v.(time.Time).Equal(v.(time.Time)) {
+				t.Errorf("%3d expect %v, got %v", i, test.v, v)
+			}
+		case !reflect.DeepEqual(v, test.v):
+			t.Errorf("%3d expect %#v, got %#v", i, test.v, v)
+		}
+	}
+	if r.Err() != nil || r.Len() != 0 {
+		t.Errorf("expect no bytes left, got %d (%v)", r.Len(), r.Err())
+	}
+	if r.Value(BytesTag); !errors.Is(r.Err(), ErrInvalid) {
+		t.Errorf("expect ErrInvalid, got %v", r.Err())
+	}
+
+	// all value tags are supported
+	for tag := NoneTag; tag < MaxTag; tag++ {
+		if tag == BytesTag {
+			continue
+		}
+		if doesPanic(func() { SkipValue(make(Decoder, 32), tag) }) {
+			t.Errorf("unexpected panic for %v", tag)
+		}
+	}
+}
+
+func TestValuePanics(t *testing.T) {
+	if !doesPanic(func() { AppendValue(nil, StringTag, 12) }) {
+		t.Error("expect AppendValue panics with mismatching type")
+	}
+	if !doesPanic(func() { AppendValue(nil, BytesTag, []byte{1}) }) {
+		t.Error("expect AppendValue panics with BytesTag")
+	}
+	if !doesPanic(func() { AppendValue(nil, MaxTag, 1) }) {
+		t.Error("expect AppendValue panics with unknown tag")
+	}
+	if !doesPanic(func() { Value(Decoder(AppendTag(nil, BytesTag))) }) {
+		t.Error("expect Value panics with BytesTag")
+	}
+	if !doesPanic(func() { Value(Decoder(AppendTag(nil, InvalidTag))) }) {
+		t.Error("expect Value panics with unknown tag")
+	}
+	if !doesPanic(func() { Value(Decoder{byte(StringTag), 5, 'a'}) }) {
+		t.Error("expect Value panics with truncated data")
+	}
+	if !doesPanic(func() { SkipValue(nil, BytesTag) }) {
+		t.Error("expect SkipValue panics with BytesTag")
+	}
+}
@@ -0,0 +1,313 @@
+package low
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+)
+
+// defaultWriterSize is the default buffer size of a Writer.
+const defaultWriterSize = 4096
+
+// maxValueSize is the maximum byte length of an encoded value, excluding
+// blobs, strings and bytes.
+const maxValueSize = 1 + dir.MaxBinaryLen
+
+// Writer is a buffered low level IDR encoder writing the encoded values to
+// an io.Writer. The first write error is retained and returned by Err and
+// Flush, and the subsequent values are ignored. The buffered data must be
+// written with Flush when done.
+type Writer struct {
+	w    io.Writer
+	buf  Encoder
+	size int
+	n    int64
+	err  error
+}
+
+// NewWriter returns a Writer with a default buffer size writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterSize(w, defaultWriterSize)
+}
+
+// NewWriterSize returns a Writer with a buffer of at least size bytes
+// writing to w.
+func NewWriterSize(w io.Writer, size int) *Writer {
+	if size < maxValueSize {
+		size = maxValueSize
+	}
+	return &Writer{w: w, buf: make(Encoder, 0, size+maxValueSize), size: size}
+}
+
+// Reset discards the buffered data, clears the error and sets the
+// destination to w.
+func (w *Writer) Reset(dst io.Writer) {
+	w.w = dst
+	w.buf = Reset(w.buf)
+	w.n = 0
+	w.err = nil
+}
+
+// Err returns the first write error, or nil.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Len returns the total byte length of the encoded values, including the
+// buffered ones.
+func (w *Writer) Len() int64 {
+	return w.n + int64(len(w.buf))
+}
+
+// Buffered returns the number of bytes not yet written.
+func (w *Writer) Buffered() int {
+	return len(w.buf)
+}
+
+// Flush writes the buffered data and returns the first write error.
+func (w *Writer) Flush() error {
+	if w.err != nil || len(w.buf) == 0 {
+		return w.err
+	}
+	n, err := w.w.Write(w.buf)
+	w.n += int64(n)
+	if err == nil && n < len(w.buf) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		w.err = err
+	}
+	w.buf = Reset(w.buf)
+	return w.err
+}
+
+// update sets the buffer to e and flushes it when full.
+func (w *Writer) update(e Encoder) {
+	if w.err != nil {
+		w.buf = Reset(w.buf)
+		return
+	}
+	w.buf = e
+	if len(w.buf) >= w.size {
+		w.Flush()
+	}
+}
+
+// write appends b to the buffer or writes it directly when big.
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	if len(w.buf)+len(b) <= w.size {
+		w.update(append(w.buf, b...))
+		return
+	}
+	if w.Flush() != nil {
+		return
+	}
+	if len(b) < w.size {
+		w.buf = append(w.buf, b...)
+		return
+	}
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	if err == nil && n < len(b) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		w.err = err
+	}
+}
+
+// AppendByte appends the byte v.
+func (w *Writer) AppendByte(v byte) {
+	w.update(AppendByte(w.buf, v))
+}
+
+// AppendBool appends the bool v.
+func (w *Writer) AppendBool(v bool) {
+	w.update(AppendBool(w.buf, v))
+}
+
+// AppendBytes appends the bytes v.
+func (w *Writer) AppendBytes(v ...byte) {
+	w.write(v)
+}
+
+// AppendVarUint64 appends the uint64 v using a compact encoding.
+func (w *Writer) AppendVarUint64(v uint64) {
+	w.update(AppendVarUint64(w.buf, v))
+}
+
+// AppendVarUint appends the uint v using a compact encoding.
+func (w *Writer) AppendVarUint(v uint) {
+	w.update(AppendVarUint(w.buf, v))
+}
+
+// AppendTag appends the Tag.
+func (w *Writer) AppendTag(t TagT) {
+	w.update(AppendTag(w.buf, t))
+}
+
+// AppendSize appends v encoded as a size value.
+func (w *Writer) AppendSize(v uint64) {
+	w.update(AppendSize(w.buf, v))
+}
+
+// AppendVarInt64 appends the int64 v using the VarUint encoding.
+func (w *Writer) AppendVarInt64(v int64) {
+	w.update(AppendVarInt64(w.buf, v))
+}
+
+// AppendVarInt appends the int v using the VarUint encoding.
+func (w *Writer) AppendVarInt(v int) {
+	w.update(AppendVarInt(w.buf, v))
+}
+
+// AppendVarFloat appends the float64 using the QVarUint encoding.
+func (w *Writer) AppendVarFloat(v float64) {
+	w.update(AppendVarFloat(w.buf, v))
+}
+
+// AppendVarComplex appends the complex128 using the QVarUint encoding.
+func (w *Writer) AppendVarComplex(v complex128) {
+	w.update(AppendVarComplex(w.buf, v))
+}
+
+// AppendUint8 appends the uint8 value v.
+func (w *Writer) AppendUint8(v uint8) {
+	w.update(AppendUint8(w.buf, v))
+}
+
+// AppendUint16 appends the uint16 value v.
+func (w *Writer) AppendUint16(v uint16) {
+	w.update(AppendUint16(w.buf, v))
+}
+
+// AppendUint32 appends the uint32 value v.
+func (w *Writer) AppendUint32(v uint32) {
+	w.update(AppendUint32(w.buf, v))
+}
+
+// AppendUint64 appends the uint64 value v.
+func (w *Writer) AppendUint64(v uint64) {
+	w.update(AppendUint64(w.buf, v))
+}
+
+// AppendInt8 appends the int8 value v.
+func (w *Writer) AppendInt8(v int8) {
+	w.update(AppendInt8(w.buf, v))
+}
+
+// AppendInt16 appends the int16 value v.
+func (w *Writer) AppendInt16(v int16) {
+	w.update(AppendInt16(w.buf, v))
+}
+
+// AppendInt32 appends the int32 value v.
+func (w *Writer) AppendInt32(v int32) {
+	w.update(AppendInt32(w.buf, v))
+}
+
+// AppendInt64 appends the int64 value v.
+func (w *Writer) AppendInt64(v int64) {
+	w.update(AppendInt64(w.buf, v))
+}
+
+// AppendFloat32 appends the float32 value v.
+func (w *Writer) AppendFloat32(v float32) {
+	w.update(AppendFloat32(w.buf, v))
+}
+
+// AppendFloat64 appends the float64 value v.
+func (w *Writer) AppendFloat64(v float64) {
+	w.update(AppendFloat64(w.buf, v))
+}
+
+// AppendComplex64 appends the complex64 value v.
+func (w *Writer) AppendComplex64(v complex64) {
+	w.update(AppendComplex64(w.buf, v))
+}
+
+// AppendComplex128 appends the complex128 value v.
+func (w *Writer) AppendComplex128(v complex128) {
+	w.update(AppendComplex128(w.buf, v))
+}
+
+// AppendBlob appends the byte slice b prefixed with its size.
+func (w *Writer) AppendBlob(b []byte) {
+	w.update(AppendSize(w.buf, uint64(len(b))))
+	w.write(b)
+}
+
+// AppendBlobFrom appends a blob of n bytes read from r. Large blobs are
+// copied to the destination without buffering. The error
+// io.ErrUnexpectedEOF is retained when r has less than n bytes, and the
+// encoded data is then invalid. A negative n is reported with the error
+// ErrInvalid and nothing is appended.
+func (w *Writer) AppendBlobFrom(r io.Reader, n int64) {
+	if n < 0 {
+		if w.err == nil {
+			w.err = fmt.Errorf("%w: negative blob size %d", ErrInvalid, n)
+		}
+		return
+	}
+	w.update(AppendSize(w.buf, uint64(n)))
+	if n <= int64(w.size-len(w.buf)) {
+		if w.err != nil {
+			return
+		}
+		l := len(w.buf)
+		m, err := io.ReadFull(r, w.buf[l:l+int(n)])
+		w.buf = w.buf[:l+m]
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			w.err = err
+		}
+		w.update(w.buf)
+		return
+	}
+	if w.Flush() != nil {
+		return
+	}
+	m, err := io.CopyN(w.w, r, n)
+	w.n += m
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		w.err = err
+	}
+}
+
+// AppendString appends the string s prefixed with its size.
+func (w *Writer) AppendString(s string) {
+	w.update(AppendSize(w.buf, uint64(len(s))))
+	if w.err != nil {
+		return
+	}
+	if len(w.buf)+len(s) <= w.size {
+		w.update(append(w.buf, s...))
+		return
+	}
+	w.write([]byte(s))
+}
+
+// AppendDIR appends the DIR d.
+func (w *Writer) AppendDIR(d dir.DIR) {
+	w.update(AppendDIR(w.buf, d))
+}
+
+// AppendVarTime appends the time t in the most compact form.
+func (w *Writer) AppendVarTime(t time.Time) {
+	w.update(AppendVarTime(w.buf, t))
+}
+
+// AppendTime appends the time t.
+func (w *Writer) AppendTime(t time.Time) {
+	w.update(AppendTime(w.buf, t))
+}
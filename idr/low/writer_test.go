@@ -0,0 +1,189 @@
+package low
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+)
+
+// writeValue writes v with the encoding identified by t.
+func writeValue(w *Writer, t TagT, v any) {
+	switch t {
+	case NoneTag:
+		w.AppendTag(v.(TagT))
+	case BoolTag:
+		w.AppendBool(v.(bool))
+	case ByteTag:
+		w.AppendByte(v.(byte))
+	case BytesTag:
+		w.AppendBytes(v.([]byte)...)
+	case VarUintTag:
+		w.AppendVarUint(v.(uint))
+	case VarIntTag:
+		w.AppendVarInt(v.(int))
+	case VarUint64Tag:
+		w.AppendVarUint64(v.(uint64))
+	case VarInt64Tag:
+		w.AppendVarInt64(v.(int64))
+	case SizeTag:
+		w.AppendSize(v.(uint64))
+	case VarFloatTag:
+		w.AppendVarFloat(v.(float64))
+	case VarComplexTag:
+		w.AppendVarComplex(v.(complex128))
+	case Uint8Tag:
+		w.AppendUint8(v.(uint8))
+	case Uint16Tag:
+		w.AppendUint16(v.(uint16))
+	case Uint32Tag:
+		w.AppendUint32(v.(uint32))
+	case Uint64Tag:
+		w.AppendUint64(v.(uint64))
+	case Int8Tag:
+		w.AppendInt8(v.(int8))
+	case Int16Tag:
+		w.AppendInt16(v.(int16))
+	case Int32Tag:
+		w.AppendInt32(v.(int32))
+	case Int64Tag:
+		w.AppendInt64(v.(int64))
+	case Float32Tag:
+		w.AppendFloat32(v.(float32))
+	case Float64Tag:
+		w.AppendFloat64(v.(float64))
+	case Complex64Tag:
+		w.AppendComplex64(v.(complex64))
+	case Complex128Tag:
+		w.AppendComplex128(v.(complex128))
+	case BlobTag:
+		w.AppendBlob(v.([]byte))
+	case StringTag:
+		w.AppendString(v.(string))
+	case DIRTag:
+		w.AppendDIR(v.(dir.DIR))
+	case VarTimeTag:
+		w.AppendVarTime(v.(time.Time))
+	case TimeTag:
+		w.AppendTime(v.(time.Time))
+	}
+}
+
+func TestWriter(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789"), 30)
+	values := append(readerValues[:len(readerValues):len(readerValues)],
+		struct {
+			t TagT
+			v any
+		}{t: BlobTag, v: big},
+		struct {
+			t TagT
+			v any
+		}{t: StringTag, v: string(big)},
+		struct {
+			t TagT
+			v any
+		}{t: BytesTag, v: big},
+	)
+	var exp Encoder
+	for _, test := range values {
+		exp = appendReaderValue(exp, test.t, test.v)
+	}
+	for _, size := range []int{0, 70, 100, 4096} {
+		var buf bytes.Buffer
+		w := NewWriterSize(&buf, size)
+		for i, test := range values {
+			writeValue(w, test.t, test.v)
+			if w.Buffered() > max(size, maxValueSize) {
+				t.Errorf("%d %3d expect at most %d buffered bytes, got %d", size, i, size, w.Buffered())
+			}
+		}
+		if w.Len() != int64(len(exp)) {
+			t.Errorf("%d expect len %d, got %d", size, len(exp), w.Len())
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("%d unexpected error: %v", size, err)
+		}
+		if !bytes.Equal(buf.Bytes(), exp) {
+			t.Errorf("%d expect %#v, got %#v", size, exp, buf.Bytes())
+		}
+		if w.Len() != int64(len(exp)) || w.Buffered() != 0 {
+			t.Errorf("%d expect len %d, got %d", size, len(exp), w.Len())
+		}
+	}
+}
+
+func TestWriterBlobFrom(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789"), 30)
+	for _, size := range []int{100, 4096} {
+		var buf bytes.Buffer
+		w := NewWriterSize(&buf, size)
+		w.AppendByte(1)
+		w.AppendBlobFrom(bytes.NewReader(big), int64(len(big)))
+		w.AppendBlobFrom(strings.NewReader("hello world"), 5)
+		w.AppendByte(2)
+		if err := w.Flush(); err != nil {
+			t.Fatalf("%d unexpected error: %v", size, err)
+		}
+		exp := AppendByte(nil, 1)
+		exp = AppendBlob(exp, big)
+		exp = AppendString(exp, "hello")
+		exp = AppendByte(exp, 2)
+		if !bytes.Equal(buf.Bytes(), exp) {
+			t.Errorf("%d expect %#v, got %#v", size, exp, buf.Bytes())
+		}
+		if w.Len() != int64(len(exp)) {
+			t.Errorf("%d expect len %d, got %d", size, len(exp), w.Len())
+		}
+
+		buf.Reset()
+		w.Reset(&buf)
+		w.AppendBlobFrom(bytes.NewReader(big), int64(len(big)+1))
+		if !errors.Is(w.Flush(), io.ErrUnexpectedEOF) {
+			t.Errorf("%d expect ErrUnexpectedEOF, got %v", size, w.Err())
+		}
+		w.Reset(&buf)
+		w.AppendBlobFrom(strings.NewReader("hi"), 3)
+		if !errors.Is(w.Flush(), io.ErrUnexpectedEOF) {
+			t.Errorf("%d expect ErrUnexpectedEOF, got %v", size, w.Err())
+		}
+		w.Reset(&buf)
+		w.AppendBlobFrom(strings.NewReader("hi"), -1)
+		if !errors.Is(w.Flush(), ErrInvalid) || w.Len() != 0 {
+			t.Errorf("%d expect ErrInvalid and no data, got %v and len %d", size, w.Err(), w.Len())
+		}
+	}
+}
+
+// errWriter accepts n bytes and then fails.
+type errWriter struct{ n int }
+
+var errWrite = errors.New("write error")
+
+func (w *errWriter) Write(b []byte) (int, error) {
+	if len(b) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errWrite
+	}
+	w.n -= len(b)
+	return len(b), nil
+}
+
+func TestWriterError(t *testing.T) {
+	w := NewWriterSize(&errWriter{n: 70}, 64)
+	for i := 0; i < 100; i++ {
+		w.AppendUint64(uint64(i))
+	}
+	w.AppendString(strings.Repeat("x", 100))
+	if !errors.Is(w.Err(), errWrite) || !errors.Is(w.Flush(), errWrite) {
+		t.Errorf("expect write error, got %v", w.Err())
+	}
+	if w.Len() != 70 || w.Buffered() != 0 {
+		t.Errorf("expect len 70 and no buffered data, got %d and %d", w.Len(), w.Buffered())
+	}
+}
@@ -0,0 +1,131 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/chmike/ditp/idr"
+	"github.com/chmike/ditp/idr/low"
+)
+
+// Node is a node of the tree of a decoded record.
+type Node struct {
+	Name     string  // field name, empty for array elements, map keys and values
+	Num      uint64  // field number, 0 for array elements, map keys and values
+	Type     Type    // type of the value
+	Value    any     // value of a scalar type, as returned by low.Value
+	Children []*Node // array elements, map keys and values in sequence, or record fields
+}
+
+// Field returns the child node of the record field with the given name,
+// or nil if the field is not present.
+func (n *Node) Field(name string) *Node {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Decode decodes the tagged record of the given name encoded in b, as
+// encoded by idr.Marshal, into a tree of nodes. Fields unknown to the
+// schema are skipped. An error is returned if the data is invalid or
+// doesn't match the schema.
+func (s *Schema) Decode(b []byte, name string) (*Node, error) {
+	if s.Record(name) == nil {
+		return nil, fmt.Errorf("%w: undefined record %s", ErrInvalid, name)
+	}
+	r := low.NewReader(b)
+	t := Type{Tag: low.RecordTag, Record: name}
+	off := r.Offset()
+	if tag := r.Tag(); r.Err() == nil && tag != t.Tag {
+		return nil, mismatch(off, tag, t)
+	}
+	n, err := s.decode(r, t)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%w: trailing data at offset %d", low.ErrInvalid, r.Offset())
+	}
+	return n, nil
+}
+
+func mismatch(off int, tag low.TagT, t Type) error {
+	return fmt.Errorf("%w: %v at offset %d, expect %v", idr.ErrMismatch, tag, off, t)
+}
+
+// decode returns the node of the value of type t. The error of the Reader
+// takes precedence as it is the cause of subsequent errors.
+func (s *Schema) decode(r *low.Reader, t Type) (*Node, error) {
+	n := &Node{Type: t}
+	err := s.decodeValue(r, n)
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+	return n, err
+}
+
+func (s *Schema) decodeValue(r *low.Reader, n *Node) error {
+	t := n.Type
+	off := r.Offset()
+	switch t.Tag {
+	case low.ArrayTag:
+		c, tag, count := r.Array()
+		if c.Err() == nil && count > 0 && tag != t.Elem.Tag {
+			return mismatch(off, tag, *t.Elem)
+		}
+		for i := uint64(0); i < count && c.Err() == nil; i++ {
+			e, err := s.decode(&c, *t.Elem)
+			if err != nil {
+				return err
+			}
+			n.Children = append(n.Children, e)
+		}
+		return c.Err()
+	case low.MapTag:
+		c, kt, vt, count := r.Map()
+		if c.Err() == nil && count > 0 && kt != t.Key.Tag {
+			return mismatch(off, kt, *t.Key)
+		}
+		if c.Err() == nil && count > 0 && vt != t.Elem.Tag {
+			return mismatch(off, vt, *t.Elem)
+		}
+		for i := uint64(0); i < count && c.Err() == nil; i++ {
+			k, err := s.decode(&c, *t.Key)
+			if err != nil {
+				return err
+			}
+			v, err := s.decode(&c, *t.Elem)
+			if err != nil {
+				return err
+			}
+			n.Children = append(n.Children, k, v)
+		}
+		return c.Err()
+	case low.RecordTag:
+		rec := s.Record(t.Record)
+		c := r.Record()
+		for c.Len() > 0 && c.Err() == nil {
+			off := c.Offset()
+			num, tag := c.Field()
+			f := rec.Field(num)
+			if f == nil {
+				c.SkipValue(tag)
+				continue
+			}
+			if c.Err() == nil && tag != f.Type.Tag {
+				return mismatch(off, tag, f.Type)
+			}
+			v, err := s.decode(&c, f.Type)
+			if err != nil {
+				return err
+			}
+			v.Name, v.Num = f.Name, f.Num
+			n.Children = append(n.Children, v)
+		}
+		return c.Err()
+	}
+	n.Value = r.Value(t.Tag)
+	return nil
+}
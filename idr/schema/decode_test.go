@@ -0,0 +1,157 @@
+package schema
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chmike/ditp/dir"
+	"github.com/chmike/ditp/idr"
+	"github.com/chmike/ditp/idr/low"
+)
+
+// person is the Go struct generated for the record Person of the example
+// schema.
+type person struct {
+	Name     string          `idr:"1"`
+	BirthDay time.Time       `idr:"2"`
+	Home     dir.DIR         `idr:"3"`
+	Phones   []string        `idr:"4"`
+	Scores   map[string]int  `idr:"5"`
+	Parent   *person         `idr:"6"`
+	Children []person        `idr:"7"`
+	Stamp    time.Time       `idr:"8,fixed"`
+	Weight   float64         `idr:"9,varint"`
+	Level    uint8           `idr:"10"`
+	Id       uint64          `idr:"11,fixed"`
+	Photo    []byte          `idr:"12"`
+	Flags    map[uint16]bool `idr:"13"`
+	Zone     int32           `idr:"14"`
+	Location complex128      `idr:"15,varint"`
+	Matrix   [][]float32     `idr:"16"`
+	Unknown  string          `idr:"99"`
+}
+
+func TestDecode(t *testing.T) {
+	src, err := os.ReadFile("testdata/example.idrs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := MustParse(string(src))
+	p := person{
+		Name:     "alice",
+		BirthDay: time.Unix(1e9, 0).UTC(),
+		Home:     dir.MustMake(1, 2, 0),
+		Phones:   []string{"123", "456"},
+		Scores:   map[string]int{"x": -1},
+		Parent:   &person{Name: "bob"},
+		Children: []person{{Name: "carol"}},
+		Weight:   60.5,
+		Level:    3,
+		Id:       7,
+		Flags:    map[uint16]bool{},
+		Location: 1 + 2i,
+		Matrix:   [][]float32{{1, 2}, {}},
+		Unknown:  "skipped",
+	}
+	b, err := idr.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n, err := s.Decode(b, "Person")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the nil photo is omitted and the unknown field is skipped
+	if n.Type.Record != "Person" || len(n.Children) != 15 || n.Field("photo") != nil {
+		t.Errorf("expect 15 fields, got %d", len(n.Children))
+	}
+	tests := []struct {
+		name string
+		v    any
+	}{
+		// 0
+		{name: "name", v: "alice"},
+		{name: "home", v: dir.MustMake(1, 2, 0)},
+		{name: "weight", v: 60.5},
+		{name: "level", v: uint8(3)},
+		{name: "id", v: uint64(7)},
+		// 5
+		{name: "location", v: 1 + 2i},
+	}
+	for i, test := range tests {
+		if f := n.Field(test.name); f == nil || f.Value != test.v {
+			t.Errorf("%d expect %v, got %+v", i, test.v, f)
+		}
+	}
+	if f := n.Field("birth_day"); f.Num != 2 || !f.Value.(time.Time).Equal(p.BirthDay) {
+		t.Errorf("expect %v, got %v", p.BirthDay, f.Value)
+	}
+	if f := n.Field("phones"); len(f.Children) != 2 || f.Children[1].Value != "456" {
+		t.Errorf("expect 2 phones, got %+v", f.Children)
+	}
+	if f := n.Field("scores"); len(f.Children) != 2 || f.Children[0].Value != "x" || f.Children[1].Value != -1 {
+		t.Errorf("expect x:-1, got %+v", f.Children)
+	}
+	if f := n.Field("parent"); f.Type.Record != "Person" || f.Field("name").Value != "bob" {
+		t.Errorf("expect parent bob, got %+v", f)
+	}
+	if f := n.Field("children"); len(f.Children) != 1 || f.Children[0].Field("name").Value != "carol" {
+		t.Errorf("expect child carol, got %+v", f)
+	}
+	if f := n.Field("matrix"); len(f.Children) != 2 || f.Children[0].Children[1].Value != float32(2) {
+		t.Errorf("expect matrix, got %+v", f)
+	}
+
+	// errors
+	if _, err := s.Decode(b, "Nobody"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expect ErrInvalid, got %v", err)
+	}
+	if _, err := s.Decode(b[:len(b)-1], "Person"); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expect ErrUnexpectedEOF, got %v", err)
+	}
+	if _, err := s.Decode(append(b, 0), "Person"); !errors.Is(err, low.ErrInvalid) {
+		t.Errorf("expect ErrInvalid, got %v", err)
+	}
+	b2, _ := idr.Marshal(struct {
+		A bool `idr:"1"`
+	}{})
+	if _, err := s.Decode(b2, "Person"); !errors.Is(err, idr.ErrMismatch) {
+		t.Errorf("expect ErrMismatch, got %v", err)
+	}
+	b2, _ = idr.Marshal(struct {
+		A []int `idr:"4"`
+	}{A: []int{1}})
+	if _, err := s.Decode(b2, "Person"); !errors.Is(err, idr.ErrMismatch) {
+		t.Errorf("expect ErrMismatch, got %v", err)
+	}
+	if _, err := s.Decode(b2[1:], "Person"); !errors.Is(err, idr.ErrMismatch) {
+		t.Errorf("expect ErrMismatch, got %v", err)
+	}
+}
+
+func TestDecodeSchema(t *testing.T) {
+	meta := MustParse(MetaSchema)
+	b, err := Marshal(meta)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n, err := meta.Decode(b, "Schema")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records := n.Field("records").Children
+	if len(records) != 4 {
+		t.Fatalf("expect 4 records, got %d", len(records))
+	}
+	typ := records[3]
+	if typ.Field("name").Value != "Type" {
+		t.Errorf("expect record Type, got %v", typ.Field("name").Value)
+	}
+	elem := typ.Field("fields").Children[2]
+	if elem.Field("name").Value != "elem" || elem.Field("type").Field("tag").Value != uint64(low.RecordTag) {
+		t.Errorf("expect field elem of type Type, got %+v", elem)
+	}
+}
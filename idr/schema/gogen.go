@@ -0,0 +1,152 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+
+	"github.com/chmike/ditp/idr/low"
+)
+
+// encoding options of the idr struct tags as a bit set
+const (
+	defaultOpt = 1 << iota
+	varintOpt
+	fixedOpt
+	anyOpt = defaultOpt | varintOpt | fixedOpt
+)
+
+// goScalar is the Go type of a scalar type and the set of idr encoding
+// options selecting its tag.
+type goScalar struct {
+	typ  string
+	opts int
+}
+
+var goScalars = map[low.TagT]goScalar{
+	low.BoolTag:       {"bool", anyOpt},
+	low.Uint8Tag:      {"uint8", defaultOpt | fixedOpt},
+	low.Uint16Tag:     {"uint16", defaultOpt | fixedOpt},
+	low.Uint32Tag:     {"uint32", defaultOpt | fixedOpt},
+	low.Uint64Tag:     {"uint64", fixedOpt},
+	low.Int8Tag:       {"int8", defaultOpt | fixedOpt},
+	low.Int16Tag:      {"int16", defaultOpt | fixedOpt},
+	low.Int32Tag:      {"int32", defaultOpt | fixedOpt},
+	low.Int64Tag:      {"int64", fixedOpt},
+	low.Float32Tag:    {"float32", defaultOpt | fixedOpt},
+	low.Float64Tag:    {"float64", defaultOpt | fixedOpt},
+	low.Complex64Tag:  {"complex64", defaultOpt | fixedOpt},
+	low.Complex128Tag: {"complex128", defaultOpt | fixedOpt},
+	low.TimeTag:       {"time.Time", fixedOpt},
+	low.BlobTag:       {"[]byte", anyOpt},
+	low.StringTag:     {"string", anyOpt},
+	low.DIRTag:        {"dir.DIR", anyOpt},
+	low.VarUintTag:    {"uint", defaultOpt | varintOpt},
+	low.VarIntTag:     {"int", defaultOpt | varintOpt},
+	low.VarUint64Tag:  {"uint64", defaultOpt | varintOpt},
+	low.VarInt64Tag:   {"int64", defaultOpt | varintOpt},
+	low.VarFloatTag:   {"float64", varintOpt},
+	low.VarComplexTag: {"complex128", varintOpt},
+	low.VarTimeTag:    {"time.Time", defaultOpt | varintOpt},
+}
+
+// goName returns the exported Go name of a schema name. The parts of
+// snake case names are capitalized.
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// goType returns the Go type of t and the set of the idr encoding options
+// selecting the tags of t. The imports required by the type are added to
+// imports.
+func goType(t Type, imports map[string]bool) (string, int) {
+	switch t.Tag {
+	case low.ArrayTag:
+		elem, opts := goType(*t.Elem, imports)
+		if elem == "uint8" {
+			// []uint8 is encoded as a blob
+			return "", 0
+		}
+		return "[]" + elem, opts
+	case low.MapTag:
+		key, kopts := goType(*t.Key, imports)
+		elem, eopts := goType(*t.Elem, imports)
+		return "map[" + key + "]" + elem, kopts & eopts
+	case low.RecordTag:
+		return goName(t.Record), anyOpt
+	}
+	s := goScalars[t.Tag]
+	switch {
+	case strings.HasPrefix(s.typ, "time."):
+		imports["time"] = true
+	case strings.HasPrefix(s.typ, "dir."):
+		imports["github.com/chmike/ditp/dir"] = true
+	}
+	return s.typ, s.opts
+}
+
+// GenerateGo returns the source of a Go file of package pkg defining a
+// struct type for each record of s. The struct fields have idr struct
+// tags so that idr.Marshal encodes the structs as described by the
+// schema. The names are converted to exported Go names, and fields of a
+// record type are pointers. An error is returned when a type has no Go
+// representation, as []uint8 that would be encoded as a blob.
+func GenerateGo(s *Schema, pkg string) ([]byte, error) {
+	imports := make(map[string]bool)
+	var body bytes.Buffer
+	types := make(map[string]bool)
+	for _, r := range s.Records {
+		name := goName(r.Name)
+		if types[name] {
+			return nil, fmt.Errorf("%w: %vrecord %s has the same Go name as another record", ErrInvalid, r.pos, r.Name)
+		}
+		types[name] = true
+		fmt.Fprintf(&body, "\n// %s is the record %s.\ntype %[1]s struct {\n", name, r.Name)
+		fields := make(map[string]bool)
+		for _, f := range r.Fields {
+			fname := goName(f.Name)
+			if fields[fname] {
+				return nil, fmt.Errorf("%w: %vfield %s of record %s has the same Go name as another field", ErrInvalid, f.pos, f.Name, r.Name)
+			}
+			fields[fname] = true
+			typ, opts := goType(f.Type, imports)
+			if opts == 0 {
+				return nil, fmt.Errorf("%w: %vtype %v of field %s of record %s has no Go representation", ErrInvalid, f.pos, f.Type, f.Name, r.Name)
+			}
+			if f.Type.Tag == low.RecordTag {
+				typ = "*" + typ
+			}
+			tag := fmt.Sprintf("%d", f.Num)
+			switch {
+			case opts&defaultOpt != 0:
+			case opts&varintOpt != 0:
+				tag += ",varint"
+			default:
+				tag += ",fixed"
+			}
+			fmt.Fprintf(&body, "\t%s %s `idr:\"%s\"`\n", fname, typ, tag)
+		}
+		body.WriteString("}\n")
+	}
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated from an IDR schema; DO NOT EDIT.\n\npackage %s\n", pkg)
+	if len(imports) > 0 {
+		src.WriteString("\nimport (\n")
+		if imports["time"] {
+			src.WriteString("\t\"time\"\n\n")
+		}
+		if imports["github.com/chmike/ditp/dir"] {
+			src.WriteString("\t\"github.com/chmike/ditp/dir\"\n")
+		}
+		src.WriteString(")\n")
+	}
+	src.Write(body.Bytes())
+	return format.Source(src.Bytes())
+}
@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestGenerateGo(t *testing.T) {
+	src, err := os.ReadFile("testdata/example.idrs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Parse(src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := GenerateGo(s, "example")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	golden := "testdata/example.go.golden"
+	if *update {
+		if err := os.WriteFile(golden, out, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, exp) {
+		t.Errorf("generated code differs from %s, run go test -update\n%s", golden, out)
+	}
+
+	tests := []string{
+		// 0
+		"record A { a []uint8 = 1 }",
+		"record A { a map[uint64]varfloat = 1 }",
+		"record A { a_b int8 = 1  aB int8 = 2 }",
+		"record a {} record A {}",
+	}
+	for i, test := range tests {
+		if _, err := GenerateGo(MustParse(test), "a"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%d expect ErrInvalid, got %v", i, err)
+		}
+	}
+}
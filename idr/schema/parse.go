@@ -0,0 +1,231 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/chmike/ditp/idr/low"
+)
+
+// token kinds
+const (
+	eofTok = iota
+	identTok
+	numberTok
+	punctTok
+)
+
+type token struct {
+	kind int
+	text string
+	pos  pos
+}
+
+// lexer splits a schema source in tokens.
+type lexer struct {
+	src  []byte
+	off  int
+	line int
+	col  int
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (l *lexer) advance() {
+	if l.src[l.off] == '\n' {
+		l.line++
+		l.col = 0
+	}
+	l.off++
+	l.col++
+}
+
+// next returns the next token.
+func (l *lexer) next() (token, error) {
+	for l.off < len(l.src) {
+		c := l.src[l.off]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			l.advance()
+			continue
+		case c == '/' && l.off+1 < len(l.src) && l.src[l.off+1] == '/':
+			for l.off < len(l.src) && l.src[l.off] != '\n' {
+				l.advance()
+			}
+			continue
+		}
+		t := token{pos: pos{line: l.line, col: l.col}}
+		start := l.off
+		switch {
+		case isLetter(c):
+			t.kind = identTok
+			for l.off < len(l.src) && (isLetter(l.src[l.off]) || isDigit(l.src[l.off])) {
+				l.advance()
+			}
+		case isDigit(c):
+			t.kind = numberTok
+			for l.off < len(l.src) && isDigit(l.src[l.off]) {
+				l.advance()
+			}
+		case c == '{' || c == '}' || c == '[' || c == ']' || c == '=':
+			t.kind = punctTok
+			l.advance()
+		default:
+			return t, fmt.Errorf("%w: %vinvalid character %q", ErrSyntax, t.pos, c)
+		}
+		t.text = string(l.src[start:l.off])
+		return t, nil
+	}
+	return token{kind: eofTok, pos: pos{line: l.line, col: l.col}}, nil
+}
+
+// parser parses a schema source with one token look ahead.
+type parser struct {
+	lex lexer
+	tok token
+}
+
+func (p *parser) next() error {
+	var err error
+	p.tok, err = p.lex.next()
+	return err
+}
+
+// expect checks that the current token is the punctuation or identifier
+// text and moves to the next token.
+func (p *parser) expect(text string) error {
+	if p.tok.text != text || p.tok.kind == numberTok {
+		return p.errorf("expect %q", text)
+	}
+	return p.next()
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	found := p.tok.text
+	if p.tok.kind == eofTok {
+		found = "end of schema"
+	}
+	return fmt.Errorf("%w: %v%s, found %q", ErrSyntax, p.tok.pos, fmt.Sprintf(format, args...), found)
+}
+
+// ident returns the current identifier and moves to the next token.
+func (p *parser) ident() (string, error) {
+	if p.tok.kind != identTok {
+		return "", p.errorf("expect identifier")
+	}
+	name := p.tok.text
+	return name, p.next()
+}
+
+// Parse parses and validates the schema source src.
+func Parse(src []byte) (*Schema, error) {
+	p := parser{lex: lexer{src: src, line: 1, col: 1}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var s Schema
+	for p.tok.kind != eofTok {
+		r, err := p.record()
+		if err != nil {
+			return nil, err
+		}
+		s.Records = append(s.Records, r)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// MustParse is like Parse but panics in case of error.
+func MustParse(src string) *Schema {
+	s, err := Parse([]byte(src))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// record parses a record definition.
+func (p *parser) record() (Record, error) {
+	var r Record
+	if err := p.expect("record"); err != nil {
+		return r, err
+	}
+	r.pos = p.tok.pos
+	var err error
+	if r.Name, err = p.ident(); err != nil {
+		return r, err
+	}
+	if err := p.expect("{"); err != nil {
+		return r, err
+	}
+	for p.tok.text != "}" || p.tok.kind != punctTok {
+		f := Field{pos: p.tok.pos}
+		if f.Name, err = p.ident(); err != nil {
+			return r, err
+		}
+		if f.Type, err = p.typ(); err != nil {
+			return r, err
+		}
+		if err := p.expect("="); err != nil {
+			return r, err
+		}
+		if p.tok.kind != numberTok {
+			return r, p.errorf("expect field number")
+		}
+		if f.Num, err = strconv.ParseUint(p.tok.text, 10, 64); err != nil {
+			return r, p.errorf("invalid field number")
+		}
+		if err := p.next(); err != nil {
+			return r, err
+		}
+		r.Fields = append(r.Fields, f)
+	}
+	return r, p.next()
+}
+
+// typ parses a type.
+func (p *parser) typ() (Type, error) {
+	switch {
+	case p.tok.kind == punctTok && p.tok.text == "[":
+		if err := p.next(); err != nil {
+			return Type{}, err
+		}
+		if err := p.expect("]"); err != nil {
+			return Type{}, err
+		}
+		elem, err := p.typ()
+		return Type{Tag: low.ArrayTag, Elem: &elem}, err
+	case p.tok.kind == identTok && p.tok.text == "map":
+		if err := p.next(); err != nil {
+			return Type{}, err
+		}
+		if err := p.expect("["); err != nil {
+			return Type{}, err
+		}
+		key, err := p.typ()
+		if err != nil {
+			return Type{}, err
+		}
+		if err := p.expect("]"); err != nil {
+			return Type{}, err
+		}
+		elem, err := p.typ()
+		return Type{Tag: low.MapTag, Key: &key, Elem: &elem}, err
+	}
+	name, err := p.ident()
+	if err != nil {
+		return Type{}, err
+	}
+	if t, ok := scalars[name]; ok {
+		return Type{Tag: t}, nil
+	}
+	return Type{Tag: low.RecordTag, Record: name}, nil
+}
@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/chmike/ditp/idr/low"
+)
+
+func TestParse(t *testing.T) {
+	s := MustParse(MetaSchema)
+	exp := &Schema{Records: []Record{
+		{Name: "Schema", Fields: []Field{
+			{Num: 1, Name: "records", Type: Type{Tag: low.ArrayTag, Elem: &Type{Tag: low.RecordTag, Record: "Record"}}},
+		}},
+		{Name: "Record", Fields: []Field{
+			{Num: 1, Name: "name", Type: Type{Tag: low.StringTag}},
+			{Num: 2, Name: "fields", Type: Type{Tag: low.ArrayTag, Elem: &Type{Tag: low.RecordTag, Record: "Field"}}},
+		}},
+		{Name: "Field", Fields: []Field{
+			{Num: 1, Name: "num", Type: Type{Tag: low.VarUint64Tag}},
+			{Num: 2, Name: "name", Type: Type{Tag: low.StringTag}},
+			{Num: 3, Name: "type", Type: Type{Tag: low.RecordTag, Record: "Type"}},
+		}},
+		{Name: "Type", Fields: []Field{
+			{Num: 1, Name: "tag", Type: Type{Tag: low.VarUint64Tag}},
+			{Num: 2, Name: "record", Type: Type{Tag: low.StringTag}},
+			{Num: 3, Name: "elem", Type: Type{Tag: low.RecordTag, Record: "Type"}},
+			{Num: 4, Name: "key", Type: Type{Tag: low.RecordTag, Record: "Type"}},
+		}},
+	}}
+	if s.String() != exp.String() {
+		t.Errorf("expect\n%s\ngot\n%s", exp, s)
+	}
+
+	// the string representation is parsed back into the same schema
+	src, err := os.ReadFile("testdata/example.idrs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = MustParse(string(src))
+	if s2 := MustParse(s.String()); s2.String() != s.String() {
+		t.Errorf("expect\n%s\ngot\n%s", s, s2)
+	}
+	if f := s.Record("Person").Field(16); f == nil || f.Type.String() != "[][]float32" {
+		t.Errorf("expect field 16 of type [][]float32, got %+v", f)
+	}
+	if s.Record("Nobody") != nil || s.Record("Person").Field(17) != nil {
+		t.Error("expect nil for undefined record and field")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err error
+		msg string
+	}{
+		// 0
+		{src: "record A { a = 1 }", err: ErrSyntax, msg: "syntax error: 1:14: expect identifier, found \"=\""},
+		{src: "record A { a int = 1 }", err: ErrInvalid, msg: "invalid schema: 1:12: field a of record A: undefined record int"},
+		{src: "record A {\n a string = 1 \n", err: ErrSyntax, msg: "syntax error: 3:1: expect identifier, found \"end of schema\""},
+		{src: "record A { a string = x }", err: ErrSyntax, msg: "syntax error: 1:23: expect field number, found \"x\""},
+		{src: "record A { a string = 99999999999999999999 }", err: ErrSyntax, msg: "syntax error: 1:23: invalid field number, found \"99999999999999999999\""},
+		// 5
+		{src: "record A { a string ; 1 }", err: ErrSyntax, msg: "syntax error: 1:21: invalid character ';'"},
+		{src: "type A {}", err: ErrSyntax, msg: "syntax error: 1:1: expect \"record\", found \"type\""},
+		{src: "record A { a map[string string = 1 }", err: ErrSyntax, msg: "syntax error: 1:25: expect \"]\", found \"string\""},
+		{src: "record A { a [string = 1 }", err: ErrSyntax, msg: "syntax error: 1:15: expect \"]\", found \"string\""},
+		{src: "record A {} record A {}", err: ErrInvalid, msg: "invalid schema: 1:20: record A is already defined"},
+		// 10
+		{src: "record string {}", err: ErrInvalid, msg: "invalid schema: 1:8: record name \"string\" is a type name"},
+		{src: "record map {}", err: ErrInvalid, msg: "invalid schema: 1:8: invalid record name \"map\""},
+		{src: "record A { a bool = 1 a bool = 2 }", err: ErrInvalid, msg: "invalid schema: 1:23: field a of record A is already defined"},
+		{src: "record A { a bool = 1 b bool = 1 }", err: ErrInvalid, msg: "invalid schema: 1:23: field number 1 of record A is already used"},
+		{src: "record A { a bool = 0 }", err: ErrInvalid, msg: "invalid schema: 1:12: field a of record A has number 0"},
+		// 15
+		{src: "record A { a B = 1 }", err: ErrInvalid, msg: "invalid schema: 1:12: field a of record A: undefined record B"},
+		{src: "record A { a map[blob]bool = 1 }", err: ErrInvalid, msg: "invalid schema: 1:12: field a of record A: invalid map key type blob"},
+		{src: "record A { a map[A]bool = 1 }", err: ErrInvalid, msg: "invalid schema: 1:12: field a of record A: invalid map key type A"},
+		{src: "record A { a []map[[]bool]bool = 1 }", err: ErrInvalid, msg: "invalid schema: 1:12: field a of record A: invalid map key type []bool"},
+	}
+	for i, test := range tests {
+		_, err := Parse([]byte(test.src))
+		if !errors.Is(err, test.err) || err.Error() != test.msg {
+			t.Errorf("%2d expect %q, got %v", i, test.msg, err)
+		}
+	}
+	if !doesPanic(func() { MustParse("record") }) {
+		t.Error("expect MustParse panics")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	src, err := os.ReadFile("testdata/example.idrs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := MustParse(string(src))
+	b, err := Marshal(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s2, err := Unmarshal(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s2.String() != s.String() {
+		t.Errorf("expect\n%s\ngot\n%s", s, s2)
+	}
+	for i := range s.Records {
+		s.Records[i].pos = pos{}
+		for j := range s.Records[i].Fields {
+			s.Records[i].Fields[j].pos = pos{}
+		}
+	}
+	if !reflect.DeepEqual(s, s2) {
+		t.Errorf("expect %+v, got %+v", s, s2)
+	}
+
+	// decoded schemas are validated
+	s2.Records[0].Fields[0].Type = Type{Tag: low.BytesTag}
+	b, _ = Marshal(s2)
+	if _, err := Unmarshal(b); !errors.Is(err, ErrInvalid) || err.Error() != "invalid schema: field name of record Person: invalid type BytesTag" {
+		t.Errorf("expect ErrInvalid, got %v", err)
+	}
+	if _, err := Unmarshal(b[:len(b)-1]); err == nil {
+		t.Error("expect error with truncated data")
+	}
+}
+
+func doesPanic(f func()) (res bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = true
+		}
+	}()
+	f()
+	return false
+}
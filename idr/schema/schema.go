@@ -0,0 +1,216 @@
+// Package schema defines a schema language describing IDR records, with
+// a parser, a validator, a Go code generator and a runtime decoding
+// records into a generic tree.
+//
+// A schema is a sequence of record definitions. A record has fields
+// identified by a name and a number, and whose type is an IDR type:
+//
+//	// Person is a record.
+//	record Person {
+//		name     string          = 1
+//		birthday vartime         = 2
+//		home     dir             = 3
+//		phones   []string        = 4
+//		scores   map[string]varint = 5
+//		parent   Person          = 6
+//	}
+//
+// The scalar types are named after the TagT constants, lower cased and
+// without the Tag suffix: bool, uint8, uint16, uint32, uint64, int8,
+// int16, int32, int64, float32, float64, complex64, complex128, time,
+// blob, string, dir, varuint, varint, varuint64, varint64, varfloat,
+// varcomplex and vartime. The type []T is an IDR array of elements of
+// type T, map[K]V an IDR map, and a record name an IDR record. Comments
+// start with // and end at the end of the line.
+//
+// The records are encoded as with the idr package: a struct with the
+// fields of the record and matching idr struct tags is encoded by
+// idr.Marshal as described by the schema. Such structs are generated by
+// GenerateGo.
+//
+// A schema is itself encoded in IDR by Marshal as described by the
+// MetaSchema, so that it may be stored as information in a DIS node.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chmike/ditp/idr"
+	"github.com/chmike/ditp/idr/low"
+)
+
+// ErrSyntax is the error returned when a schema can't be parsed.
+var ErrSyntax = errors.New("syntax error")
+
+// ErrInvalid is the error returned when a schema is invalid.
+var ErrInvalid = errors.New("invalid schema")
+
+// MetaSchema is the schema of the IDR encoding of schemas.
+const MetaSchema = `// Schema is an IDR schema.
+record Schema {
+	records []Record = 1
+}
+
+record Record {
+	name   string  = 1
+	fields []Field = 2
+}
+
+record Field {
+	num  varuint64 = 1
+	name string    = 2
+	type Type      = 3
+}
+
+// Type is an IDR type. Record is the name of the record when tag is
+// RecordTag. Elem is the type of the elements of an array and of the
+// values of a map, and Key is the type of the keys of a map.
+record Type {
+	tag    varuint64 = 1
+	record string    = 2
+	elem   Type      = 3
+	key    Type      = 4
+}
+`
+
+// Schema is a set of record definitions.
+type Schema struct {
+	Records []Record `idr:"1"`
+}
+
+// Record is a record definition.
+type Record struct {
+	Name   string  `idr:"1"`
+	Fields []Field `idr:"2"`
+	pos    pos
+}
+
+// Field is a record field definition.
+type Field struct {
+	Num  uint64 `idr:"1"`
+	Name string `idr:"2"`
+	Type Type   `idr:"3"`
+	pos  pos
+}
+
+// Type is an IDR type. Record is the name of the record when Tag is
+// RecordTag. Elem is the type of the elements of an array and of the
+// values of a map, and Key is the type of the keys of a map.
+type Type struct {
+	Tag    low.TagT `idr:"1"`
+	Record string   `idr:"2"`
+	Elem   *Type    `idr:"3"`
+	Key    *Type    `idr:"4"`
+}
+
+// pos is a position in the schema source.
+type pos struct {
+	line, col int
+}
+
+func (p pos) String() string {
+	if p.line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d: ", p.line, p.col)
+}
+
+// scalars are the scalar type names and their tag.
+var scalars = map[string]low.TagT{}
+
+func init() {
+	for t := low.BoolTag; t < low.MaxTag; t++ {
+		switch t {
+		case low.ByteTag, low.BytesTag, low.SizeTag, low.ArrayTag, low.ListTag, low.MapTag, low.RecordTag,
+			low.DeltaInt64Tag, low.DeltaUint64Tag, low.MonoInt64Tag, low.MonoUint64Tag, low.DeltaDeltaInt64Tag:
+			continue
+		}
+		scalars[strings.ToLower(strings.TrimSuffix(t.String(), "Tag"))] = t
+	}
+}
+
+// scalarName returns the name of the scalar type with tag t.
+func scalarName(t low.TagT) (string, bool) {
+	name := strings.ToLower(strings.TrimSuffix(t.String(), "Tag"))
+	if scalars[name] != t || t == low.NoneTag {
+		return "", false
+	}
+	return name, true
+}
+
+// Record returns the record definition with the given name or nil.
+func (s *Schema) Record(name string) *Record {
+	for i := range s.Records {
+		if s.Records[i].Name == name {
+			return &s.Records[i]
+		}
+	}
+	return nil
+}
+
+// Field returns the field with number num or nil.
+func (r *Record) Field(num uint64) *Field {
+	for i := range r.Fields {
+		if r.Fields[i].Num == num {
+			return &r.Fields[i]
+		}
+	}
+	return nil
+}
+
+// String returns the type as written in a schema.
+func (t Type) String() string {
+	switch t.Tag {
+	case low.ArrayTag:
+		if t.Elem != nil {
+			return "[]" + t.Elem.String()
+		}
+	case low.MapTag:
+		if t.Key != nil && t.Elem != nil {
+			return "map[" + t.Key.String() + "]" + t.Elem.String()
+		}
+	case low.RecordTag:
+		return t.Record
+	default:
+		if name, ok := scalarName(t.Tag); ok {
+			return name
+		}
+	}
+	return fmt.Sprintf("invalid(%v)", t.Tag)
+}
+
+// String returns the schema source of s.
+func (s *Schema) String() string {
+	var b strings.Builder
+	for i, r := range s.Records {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "record %s {\n", r.Name)
+		for _, f := range r.Fields {
+			fmt.Fprintf(&b, "\t%s %s = %d\n", f.Name, f.Type, f.Num)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// Marshal returns the IDR encoding of the schema s as described by the
+// MetaSchema.
+func Marshal(s *Schema) ([]byte, error) {
+	return idr.Marshal(s)
+}
+
+// Unmarshal decodes and validates the IDR encoding of a schema.
+func Unmarshal(b []byte) (*Schema, error) {
+	var s Schema
+	if err := idr.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
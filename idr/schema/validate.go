@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/chmike/ditp/idr/low"
+)
+
+// isIdent returns true if s is a valid identifier.
+func isIdent(s string) bool {
+	if s == "" || !isLetter(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isLetter(s[i]) && !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that the record names are unique identifiers that are
+// not scalar type names, that the field names and numbers are unique in
+// each record, that the field numbers are not 0, that the referenced
+// records are defined, and that the map keys are scalar types other than
+// blob.
+func (s *Schema) Validate() error {
+	names := make(map[string]bool)
+	for _, r := range s.Records {
+		if !isIdent(r.Name) || r.Name == "record" || r.Name == "map" {
+			return fmt.Errorf("%w: %vinvalid record name %q", ErrInvalid, r.pos, r.Name)
+		}
+		if _, ok := scalars[r.Name]; ok {
+			return fmt.Errorf("%w: %vrecord name %q is a type name", ErrInvalid, r.pos, r.Name)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("%w: %vrecord %s is already defined", ErrInvalid, r.pos, r.Name)
+		}
+		names[r.Name] = true
+	}
+	for _, r := range s.Records {
+		fieldNames := make(map[string]bool)
+		nums := make(map[uint64]bool)
+		for _, f := range r.Fields {
+			if !isIdent(f.Name) {
+				return fmt.Errorf("%w: %vinvalid field name %q in record %s", ErrInvalid, f.pos, f.Name, r.Name)
+			}
+			if fieldNames[f.Name] {
+				return fmt.Errorf("%w: %vfield %s of record %s is already defined", ErrInvalid, f.pos, f.Name, r.Name)
+			}
+			fieldNames[f.Name] = true
+			if f.Num == 0 {
+				return fmt.Errorf("%w: %vfield %s of record %s has number 0", ErrInvalid, f.pos, f.Name, r.Name)
+			}
+			if nums[f.Num] {
+				return fmt.Errorf("%w: %vfield number %d of record %s is already used", ErrInvalid, f.pos, f.Num, r.Name)
+			}
+			nums[f.Num] = true
+			if err := s.validateType(f.Type); err != nil {
+				return fmt.Errorf("%w: %vfield %s of record %s: %v", ErrInvalid, f.pos, f.Name, r.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateType checks the type t.
+func (s *Schema) validateType(t Type) error {
+	switch t.Tag {
+	case low.ArrayTag:
+		if t.Elem == nil || t.Key != nil {
+			return fmt.Errorf("invalid array type")
+		}
+		return s.validateType(*t.Elem)
+	case low.MapTag:
+		if t.Elem == nil || t.Key == nil {
+			return fmt.Errorf("invalid map type")
+		}
+		if _, ok := scalarName(t.Key.Tag); !ok || t.Key.Tag == low.BlobTag {
+			return fmt.Errorf("invalid map key type %v", t.Key)
+		}
+		if err := s.validateType(*t.Key); err != nil {
+			return err
+		}
+		return s.validateType(*t.Elem)
+	case low.RecordTag:
+		if s.Record(t.Record) == nil {
+			return fmt.Errorf("undefined record %s", t.Record)
+		}
+	default:
+		if _, ok := scalarName(t.Tag); !ok {
+			return fmt.Errorf("invalid type %v", t.Tag)
+		}
+	}
+	if t.Elem != nil || t.Key != nil {
+		return fmt.Errorf("invalid %v type", t.Tag)
+	}
+	return nil
+}